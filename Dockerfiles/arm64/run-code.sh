@@ -1,13 +1,15 @@
 #!/bin/sh
 
-if [ "$#" -ne 3 ]; then
-    echo "Usage: $0 <language> <source_file_path> <input_file_path>"
+if [ "$#" -lt 3 ] || [ "$#" -gt 4 ]; then
+    echo "Usage: $0 <language> <source_file_path> <input_file_path> [compiler_flags]"
     exit 1
 fi
 
 language="$1"
 source_file="$2"
 input_file="$3"
+# Optional compiler flags of the language version, e.g. -std=c++20
+compiler_flags="$4"
 
 if [ ! -f "$source_file" ]; then
     echo "Error: Source file '$source_file' does not exist!" >&2
@@ -19,47 +21,260 @@ if [ ! -f "$input_file" ]; then
     exit 1
 fi
 
-# C++ function to compile and run the program
-run_cpp() {
-    executable="a.out"
+# The script has to be the init process of the container, which the program can't kill, so that it outlives every
+# process of the program and kills what is left of it before writing the reports. Run it with exec.
+if [ "$$" -ne 1 ]; then
+    echo "Error: run-code.sh has to be the init process of the container, run it with exec." >&2
+    exit 1
+fi
 
-    # Compile the C++ program and direct any errors to stderr
-    g++ "$source_file" -o "$executable" 2>&1
-    if [ $? -ne 0 ]; then
-        echo "Compilation failed. Please check the error messages above." >&2
-        exit 1
+# Compiled programs are kept out of the code directory, it is shared with the host
+build_dir=$(mktemp -d)
+trap 'rm -rf "$build_dir"' EXIT
+
+# The container starts in the submission directory. Multi-file submissions keep all their files
+# below it, so every source file found there is part of the program.
+project_dir=$(pwd)
+source_dir=$(dirname "$source_file")
+
+# The reports for the server are written to the report directory, a mount of its own which the server reads after
+# the container exited. Nothing is reported if it isn't mounted.
+report_dir=/rce-report
+
+# A failed compilation is marked in the report directory, the program never ran then so it can't fake the marker.
+compilation_failed() {
+    echo "Compilation failed. Please check the error messages above." >&2
+    if [ -d "$report_dir" ]; then
+        : > "$report_dir/compilation-failed"
     fi
+    exit 1
+}
+
+# The compiler flags are intentionally unquoted below so that several flags can be passed.
+# Every language has a compile function, which exits if the compilation fails, the command running
+# the program is set as the positional parameters afterwards.
 
-    # Run the compiled executable, redirecting both stdout and stderr to the terminal
-    ./$executable < "$input_file" 2>&1
+compile_cpp() {
+    find "$project_dir" -type f \( -name '*.cpp' -o -name '*.cc' -o -name '*.cxx' \) \
+        -exec g++ $compiler_flags -I "$project_dir" -o "$build_dir/main" {} + 2>&1 || compilation_failed
+}
+
+compile_c() {
+    # The math library has to be linked after the sources
+    find "$project_dir" -type f -name '*.c' \
+        -exec sh -c 'gcc "$@" -lm' sh $compiler_flags -I "$project_dir" -o "$build_dir/main" {} + 2>&1 || compilation_failed
+}
 
-    if [ $? -ne 0 ]; then
-        echo "Runtime error occurred. Check the output above for details." >&2
+# With a go.mod the package of the entry point is built so that it can import the other packages
+# of the module, otherwise the files next to the entry point make up the program.
+# If a module cache is mounted, programs without a go.mod get one in the build directory which
+# resolves their imports from the cache, the cache is used as a file proxy so nothing is downloaded.
+compile_go() {
+    go_files=$(find "$source_dir" -maxdepth 1 -type f -name '*.go' ! -name '*_test.go')
+    if [ -f "$project_dir/go.mod" ]; then
+        (cd "$source_dir" && go build $compiler_flags -o "$build_dir/main" .) 2>&1 || compilation_failed
+    elif [ -n "$GOMODCACHE" ] && [ -d "$GOMODCACHE/cache/download" ]; then
+        mkdir "$build_dir/src"
+        cp $go_files "$build_dir/src"
+        (
+            cd "$build_dir/src" &&
+            go mod init submission > /dev/null 2>&1 &&
+            GOPROXY="file://$GOMODCACHE/cache/download" go mod tidy > /dev/null 2>&1;
+            go build $compiler_flags -o "$build_dir/main" .
+        ) 2>&1 || compilation_failed
+    else
+        go build $compiler_flags -o "$build_dir/main" $go_files 2>&1 || compilation_failed
     fi
+}
 
-    # Clean up the executable
-    rm "$executable"
+compile_rust() {
+    rustc $compiler_flags -o "$build_dir/main" "$source_file" 2>&1 || compilation_failed
 }
 
-# Go function to run the program
-run_go() {
-    # Run the Go program and capture both stdout and stderr
-    go run "$source_file" < "$input_file" 2>&1
-    if [ $? -ne 0 ]; then
-        echo "Runtime error occurred while running the Go program." >&2
+# The source file of a public class has to be named after the class, and the class declaring
+# main is the one which has to be run. Both are detected from the source, falling back to Main.
+# Multi-file submissions have to name their files after the classes themselves, all of them are compiled.
+compile_java() {
+    main_class=$(awk '
+        /(^|[^A-Za-z0-9_])class[ \t]+[A-Za-z_]/ {
+            match($0, /class[ \t]+[A-Za-z_][A-Za-z0-9_]*/)
+            split(substr($0, RSTART, RLENGTH), parts, /[ \t]+/)
+            class = parts[2]
+        }
+        /static[ \t]+void[ \t]+main[ \t]*\(/ { print class; exit }
+    ' "$source_file")
+    package=$(sed -nE 's/^[[:space:]]*package[[:space:]]+([A-Za-z0-9_.]+)[[:space:]]*;.*/\1/p' "$source_file" | head -n 1)
+
+    if [ "$(find "$project_dir" -type f -name '*.java' | wc -l)" -gt 1 ]; then
+        main_class=${main_class:-$(basename "$source_file" .java)}
+        find "$project_dir" -type f -name '*.java' \
+            -exec javac $compiler_flags -d "$build_dir" {} + 2>&1 || compilation_failed
+    else
+        public_class=$(sed -nE 's/.*public[[:space:]]+((final|abstract)[[:space:]]+)?class[[:space:]]+([A-Za-z_][A-Za-z0-9_]*).*/\3/p' "$source_file" | head -n 1)
+        file_class=${public_class:-${main_class:-Main}}
+        main_class=${main_class:-$file_class}
+
+        cp "$source_file" "$build_dir/$file_class.java"
+        javac $compiler_flags -d "$build_dir" "$build_dir/$file_class.java" 2>&1 || compilation_failed
     fi
+
+    if [ -n "$package" ]; then
+        main_class="$package.$main_class"
+    fi
+}
+
+compile_python() {
+    # Report syntax errors in any module as compilation errors, the byte code is kept out of the code directory
+    PYTHONPYCACHEPREFIX="$build_dir" python3 -m compileall -q "$project_dir" 2>&1 || compilation_failed
+}
+
+compile_javascript() {
+    node --check "$source_file" 2>&1 || compilation_failed
 }
 
-# Check the programming language and call the appropriate function
+# Check the programming language and call the appropriate functions
 case "$language" in
     cpp)
-        run_cpp
+        compile_cpp
+        set -- "$build_dir/main"
+        ;;
+    c)
+        compile_c
+        set -- "$build_dir/main"
         ;;
     golang)
-        run_go
+        compile_go
+        set -- "$build_dir/main"
+        ;;
+    rust)
+        compile_rust
+        set -- "$build_dir/main"
+        ;;
+    java)
+        compile_java
+        set -- java -cp "$build_dir" "$main_class"
+        ;;
+    python)
+        compile_python
+        set -- env PYTHONPYCACHEPREFIX="$build_dir" python3 "$source_file"
+        ;;
+    javascript)
+        compile_javascript
+        set -- node "$source_file"
         ;;
     *)
-        echo "Error: Unsupported language '$language'. Please use 'cpp' or 'go'." >&2
+        echo "Error: Unsupported language '$language'." >&2
         exit 1
         ;;
 esac
+
+# The wall time, the CPU time and the peak memory of the program are written to the usage file in the report
+# directory. time reports them through a pipe which the program has no descriptor of, and the file is only written
+# once nothing of the program runs anymore.
+usage_file="$report_dir/usage"
+usage=""
+
+# The CPU time limit applies to the program only, compiling doesn't count. It is checked against the CPU time the
+# cgroup of the container accounts for the run, which the program can't write, a program which used more exits
+# with the status of a program killed by SIGXCPU.
+cpu_time_limit_ms=${RCE_CPU_TIME_LIMIT_MS:-0}
+cpu_time_exceeded_status=152
+
+# cpu_usage prints the CPU time used by the processes of the container in microseconds, nothing if the cgroup
+# can't be read.
+cpu_usage() {
+    cgroup=$(sed -n 's/^0:://p' /proc/self/cgroup 2> /dev/null)
+    if [ -n "$cgroup" ] && [ -r "/sys/fs/cgroup${cgroup%/}/cpu.stat" ]; then
+        sed -n 's/^usage_usec //p' "/sys/fs/cgroup${cgroup%/}/cpu.stat"
+    elif [ -r /sys/fs/cgroup/cpuacct/cpuacct.usage ]; then
+        echo $(($(cat /sys/fs/cgroup/cpuacct/cpuacct.usage) / 1000))
+    fi
+}
+
+# run_measured runs the program once, sets status to its exit status and usage to the usage reported by time.
+# The report of time is captured from its stderr, the program writes its stderr to stdout and has no copy of
+# the descriptors of time.
+run_measured() {
+    cpu_before=$(cpu_usage)
+    {
+        report=$(command time -f '%e %U %S %M' \
+            sh -c 'input=$1; shift; exec "$@" < "$input" 2>&1 3>&-' sh "$input_file" "$@" 2>&1 1>&3)
+    } 3>&1
+    status=$?
+    cpu_after=$(cpu_usage)
+    usage=$(printf '%s\n' "$report" | tail -n 1)
+    # time exits with the number of the signal which killed the program, the shell reports 128 + signal
+    signal=$(printf '%s\n' "$report" | sed -n 's/^Command terminated by signal \([0-9]*\).*/\1/p')
+    if [ -n "$signal" ]; then
+        status=$((128 + signal))
+    fi
+    if [ "$cpu_time_limit_ms" -gt 0 ] && [ -n "$cpu_before" ] && [ -n "$cpu_after" ] &&
+        [ $((cpu_after - cpu_before)) -gt $((cpu_time_limit_ms * 1000)) ]; then
+        status=$cpu_time_exceeded_status
+    fi
+}
+
+# A benchmark runs the program RCE_BENCHMARK_WARMUPS times and then RCE_BENCHMARK_RUNS times, the usage
+# of every run after the warmups is written to the benchmark file. Only the output of the first run is
+# kept, the benchmark stops at the first run which fails.
+benchmark_file="$report_dir/benchmark"
+benchmark_records=""
+benchmark_runs=${RCE_BENCHMARK_RUNS:-0}
+benchmark_warmups=${RCE_BENCHMARK_WARMUPS:-0}
+
+# Every process of the program gets SIGXCPU once it used the CPU time limit rounded up to seconds and is killed a
+# second later, the limit is set after compiling so that it doesn't apply to the compiler.
+if [ "$cpu_time_limit_ms" -gt 0 ]; then
+    cpu_time_limit_s=$(((cpu_time_limit_ms + 999) / 1000))
+    ulimit -t $((cpu_time_limit_s + 1))
+    ulimit -S -t $cpu_time_limit_s
+fi
+
+if ! command -v time > /dev/null 2>&1; then
+    # Without time the program runs unmeasured
+    "$@" < "$input_file" 2>&1
+    status=$?
+elif [ "$benchmark_runs" -gt 0 ]; then
+    run=0
+    while [ $run -lt $((benchmark_warmups + benchmark_runs)) ]; do
+        if [ $run -eq 0 ]; then
+            run_measured "$@"
+        else
+            run_measured "$@" > /dev/null
+        fi
+        if [ $status -ne 0 ]; then
+            echo "Run $((run + 1)) of the benchmark failed." >&2
+            break
+        fi
+        if [ $run -ge "$benchmark_warmups" ]; then
+            benchmark_records="$benchmark_records$usage
+"
+        fi
+        run=$((run + 1))
+    done
+else
+    run_measured "$@"
+fi
+
+# Processes the program left behind are killed before the reports are written so that nothing of the program can
+# rewrite them, kill -1 signals every process but the script. Whatever the program put in the report directory is
+# removed, the reports are only written if the directory is mounted.
+kill -KILL -1 2> /dev/null
+if [ -d "$report_dir" ]; then
+    rm -rf "$report_dir"/* "$report_dir"/.[!.]* "$report_dir"/..?*
+    if [ -n "$usage" ]; then
+        printf '%s\n' "$usage" > "$usage_file"
+    fi
+    if [ -n "$benchmark_records" ]; then
+        printf '%s' "$benchmark_records" > "$benchmark_file"
+    fi
+fi
+
+if [ "$cpu_time_limit_ms" -gt 0 ] && [ $status -eq $cpu_time_exceeded_status ]; then
+    echo "The program exceeded the CPU time limit of $cpu_time_limit_ms ms." >&2
+elif [ $status -ne 0 ]; then
+    echo "Runtime error occurred, the program exited with status $status." >&2
+fi
+
+# Exit with the status of the program so that it is reported as the exit code of the container
+exit $status
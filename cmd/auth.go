@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"remote-code-engine/pkg/config"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The tokens of the admin and of the tenants are sent in this header as "Bearer <token>".
+const authorizationHeader = "Authorization"
+
+// Names the tenant of the caller if the server trusts it, see config.ServerConfig.TrustTenantHeader.
+const tenantHeader = "X-Tenant-ID"
+
+// identity is who made a request, callers without a tenant are anonymous.
+type identity struct {
+	tenant string
+	admin  bool
+}
+
+// authenticator identifies the callers by their bearer token, or by the tenant header if a trusted gateway sets it.
+type authenticator struct {
+	adminToken string
+	// Tenants by their token.
+	tenants           map[string]string
+	trustTenantHeader bool
+}
+
+func newAuthenticator(serverConfig *config.ServerConfig) *authenticator {
+	// The tokens are checked when the config is validated.
+	tenants, _ := serverConfig.ParseTenantTokens()
+	return &authenticator{
+		adminToken:        serverConfig.AdminToken,
+		tenants:           tenants,
+		trustTenantHeader: serverConfig.TrustTenantHeader,
+	}
+}
+
+// identify returns the identity of a caller sending the authorization and the tenant headers. There is no admin
+// without an admin token, the tenant header is ignored unless it is trusted.
+func (a *authenticator) identify(authorization, tenant string) identity {
+	var id identity
+	if token, ok := strings.CutPrefix(authorization, "Bearer "); ok && token != "" {
+		for tenantToken, name := range a.tenants {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(tenantToken)) == 1 {
+				return identity{tenant: name}
+			}
+		}
+		id.admin = a.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.adminToken)) == 1
+	}
+	if a.trustTenantHeader {
+		id.tenant = tenant
+	}
+	return id
+}
+
+// identifyRequest identifies the caller of a REST request.
+func (a *authenticator) identifyRequest(ctx *gin.Context) identity {
+	return a.identify(ctx.GetHeader(authorizationHeader), ctx.GetHeader(tenantHeader))
+}
+
+// identifyCall identifies the caller of a gRPC call by the metadata, the counterpart of the headers.
+func (a *authenticator) identifyCall(ctx context.Context) identity {
+	return a.identify(getMetadata(ctx, authorizationHeader), getMetadata(ctx, tenantHeader))
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestIdentify(t *testing.T) {
+	auth := &authenticator{adminToken: "secret", tenants: map[string]string{"t1": "cs101", "t2": "cs102"}}
+	trusting := &authenticator{adminToken: "secret", tenants: auth.tenants, trustTenantHeader: true}
+	tests := []struct {
+		name          string
+		auth          *authenticator
+		authorization string
+		tenant        string
+		expected      identity
+	}{
+		{name: "admin token", auth: auth, authorization: "Bearer secret", expected: identity{admin: true}},
+		{name: "token without the scheme", auth: auth, authorization: "secret"},
+		{name: "wrong token", auth: auth, authorization: "Bearer other"},
+		{name: "tenant token", auth: auth, authorization: "Bearer t2", expected: identity{tenant: "cs102"}},
+		{name: "tenant token wins over the header", auth: trusting, authorization: "Bearer t1", tenant: "cs102", expected: identity{tenant: "cs101"}},
+		{name: "untrusted header", auth: auth, tenant: "cs101"},
+		{name: "trusted header", auth: trusting, tenant: "cs101", expected: identity{tenant: "cs101"}},
+		{name: "admin with a trusted header", auth: trusting, authorization: "Bearer secret", tenant: "cs101", expected: identity{tenant: "cs101", admin: true}},
+		{name: "no admin token", auth: &authenticator{}, authorization: "Bearer "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if id := tt.auth.identify(tt.authorization, tt.tenant); id != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, id)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"remote-code-engine/pkg/config"
+	"time"
+)
+
+// runPopulateCache implements the populate-cache subcommand, it returns the exit code of the process.
+// It has to run on a machine with network access, the populated directories can then be copied to
+// the servers whose containers have none.
+func runPopulateCache(args []string) int {
+	flags := flag.NewFlagSet("populate-cache", flag.ContinueOnError)
+	configPath := flags.String("config", "../config.yml", "Path to the config file declaring the caches")
+	language := flags.String("language", "", "Only populate the caches of this language")
+	timeout := flags.Duration("timeout", 30*time.Minute, "Timeout for populating all the caches")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	imageConfig, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	populated := 0
+	for _, lang := range imageConfig.GetSupportedLanguages() {
+		if *language != "" && string(lang) != *language {
+			continue
+		}
+
+		for _, cache := range imageConfig.GetLanguageConfig(lang).Caches {
+			if err := populateCache(ctx, cache); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to populate the cache %s of %s: %v\n", cache.Source, lang, err)
+				return 1
+			}
+			populated++
+		}
+	}
+
+	fmt.Printf("populated %d caches\n", populated)
+	return 0
+}
+
+func populateCache(ctx context.Context, cache config.Cache) error {
+	if err := os.MkdirAll(cache.Source, 0755); err != nil {
+		return fmt.Errorf("failed to create the cache directory: %w", err)
+	}
+
+	for _, module := range cache.Modules {
+		fmt.Printf("downloading %s into %s\n", module, cache.Source)
+
+		var err error
+		if config.IsURLModule(module) {
+			err = downloadFile(ctx, module, cache.Source)
+		} else {
+			err = downloadGoModule(ctx, module, cache.Source)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", module, err)
+		}
+	}
+	return nil
+}
+
+// downloadGoModule downloads the module and its dependencies into a Go module cache.
+func downloadGoModule(ctx context.Context, module, dir string) error {
+	cmd := exec.CommandContext(ctx, "go", "mod", "download", module)
+	// Outside of a module, go mod download accepts path@version arguments. The cache is kept
+	// writable so that it can be populated again, the containers mount it read-only.
+	cmd.Dir = os.TempDir()
+	cmd.Env = append(os.Environ(), "GOMODCACHE="+dir, "GOFLAGS=-modcacherw", "GO111MODULE=on")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// downloadFile downloads the file at url into dir, it is named after the last element of the URL path.
+func downloadFile(ctx context.Context, url, dir string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	f, err := os.Create(filepath.Join(dir, path.Base(req.URL.Path)))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	_, err = io.Copy(f, res.Body)
+	return err
+}
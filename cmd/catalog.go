@@ -0,0 +1,59 @@
+package main
+
+import "remote-code-engine/pkg/config"
+
+func newLimitsInfo(serverConfig *config.ServerConfig, limits config.Limits) LimitsInfo {
+	effective := serverConfig.EffectiveLimits(limits)
+	return LimitsInfo{
+		TimeLimitMs:    effective.TimeLimit.Milliseconds(),
+		CPUTimeLimitMs: effective.CPUTimeLimit.Milliseconds(),
+		MemoryMB:       effective.MemoryMB,
+		CPUs:           effective.CPUs,
+		MaxProcesses:   effective.MaxProcesses,
+		MaxFileSizeMB:  effective.MaxFileSizeMB,
+	}
+}
+
+func newLanguageInfo(serverConfig *config.ServerConfig, lang config.Language, langConfig config.LanguageConfig) LanguageInfo {
+	info := LanguageInfo{
+		Name:           lang,
+		DisplayName:    langConfig.GetDisplayName(lang),
+		Extension:      langConfig.Extension,
+		EditorMode:     langConfig.EditorMode,
+		Template:       langConfig.Template,
+		DefaultVersion: langConfig.DefaultVersion,
+	}
+
+	if len(langConfig.Versions) == 0 {
+		limits := newLimitsInfo(serverConfig, langConfig.Limits)
+		info.Flags = langConfig.Flags
+		info.Limits = &limits
+		return info
+	}
+
+	for _, version := range langConfig.GetVersions() {
+		resolved := langConfig.ResolveVersion(version)
+
+		displayName := langConfig.Versions[version].DisplayName
+		if displayName == "" {
+			displayName = info.DisplayName + " " + version
+		}
+
+		info.Versions = append(info.Versions, VersionInfo{
+			Name:        version,
+			DisplayName: displayName,
+			Flags:       resolved.Flags,
+			Limits:      newLimitsInfo(serverConfig, resolved.Limits),
+		})
+	}
+	return info
+}
+
+// newCatalog describes every language in a stable order.
+func newCatalog(serverConfig *config.ServerConfig, imageConfig *config.ImageConfig) []LanguageInfo {
+	catalog := []LanguageInfo{}
+	for _, lang := range imageConfig.GetSupportedLanguages() {
+		catalog = append(catalog, newLanguageInfo(serverConfig, lang, imageConfig.GetLanguageConfig(lang)))
+	}
+	return catalog
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"remote-code-engine/pkg/config"
+	codecontainer "remote-code-engine/pkg/container"
+	"remote-code-engine/pkg/inputs"
+	"remote-code-engine/pkg/limit"
+	"remote-code-engine/pkg/store"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Machine-readable codes of the error responses, clients should match these instead of the messages.
+const (
+	// The request body or a parameter can't be decoded.
+	ErrorCodeInvalidRequest      = "invalid_request"
+	ErrorCodeUnsupportedLanguage = "unsupported_language"
+	ErrorCodeInvalidEncoding     = "invalid_encoding"
+	ErrorCodeInvalidArchive      = "invalid_archive"
+	// The submission is rejected for another reason, e.g. an invalid file path.
+	ErrorCodeInvalidSubmission = "invalid_submission"
+	// The uploaded input doesn't exist, it may have expired.
+	ErrorCodeInputNotFound   = "input_not_found"
+	ErrorCodePayloadTooLarge = "payload_too_large"
+	ErrorCodeNotFound        = "not_found"
+	ErrorCodeNotRunning      = "not_running"
+	// The admin token is missing, or the tenant may not access the resource.
+	ErrorCodeForbidden = "forbidden"
+	// The tenant made too many submissions, retry after the Retry-After header.
+	ErrorCodeQuotaExceeded = "quota_exceeded"
+	// Too many executions are waiting, retry after the Retry-After header.
+	ErrorCodeQueueFull         = "queue_full"
+	ErrorCodeDaemonUnavailable = "daemon_unavailable"
+	ErrorCodeImageNotFound     = "image_not_found"
+	// The image of the language was rebuilt since the run whose image digest a deterministic run expects.
+	ErrorCodeImageChanged = "image_changed"
+	ErrorCodeInternal     = "internal_error"
+)
+
+// Rejected executions are retried after this long, the queue has no better estimate.
+const queueRetryAfter = 5 * time.Second
+
+// apiError is an error answered with an ErrorResponse.
+type apiError struct {
+	status  int
+	code    string
+	message string
+	details map[string]any
+	// Sent as the Retry-After header if it is set.
+	retryAfter time.Duration
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func newAPIError(status int, code, message string) *apiError {
+	return &apiError{status: status, code: code, message: message}
+}
+
+// withRetryAfter tells the client when to retry, in the Retry-After header and the details.
+func (e *apiError) withRetryAfter(d time.Duration) *apiError {
+	e.retryAfter = d
+	e.details = map[string]any{"retry_after_seconds": retryAfterSeconds(d)}
+	return e
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// quotaExceededError rejects a submission of a tenant which has used up its quota.
+type quotaExceededError struct {
+	retryAfter time.Duration
+}
+
+func (e *quotaExceededError) Error() string {
+	return fmt.Sprintf("The submission quota of the tenant is exceeded, retry in %s", e.retryAfter.Round(time.Second))
+}
+
+func (e *quotaExceededError) Unwrap() error {
+	return limit.ErrQuotaExceeded
+}
+
+// toAPIError maps an error of the submitter to the error answered to the client. Failures of the server are
+// answered with internalMessage, the details are only logged.
+func toAPIError(err error, internalMessage string) *apiError {
+	var apiErr *apiError
+	var invalid *invalidSubmissionError
+	var quota *quotaExceededError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.As(err, &invalid):
+		switch {
+		case errors.Is(err, config.ErrUnsupportedLanguage), errors.Is(err, config.ErrUnsupportedVersion):
+			return newAPIError(http.StatusBadRequest, ErrorCodeUnsupportedLanguage, err.Error())
+		case errors.Is(err, codecontainer.ErrInvalidEncoding):
+			return newAPIError(http.StatusBadRequest, ErrorCodeInvalidEncoding, err.Error())
+		case errors.Is(err, codecontainer.ErrInvalidArchive):
+			return newAPIError(http.StatusBadRequest, ErrorCodeInvalidArchive, err.Error())
+		case errors.Is(err, inputs.ErrNotFound):
+			return newAPIError(http.StatusBadRequest, ErrorCodeInputNotFound, err.Error())
+		}
+		return newAPIError(http.StatusBadRequest, ErrorCodeInvalidSubmission, err.Error())
+	case errors.Is(err, errSubmissionNotFound), errors.Is(err, store.ErrNotFound):
+		return newAPIError(http.StatusNotFound, ErrorCodeNotFound, "Submission not found")
+	case errors.Is(err, errSubmissionNotRunning):
+		return newAPIError(http.StatusConflict, ErrorCodeNotRunning, "The submission is not running")
+	case errors.As(err, &quota):
+		return newAPIError(http.StatusTooManyRequests, ErrorCodeQuotaExceeded, quota.Error()).
+			withRetryAfter(quota.retryAfter)
+	case errors.Is(err, limit.ErrQueueFull):
+		return newAPIError(http.StatusServiceUnavailable, ErrorCodeQueueFull, "Too many executions are waiting, retry later").
+			withRetryAfter(queueRetryAfter)
+	case errors.Is(err, codecontainer.ErrDaemonUnavailable):
+		return newAPIError(http.StatusServiceUnavailable, ErrorCodeDaemonUnavailable, "The container runtime is unavailable, retry later")
+	case errors.Is(err, codecontainer.ErrImageChanged):
+		return newAPIError(http.StatusConflict, ErrorCodeImageChanged, "The image with the digest image_digest was removed from the server")
+	case errors.Is(err, codecontainer.ErrImageNotFound):
+		return newAPIError(http.StatusInternalServerError, ErrorCodeImageNotFound, "The image of the language is missing on the server")
+	}
+	return newAPIError(http.StatusInternalServerError, ErrorCodeInternal, internalMessage)
+}
+
+// newErrorInfo returns the error of the envelope, the request ID is set by the request ID middleware.
+func newErrorInfo(ctx *gin.Context, err *apiError) *ErrorInfo {
+	return &ErrorInfo{
+		Code:      err.code,
+		Message:   err.message,
+		Details:   err.details,
+		RequestID: ctx.GetString(requestIDKey),
+	}
+}
+
+// writeError answers the request with the error envelope, see toAPIError for internalMessage.
+func writeError(ctx *gin.Context, err error, internalMessage string) {
+	apiErr := toAPIError(err, internalMessage)
+	if apiErr.retryAfter > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(retryAfterSeconds(apiErr.retryAfter)))
+	}
+	ctx.AbortWithStatusJSON(apiErr.status, ErrorResponse{Error: newErrorInfo(ctx, apiErr)})
+}
+
+// abortWithError answers the request with an error which isn't caused by the submitter.
+func abortWithError(ctx *gin.Context, status int, code, message string) {
+	writeError(ctx, newAPIError(status, code, message), "")
+}
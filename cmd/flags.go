@@ -2,25 +2,52 @@ package main
 
 import (
 	"flag"
+	"os"
 	"remote-code-engine/pkg/config"
 
 	"go.uber.org/zap"
 )
 
-func ParseFlags() {
-	flag.StringVar(&config.BaseCodePath, "code-dir", "/tmp/", "Base path to store the code files")
-	flag.BoolVar(&config.ResourceConstraints, "resource-constraints", false, "Enable resource constraints (default false)")
+type Flags struct {
+	ConfigPath string
+
+	// Values of the server settings passed as flags, only the flags which were set override the config file.
+	server config.ServerConfig
+}
+
+func ParseFlags() *Flags {
+	flags := &Flags{
+		server: config.DefaultServerConfig(),
+	}
+
+	flag.StringVar(&flags.ConfigPath, "config", "../config.yml", "Path to the config file, the languages are reloaded on changes and SIGHUP")
+	flags.server.RegisterFlags(flag.CommandLine)
 	help := flag.Bool("help", false, "Display help")
 
 	flag.Parse()
 
 	if *help {
 		flag.Usage()
-		return
+		os.Exit(0)
 	}
 
 	logger.Info("parsed the flags",
-		zap.String("code-dir", config.BaseCodePath),
-		zap.Bool("resource-constraints", config.ResourceConstraints),
+		zap.String("config", flags.ConfigPath),
 	)
+	return flags
+}
+
+// LoadServerConfig layers the server settings: defaults, the server section of the config file,
+// environment variables and finally the flags which were set explicitly.
+func (f *Flags) LoadServerConfig(file *config.File) (*config.ServerConfig, error) {
+	serverConfig := file.Server
+	if err := serverConfig.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	serverConfig.ApplyFlags(flag.CommandLine, &f.server)
+
+	if err := serverConfig.Validate(); err != nil {
+		return nil, err
+	}
+	return &serverConfig, nil
 }
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"remote-code-engine/pkg/config"
+	"remote-code-engine/pkg/rcepb"
+	"remote-code-engine/pkg/store"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcServer serves the gRPC API with the same submitter as the REST API.
+type grpcServer struct {
+	rcepb.UnimplementedCodeExecutionServer
+	submitter    *submitter
+	serverConfig *config.ServerConfig
+	configStore  *config.Store
+	// nil if the submission history is disabled.
+	submissionStore store.Store
+	auth            *authenticator
+}
+
+func newGRPCServer(submitter *submitter, serverConfig *config.ServerConfig, configStore *config.Store, submissionStore store.Store) *grpc.Server {
+	// Messages are capped like the JSON request bodies.
+	server := grpc.NewServer(grpc.MaxRecvMsgSize(int(serverConfig.MaxBodySizeMB * 1024 * 1024)))
+	rcepb.RegisterCodeExecutionServer(server, &grpcServer{
+		submitter:       submitter,
+		serverConfig:    serverConfig,
+		configStore:     configStore,
+		submissionStore: submissionStore,
+		auth:            newAuthenticator(serverConfig),
+	})
+	return server
+}
+
+func (g *grpcServer) Submit(ctx context.Context, req *rcepb.SubmitRequest) (*rcepb.Result, error) {
+	logger.Info("received a gRPC request",
+		zap.String("method", "Submit"),
+		zap.String("language", req.GetLanguage()),
+	)
+
+	imageConfig := g.configStore.Load()
+	request := newRequest(req)
+	if apiErr := validateRequest(request, imageConfig, g.serverConfig); apiErr != nil {
+		return nil, grpcError(apiErr)
+	}
+	response, err := g.submitter.submit(ctx, imageConfig, request, newFiles(request.Files), g.getCaller(ctx))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return newResult(response), nil
+}
+
+func (g *grpcServer) Execute(req *rcepb.SubmitRequest, stream rcepb.CodeExecution_ExecuteServer) error {
+	logger.Info("received a gRPC request",
+		zap.String("method", "Execute"),
+		zap.String("language", req.GetLanguage()),
+	)
+
+	if req.GetAsync() || req.GetCallbackUrl() != "" {
+		return status.Error(codes.InvalidArgument, "Execute streams the output of synchronous submissions, use Submit for asynchronous ones")
+	}
+
+	ctx := stream.Context()
+	imageConfig := g.configStore.Load()
+	request := newRequest(req)
+	if apiErr := validateRequest(request, imageConfig, g.serverConfig); apiErr != nil {
+		return grpcError(apiErr)
+	}
+	p, err := g.submitter.prepare(imageConfig, request, newFiles(request.Files), g.getCaller(ctx))
+	if err != nil {
+		return grpcError(err)
+	}
+
+	err = stream.Send(&rcepb.ExecuteResponse{
+		Event: &rcepb.ExecuteResponse_Started{
+			Started: &rcepb.Started{Id: p.submission.ID, Version: p.code.Version},
+		},
+	})
+	if err != nil {
+		p.ticket.Release()
+		return err
+	}
+
+	// The output is written by a single goroutine which has finished once execute returns,
+	// so the chunks and the result are never sent concurrently.
+	stdout := &streamWriter{stream: stream, kind: rcepb.OutputChunk_STREAM_STDOUT}
+	stderr := &streamWriter{stream: stream, kind: rcepb.OutputChunk_STREAM_STDERR}
+	response, err := g.submitter.execute(ctx, p, stdout, stderr)
+	if err != nil {
+		return grpcError(err)
+	}
+
+	return stream.Send(&rcepb.ExecuteResponse{
+		Event: &rcepb.ExecuteResponse_Result{Result: newResult(response)},
+	})
+}
+
+func (g *grpcServer) GetResult(ctx context.Context, req *rcepb.GetResultRequest) (*rcepb.Submission, error) {
+	if g.submissionStore == nil {
+		return nil, status.Error(codes.Unimplemented, "The submission history is disabled")
+	}
+
+	submission, err := getSubmission(ctx, g.submissionStore, req.GetId(), g.auth.identifyCall(ctx))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return newSubmissionMessage(submission), nil
+}
+
+func (g *grpcServer) Cancel(ctx context.Context, req *rcepb.CancelRequest) (*rcepb.CancelResponse, error) {
+	if err := g.submitter.cancel(ctx, req.GetId(), g.auth.identifyCall(ctx)); err != nil {
+		return nil, grpcError(err)
+	}
+	return &rcepb.CancelResponse{}, nil
+}
+
+func (g *grpcServer) ListLanguages(ctx context.Context, req *rcepb.ListLanguagesRequest) (*rcepb.ListLanguagesResponse, error) {
+	response := &rcepb.ListLanguagesResponse{}
+	for _, info := range newCatalog(g.serverConfig, g.configStore.Load()) {
+		language := &rcepb.Language{
+			Name:           string(info.Name),
+			DisplayName:    info.DisplayName,
+			Extension:      info.Extension,
+			EditorMode:     info.EditorMode,
+			Template:       info.Template,
+			Flags:          info.Flags,
+			DefaultVersion: info.DefaultVersion,
+		}
+		if info.Limits != nil {
+			language.Limits = newLimitsMessage(*info.Limits)
+		}
+		for _, version := range info.Versions {
+			language.Versions = append(language.Versions, &rcepb.Version{
+				Name:        version.Name,
+				DisplayName: version.DisplayName,
+				Flags:       version.Flags,
+				Limits:      newLimitsMessage(version.Limits),
+			})
+		}
+		response.Languages = append(response.Languages, language)
+	}
+	return response, nil
+}
+
+// streamWriter sends everything written to it as output chunks of the stream.
+type streamWriter struct {
+	stream rcepb.CodeExecution_ExecuteServer
+	kind   rcepb.OutputChunk_Stream
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	err := w.stream.Send(&rcepb.ExecuteResponse{
+		Event: &rcepb.ExecuteResponse_Output{
+			Output: &rcepb.OutputChunk{Stream: w.kind, Data: p},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// getCaller identifies the caller by its tenant and the address of the connection.
+func (g *grpcServer) getCaller(ctx context.Context) caller {
+	c := caller{tenant: g.auth.identifyCall(ctx).tenant}
+	if p, ok := peer.FromContext(ctx); ok {
+		c.address = p.Addr.String()
+		if host, _, err := net.SplitHostPort(c.address); err == nil {
+			c.address = host
+		}
+	}
+	return c
+}
+
+// getMetadata returns the first value of a header sent as gRPC metadata.
+func getMetadata(ctx context.Context, header string) string {
+	values := metadata.ValueFromIncomingContext(ctx, strings.ToLower(header))
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// grpcError maps the errors of the submitter to gRPC status codes, like the error codes of the REST API.
+func grpcError(err error) error {
+	apiErr := toAPIError(err, "Internal error")
+	code, ok := grpcCodes[apiErr.code]
+	if !ok {
+		code = codes.Internal
+	}
+	if code == codes.Internal {
+		logger.Error("failed to serve the gRPC request", zap.Error(err))
+	}
+	return status.Error(code, apiErr.message)
+}
+
+var grpcCodes = map[string]codes.Code{
+	ErrorCodeInvalidRequest:      codes.InvalidArgument,
+	ErrorCodeUnsupportedLanguage: codes.InvalidArgument,
+	ErrorCodeInvalidEncoding:     codes.InvalidArgument,
+	ErrorCodeInvalidArchive:      codes.InvalidArgument,
+	ErrorCodeInvalidSubmission:   codes.InvalidArgument,
+	ErrorCodeInputNotFound:       codes.InvalidArgument,
+	ErrorCodePayloadTooLarge:     codes.ResourceExhausted,
+	ErrorCodeNotFound:            codes.NotFound,
+	ErrorCodeForbidden:           codes.PermissionDenied,
+	ErrorCodeNotRunning:          codes.FailedPrecondition,
+	ErrorCodeQuotaExceeded:       codes.ResourceExhausted,
+	ErrorCodeQueueFull:           codes.ResourceExhausted,
+	ErrorCodeDaemonUnavailable:   codes.Unavailable,
+	ErrorCodeImageNotFound:       codes.FailedPrecondition,
+	ErrorCodeImageChanged:        codes.FailedPrecondition,
+	ErrorCodeInternal:            codes.Internal,
+}
+
+// newRequest converts a gRPC submission to a REST request, the raw bytes are base64 encoded like in a JSON request.
+func newRequest(req *rcepb.SubmitRequest) Request {
+	request := Request{
+		EncodedCode:  base64.StdEncoding.EncodeToString(req.GetCode()),
+		EncodedInput: base64.StdEncoding.EncodeToString(req.GetInput()),
+		InputID:      req.GetInputId(),
+		Language:     config.Language(req.GetLanguage()),
+		Version:      req.GetVersion(),
+		Entrypoint:   req.GetEntrypoint(),
+		Artifacts:    req.GetArtifacts(),
+		NoCache:      req.GetNoCache(),
+		Async:        req.GetAsync(),
+		CallbackURL:  req.GetCallbackUrl(),
+	}
+	for _, file := range req.GetFiles() {
+		request.Files = append(request.Files, File{
+			Path:           file.GetPath(),
+			EncodedContent: base64.StdEncoding.EncodeToString(file.GetContent()),
+		})
+	}
+	if benchmark := req.GetBenchmark(); benchmark != nil {
+		request.Benchmark = &BenchmarkRequest{
+			Runs:    int(benchmark.GetRuns()),
+			Warmups: int(benchmark.GetWarmups()),
+		}
+	}
+	if deterministic := req.GetDeterministic(); deterministic != nil {
+		request.Deterministic = &DeterministicRequest{
+			Seed:        deterministic.GetSeed(),
+			ImageDigest: deterministic.GetImageDigest(),
+		}
+	}
+	if limits := req.GetLimits(); limits != nil {
+		request.Limits = &LimitsInfo{
+			TimeLimitMs:    limits.GetTimeLimit().AsDuration().Milliseconds(),
+			CPUTimeLimitMs: limits.GetCpuTimeLimit().AsDuration().Milliseconds(),
+			MemoryMB:       limits.GetMemoryMb(),
+			CPUs:           limits.GetCpus(),
+			MaxProcesses:   limits.GetMaxProcesses(),
+			MaxFileSizeMB:  limits.GetMaxFileSizeMb(),
+		}
+	}
+	return request
+}
+
+func newResult(response *Response) *rcepb.Result {
+	result := &rcepb.Result{
+		Id:                 response.ID,
+		Status:             response.Status,
+		Output:             response.Output,
+		Verdict:            response.Verdict,
+		ExitCode:           int32(response.ExitCode),
+		Version:            response.Version,
+		ArtifactsTruncated: response.ArtifactsTruncated,
+		Truncated:          response.Truncated,
+		Usage:              newUsageMessage(response.Usage),
+		ContainerUsage:     newUsageMessage(response.ContainerUsage),
+		Benchmark:          newBenchmarkMessage(response.Benchmark),
+		Environment:        newEnvironmentMessage(response.Environment),
+		Cached:             response.Cached,
+	}
+	for _, artifact := range response.Artifacts {
+		// The artifacts are encoded by the server, decoding them can't fail.
+		content, _ := base64.StdEncoding.DecodeString(artifact.EncodedContent)
+		result.Artifacts = append(result.Artifacts, &rcepb.Artifact{
+			Path:    artifact.Path,
+			Content: content,
+			Size:    artifact.Size,
+		})
+	}
+	return result
+}
+
+func newSubmissionMessage(submission *store.Submission) *rcepb.Submission {
+	message := &rcepb.Submission{
+		Id:        submission.ID,
+		Tenant:    submission.Tenant,
+		Language:  string(submission.Language),
+		Version:   submission.Version,
+		Status:    submission.Status,
+		Verdict:   submission.Verdict,
+		ExitCode:  int32(submission.ExitCode),
+		Output:    submission.Output,
+		Cached:    submission.Cached,
+		CreatedAt: timestamppb.New(submission.CreatedAt),
+		Duration:  durationpb.New(submission.Duration),
+	}
+	if !submission.FinishedAt.IsZero() {
+		message.FinishedAt = timestamppb.New(submission.FinishedAt)
+	}
+	return message
+}
+
+func newBenchmarkMessage(benchmark *BenchmarkInfo) *rcepb.BenchmarkResult {
+	if benchmark == nil {
+		return nil
+	}
+	return &rcepb.BenchmarkResult{
+		Runs:         int32(benchmark.Runs),
+		Warmups:      int32(benchmark.Warmups),
+		WallTimeMs:   newSummaryMessage(benchmark.WallTimeMs),
+		CpuTimeMs:    newSummaryMessage(benchmark.CPUTimeMs),
+		PeakMemoryKb: newSummaryMessage(benchmark.PeakMemoryKB),
+	}
+}
+
+func newEnvironmentMessage(environment *EnvironmentInfo) *rcepb.Environment {
+	if environment == nil {
+		return nil
+	}
+	message := &rcepb.Environment{
+		ImageDigest: environment.ImageDigest,
+		Command:     environment.Command,
+		Env:         environment.Env,
+		Hostname:    environment.Hostname,
+		Seed:        environment.Seed,
+		Limits:      newLimitsMessage(environment.Limits),
+	}
+	if environment.TimeOffsetSeconds != 0 {
+		message.TimeOffset = durationpb.New(time.Duration(environment.TimeOffsetSeconds) * time.Second)
+	}
+	return message
+}
+
+func newSummaryMessage(summary SummaryInfo) *rcepb.Summary {
+	return &rcepb.Summary{
+		Min:    summary.Min,
+		Median: summary.Median,
+		P95:    summary.P95,
+		Stddev: summary.Stddev,
+	}
+}
+
+func newUsageMessage(usage *UsageInfo) *rcepb.Usage {
+	if usage == nil {
+		return nil
+	}
+	return &rcepb.Usage{
+		CpuTime:      durationpb.New(time.Duration(usage.CPUTimeMs) * time.Millisecond),
+		PeakMemoryKb: usage.PeakMemoryKB,
+	}
+}
+
+func newLimitsMessage(limits LimitsInfo) *rcepb.Limits {
+	limitsMessage := &rcepb.Limits{
+		TimeLimit:     durationpb.New(time.Duration(limits.TimeLimitMs) * time.Millisecond),
+		MemoryMb:      limits.MemoryMB,
+		Cpus:          limits.CPUs,
+		MaxProcesses:  limits.MaxProcesses,
+		MaxFileSizeMb: limits.MaxFileSizeMB,
+	}
+	if limits.CPUTimeLimitMs != 0 {
+		limitsMessage.CpuTimeLimit = durationpb.New(time.Duration(limits.CPUTimeLimitMs) * time.Millisecond)
+	}
+	return limitsMessage
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	// Key of the request ID in the gin context.
+	requestIDKey = "request_id"
+)
+
+// Request IDs sent by the clients or proxies are kept if they are reasonably short and printable.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestID tags every request with an ID, answered in the X-Request-ID header and in the error responses.
+func requestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.New().String()
+		}
+		ctx.Set(requestIDKey, id)
+		ctx.Header(requestIDHeader, id)
+		ctx.Next()
+	}
+}
+
+// recovery answers panicking handlers with an internal error instead of an empty 500.
+func recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(ctx *gin.Context, recovered any) {
+		logger.Error("recovered from a panic",
+			zap.String("request id", ctx.GetString(requestIDKey)),
+			zap.Any("panic", recovered),
+		)
+		abortWithError(ctx, http.StatusInternalServerError, ErrorCodeInternal, "Internal error")
+	})
+}
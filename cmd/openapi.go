@@ -0,0 +1,50 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"remote-code-engine/pkg/config"
+)
+
+// openAPIDocument describes the REST API. The parts which depend on the configuration are filled in by newOpenAPIDocument.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// newOpenAPIDocument returns the OpenAPI document with the configured languages and the limits the validation
+// middleware enforces.
+func newOpenAPIDocument(imageConfig *config.ImageConfig, serverConfig *config.ServerConfig) ([]byte, error) {
+	var document map[string]any
+	if err := json.Unmarshal(openAPIDocument, &document); err != nil {
+		return nil, fmt.Errorf("failed to decode the OpenAPI document: %w", err)
+	}
+	schemas := document["components"].(map[string]any)["schemas"].(map[string]any)
+	properties := func(schema string) map[string]any {
+		return schemas[schema].(map[string]any)["properties"].(map[string]any)
+	}
+	schemas["Language"].(map[string]any)["enum"] = imageConfig.GetSupportedLanguages()
+
+	request := properties("Request")
+	// The sizes are enforced after decoding, base64 is the longest encoding.
+	request["code"].(map[string]any)["maxLength"] = base64.StdEncoding.EncodedLen(int(serverConfig.MaxCodeSizeMB * 1024 * 1024))
+	request["input"].(map[string]any)["maxLength"] = base64.StdEncoding.EncodedLen(int(serverConfig.MaxInputSizeMB * 1024 * 1024))
+
+	limits := properties("RequestLimits")
+	for _, r := range limitRanges {
+		property := limits[r.field].(map[string]any)
+		if r.min == 0 {
+			property["minimum"] = r.min
+			property["maximum"] = r.max
+			continue
+		}
+		// 0 keeps the limit of the language.
+		property["anyOf"] = []any{
+			map[string]any{"enum": []any{0}},
+			map[string]any{"minimum": r.min, "maximum": r.max},
+		}
+	}
+
+	return json.Marshal(document)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"remote-code-engine/pkg/client"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Verdicts of a test, the verdict of the engine is shown for executions which didn't finish successfully.
+const (
+	verdictPassed      = "passed"
+	verdictWrongAnswer = "wrong_answer"
+)
+
+// testCase is a pair of files, NAME.in is the input and NAME.out the expected output.
+type testCase struct {
+	name       string
+	inputPath  string
+	outputPath string
+}
+
+// runJudge implements the judge subcommand, it returns 0 if every test passed and 1 otherwise.
+func runJudge(args []string) int {
+	var opts options
+	flags := flag.NewFlagSet("judge", flag.ContinueOnError)
+	opts.register(flags)
+	flags.Usage = func() {
+		usage()
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 2 {
+		usage()
+		return exitUsage
+	}
+
+	tests, err := findTestCases(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rce:", err)
+		return exitUsage
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	ctx, cancelTimeout := context.WithTimeout(ctx, opts.timeout)
+	defer cancelTimeout()
+
+	c := opts.newClient()
+	submission, err := opts.newSubmission(ctx, c, flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rce:", err)
+		return exitEngineError
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TEST\tVERDICT\tEXIT CODE")
+	passed := 0
+	for _, test := range tests {
+		verdict, exitCode, err := judge(ctx, c, *submission, test)
+		if err != nil {
+			_ = table.Flush()
+			if errors.Is(err, context.Canceled) {
+				return exitCancelled
+			}
+			fmt.Fprintf(os.Stderr, "rce: test %s: %v\n", test.name, err)
+			return exitEngineError
+		}
+		if verdict == verdictPassed {
+			passed++
+		}
+		fmt.Fprintf(table, "%s\t%s\t%d\n", test.name, verdict, exitCode)
+	}
+	_ = table.Flush()
+
+	fmt.Printf("\n%d/%d tests passed\n", passed, len(tests))
+	if passed != len(tests) {
+		return 1
+	}
+	return 0
+}
+
+// findTestCases lists the test pairs of the directory sorted by name, every input needs an expected output.
+func findTestCases(dir string) ([]testCase, error) {
+	inputs, err := filepath.Glob(filepath.Join(dir, "*.in"))
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%s has no *.in files", dir)
+	}
+	slices.Sort(inputs)
+
+	var tests []testCase
+	for _, input := range inputs {
+		output := strings.TrimSuffix(input, ".in") + ".out"
+		if _, err := os.Stat(output); err != nil {
+			return nil, fmt.Errorf("the expected output of %s is missing: %w", input, err)
+		}
+		tests = append(tests, testCase{
+			name:       strings.TrimSuffix(filepath.Base(input), ".in"),
+			inputPath:  input,
+			outputPath: output,
+		})
+	}
+	return tests, nil
+}
+
+// judge runs the test and compares the stdout of the program with the expected output.
+func judge(ctx context.Context, c *client.Client, submission client.Submission, test testCase) (string, int, error) {
+	input, err := os.ReadFile(test.inputPath)
+	if err != nil {
+		return "", 0, err
+	}
+	expected, err := os.ReadFile(test.outputPath)
+	if err != nil {
+		return "", 0, err
+	}
+
+	submission.Input = input
+	var stdout bytes.Buffer
+	result, err := c.Stream(ctx, &submission, &stdout, io.Discard)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if result.Verdict != client.VerdictOK {
+		return result.Verdict, result.ExitCode, nil
+	}
+	if normalizeOutput(stdout.String()) != normalizeOutput(string(expected)) {
+		return verdictWrongAnswer, result.ExitCode, nil
+	}
+	return verdictPassed, result.ExitCode, nil
+}
+
+// normalizeOutput ignores trailing whitespace on every line and trailing empty lines, like most judges.
+func normalizeOutput(output string) string {
+	lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
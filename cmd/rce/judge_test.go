@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{name: "unchanged", output: "1\n2", expected: "1\n2"},
+		{name: "trailing newlines", output: "1\n2\n\n\n", expected: "1\n2"},
+		{name: "trailing whitespace of the lines", output: "1 \t\n2  \n", expected: "1\n2"},
+		{name: "windows line endings", output: "1\r\n2\r\n", expected: "1\n2"},
+		{name: "leading whitespace is kept", output: "  1\n\n2", expected: "  1\n\n2"},
+		{name: "empty", output: "\n \n", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if normalized := normalizeOutput(tt.output); normalized != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, normalized)
+			}
+		})
+	}
+}
+
+func TestFindTestCases(t *testing.T) {
+	tests := []struct {
+		name          string
+		files         []string
+		expectedNames []string
+		expectErr     bool
+	}{
+		{
+			name:          "pairs sorted by name",
+			files:         []string{"2.in", "2.out", "1.in", "1.out", "notes.txt"},
+			expectedNames: []string{"1", "2"},
+		},
+		{
+			name:          "expected output without an input",
+			files:         []string{"1.in", "1.out", "2.out"},
+			expectedNames: []string{"1"},
+		},
+		{
+			name:      "missing expected output",
+			files:     []string{"1.in", "1.out", "2.in"},
+			expectErr: true,
+		},
+		{
+			name:      "no inputs",
+			files:     []string{"1.out"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, file := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, file), nil, 0644); err != nil {
+					t.Fatalf("failed to create %s: %v", file, err)
+				}
+			}
+
+			testCases, err := findTestCases(dir)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", testCases)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to find the test cases: %v", err)
+			}
+
+			var names []string
+			for _, testCase := range testCases {
+				names = append(names, testCase.name)
+				if testCase.inputPath != filepath.Join(dir, testCase.name+".in") || testCase.outputPath != filepath.Join(dir, testCase.name+".out") {
+					t.Errorf("unexpected paths of %s: %+v", testCase.name, testCase)
+				}
+			}
+			if !slices.Equal(names, tt.expectedNames) {
+				t.Errorf("expected the test cases %v, got %v", tt.expectedNames, names)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+// rce runs local files against an engine server.
+//
+//	rce [flags] FILE              runs the file, reading the input from --input or stdin
+//	rce judge [flags] FILE DIR    runs the file against the *.in/*.out test pairs in DIR
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"remote-code-engine/pkg/client"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Exit codes of the CLI itself, everything else is the exit code of the program.
+const (
+	exitUsage = 2
+	// The time limit was exceeded, like timeout(1).
+	exitTimeLimit = 124
+	// The engine failed, like docker run.
+	exitEngineError = 125
+	exitCancelled   = 130
+	// The program was killed because of the output limit, like a shell reports SIGKILL.
+	exitOutputLimit = 137
+)
+
+// options are the flags shared by the subcommands.
+type options struct {
+	server   string
+	language string
+	version  string
+	tenant   string
+	token    string
+	noCache  bool
+	timeout  time.Duration
+}
+
+func (o *options) register(flags *flag.FlagSet) {
+	server := os.Getenv("RCE_SERVER")
+	if server == "" {
+		server = "http://localhost:9000"
+	}
+	flags.StringVar(&o.server, "server", server, "URL of the engine, defaults to $RCE_SERVER")
+	flags.StringVar(&o.language, "language", "", "Language of the file, detected from the extension if empty")
+	flags.StringVar(&o.version, "version", "", "Version of the language, the default version if empty")
+	flags.StringVar(&o.tenant, "tenant", os.Getenv("RCE_TENANT"), "Tenant the submissions are recorded for behind a gateway, defaults to $RCE_TENANT")
+	flags.StringVar(&o.token, "token", os.Getenv("RCE_TOKEN"), "Bearer token of the tenant, defaults to $RCE_TOKEN")
+	flags.BoolVar(&o.noCache, "no-cache", false, "Execute the code even if the result is cached")
+	flags.DurationVar(&o.timeout, "timeout", 5*time.Minute, "Timeout for the whole run")
+}
+
+func (o *options) newClient() *client.Client {
+	return client.New(o.server, client.WithTenant(o.tenant), client.WithToken(o.token))
+}
+
+// newSubmission reads the file and resolves its language.
+func (o *options) newSubmission(ctx context.Context, c *client.Client, path string) (*client.Submission, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the file: %w", err)
+	}
+
+	language := o.language
+	if language == "" {
+		if language, err = detectLanguage(ctx, c, path); err != nil {
+			return nil, err
+		}
+	}
+
+	return &client.Submission{
+		Language: language,
+		Version:  o.version,
+		Code:     code,
+		NoCache:  o.noCache,
+	}, nil
+}
+
+// detectLanguage finds the language whose extension matches the file.
+func detectLanguage(ctx context.Context, c *client.Client, path string) (string, error) {
+	extension := filepath.Ext(path)
+	if extension == "" {
+		return "", fmt.Errorf("%s has no extension, pass --language", path)
+	}
+
+	languages, err := c.Languages(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list the languages: %w", err)
+	}
+
+	var matches []string
+	for _, language := range languages {
+		if language.Extension == extension {
+			matches = append(matches, language.Name)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no language of the server uses the extension %s, pass --language", extension)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("the languages %s use the extension %s, pass --language", strings.Join(matches, ", "), extension)
+	}
+}
+
+// exitCode maps the result to the exit code of the CLI.
+func exitCode(result *client.Result) int {
+	switch result.Verdict {
+	case client.VerdictTimeLimitExceeded:
+		return exitTimeLimit
+	case client.VerdictInternalError:
+		return exitEngineError
+	case client.VerdictCancelled:
+		return exitCancelled
+	case client.VerdictOutputLimitExceeded:
+		return exitOutputLimit
+	}
+	return result.ExitCode
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage:
+  rce [flags] FILE            run the file, reading the input from --input or stdin
+  rce judge [flags] FILE DIR  run the file against the *.in/*.out test pairs in DIR
+
+Run "rce -h" or "rce judge -h" for the flags.`)
+}
+
+// run implements the default command, it returns the exit code of the process.
+func run(args []string) int {
+	var opts options
+	flags := flag.NewFlagSet("rce", flag.ContinueOnError)
+	opts.register(flags)
+	inputPath := flags.String("input", "", "File the input is read from, stdin is used if it is piped")
+	runs := flags.Int("benchmark", 0, "Run the program this many times and print statistics of the runs to stderr")
+	warmups := flags.Int("warmups", 0, "Unmeasured runs before the runs of a benchmark")
+	deterministic := flags.Bool("deterministic", false, "Run the code in a fixed environment and print its image digest to stderr")
+	seed := flags.Int64("seed", 0, "Seed of the random number env vars of a deterministic run")
+	imageDigest := flags.String("image-digest", "", "Digest the image must have, e.g. the one of a disputed run, implies --deterministic")
+	flags.Usage = func() {
+		usage()
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		usage()
+		return exitUsage
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	ctx, cancelTimeout := context.WithTimeout(ctx, opts.timeout)
+	defer cancelTimeout()
+
+	c := opts.newClient()
+	submission, err := opts.newSubmission(ctx, c, flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rce:", err)
+		return exitEngineError
+	}
+
+	if submission.Input, err = readInput(*inputPath); err != nil {
+		fmt.Fprintln(os.Stderr, "rce:", err)
+		return exitUsage
+	}
+	if *runs > 0 {
+		submission.Benchmark = &client.Benchmark{Runs: *runs, Warmups: *warmups}
+	}
+	if *deterministic || *imageDigest != "" {
+		submission.Deterministic = &client.Deterministic{Seed: *seed, ImageDigest: *imageDigest}
+	}
+
+	// Interrupting closes the stream, which kills the program.
+	result, err := c.Stream(ctx, submission, os.Stdout, os.Stderr)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return exitCancelled
+		}
+		fmt.Fprintln(os.Stderr, "rce:", err)
+		return exitEngineError
+	}
+
+	if result.Verdict != client.VerdictOK {
+		fmt.Fprintf(os.Stderr, "rce: %s, exit code %d\n", result.Verdict, result.ExitCode)
+	}
+	if result.Benchmark != nil {
+		printBenchmark(os.Stderr, result.Benchmark)
+	}
+	if result.Environment != nil {
+		fmt.Fprintf(os.Stderr, "rce: deterministic run of %s with the seed %d\n", result.Environment.ImageDigest, result.Environment.Seed)
+	}
+	return exitCode(result)
+}
+
+// printBenchmark prints a table with the statistics of every metric of the benchmark.
+func printBenchmark(w io.Writer, benchmark *client.BenchmarkResult) {
+	fmt.Fprintf(w, "\n%d runs after %d warmups\n", benchmark.Runs, benchmark.Warmups)
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "\tMIN\tMEDIAN\tP95\tSTDDEV\t")
+	for _, metric := range []struct {
+		name    string
+		summary client.Summary
+	}{
+		{"wall time (ms)", benchmark.WallTimeMs},
+		{"cpu time (ms)", benchmark.CPUTimeMs},
+		{"peak memory (KB)", benchmark.PeakMemoryKB},
+	} {
+		s := metric.summary
+		fmt.Fprintf(table, "%s\t%.1f\t%.1f\t%.1f\t%.1f\t\n", metric.name, s.Min, s.Median, s.P95, s.Stddev)
+	}
+	_ = table.Flush()
+}
+
+// readInput reads the input file, or stdin if it isn't a terminal.
+func readInput(path string) ([]byte, error) {
+	if path != "" {
+		input, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the input: %w", err)
+		}
+		return input, nil
+	}
+
+	stat, err := os.Stdin.Stat()
+	if err != nil || stat.Mode()&os.ModeCharDevice != 0 {
+		return nil, nil
+	}
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the input from stdin: %w", err)
+	}
+	return input, nil
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "judge" {
+		os.Exit(runJudge(os.Args[2:]))
+	}
+	os.Exit(run(os.Args[1:]))
+}
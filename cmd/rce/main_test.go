@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"remote-code-engine/pkg/client"
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/languages" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"languages": [
+			{"name": "python", "extension": ".py"},
+			{"name": "cpp", "extension": ".cpp"},
+			{"name": "c", "extension": ".h"},
+			{"name": "objc", "extension": ".h"}
+		]}`)
+	}))
+	defer server.Close()
+	c := client.New(server.URL, client.WithRetries(0, 0))
+
+	tests := []struct {
+		name        string
+		path        string
+		expected    string
+		expectedErr string
+	}{
+		{name: "single match", path: "solution/main.py", expected: "python"},
+		{name: "no extension", path: "Makefile", expectedErr: "has no extension"},
+		{name: "unknown extension", path: "main.rs", expectedErr: "no language of the server uses the extension .rs"},
+		{name: "ambiguous extension", path: "util.h", expectedErr: "the languages c, objc use the extension .h"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			language, err := detectLanguage(context.Background(), c, tt.path)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("expected an error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil || language != tt.expected {
+				t.Errorf("expected the language %q, got %q and %v", tt.expected, language, err)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   client.Result
+		expected int
+	}{
+		{name: "ok", result: client.Result{Verdict: client.VerdictOK}, expected: 0},
+		{name: "exit code of the program", result: client.Result{Verdict: client.VerdictRuntimeError, ExitCode: 3}, expected: 3},
+		{name: "time limit", result: client.Result{Verdict: client.VerdictTimeLimitExceeded}, expected: exitTimeLimit},
+		{name: "internal error", result: client.Result{Verdict: client.VerdictInternalError, ExitCode: 1}, expected: exitEngineError},
+		{name: "cancelled", result: client.Result{Verdict: client.VerdictCancelled}, expected: exitCancelled},
+		{name: "output limit", result: client.Result{Verdict: client.VerdictOutputLimitExceeded}, expected: exitOutputLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := exitCode(&tt.result); code != tt.expected {
+				t.Errorf("expected the exit code %d, got %d", tt.expected, code)
+			}
+		})
+	}
+}
@@ -1,60 +1,255 @@
 package main
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"net/http"
 	"remote-code-engine/pkg/config"
 	codecontainer "remote-code-engine/pkg/container"
+	"remote-code-engine/pkg/inputs"
+	"remote-code-engine/pkg/store"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-func RegisterRoutes(r *gin.Engine, client codecontainer.ContainerClient, config *config.ImageConfig) {
-	r.POST("/api/v1/submit", func(ctx *gin.Context) {
-		var req Request
-		if err := ctx.BindJSON(&req); err != nil {
+// Room for the form fields sent along with an archive.
+const multipartOverhead = 1024 * 1024
+
+// RegisterRoutes registers the API, submissionStore is nil if the submission history is disabled.
+// Every error, including unknown routes and panics, is answered with an ErrorResponse.
+func RegisterRoutes(r *gin.Engine, submitter *submitter, serverConfig *config.ServerConfig, configStore *config.Store, submissionStore store.Store) {
+	r.Use(requestID(), recovery())
+	auth := newAuthenticator(serverConfig)
+	r.NoRoute(func(ctx *gin.Context) {
+		abortWithError(ctx, http.StatusNotFound, ErrorCodeNotFound, "Route not found")
+	})
+
+	r.GET("/api/v1/openapi.json", func(ctx *gin.Context) {
+		document, err := newOpenAPIDocument(configStore.Load(), serverConfig)
+		if err != nil {
+			logger.Error("failed to create the OpenAPI document", zap.Error(err))
+			writeError(ctx, err, "Failed to create the OpenAPI document")
 			return
 		}
+		ctx.Data(http.StatusOK, "application/json", document)
+	})
 
-		logger.Info("received a request at",
-			zap.String("route", "/api/v1/submit"),
-			zap.Any("request params", req),
-		)
+	r.POST("/api/v1/submit", validateSubmission(configStore, serverConfig), func(ctx *gin.Context) {
+		// Load the config once so the request is served with the same config even if it gets reloaded.
+		config := configStore.Load()
+		req := submittedRequest(ctx)
+
+		logRequest(ctx, "/api/v1/submit", req)
 
-		if !config.IsLanguageSupported(req.Language) {
-			logger.Error("unsupported language",
-				zap.String("language", string(req.Language)),
-			)
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language"})
+		response, err := submitter.submit(ctx, config, req, newFiles(req.Files), newCaller(ctx, auth))
+		writeSubmitResponse(ctx, response, err)
+	})
+
+	r.POST("/api/v1/submit/stream", validateSubmission(configStore, serverConfig), func(ctx *gin.Context) {
+		config := configStore.Load()
+		req := submittedRequest(ctx)
+
+		logRequest(ctx, "/api/v1/submit/stream", req)
+
+		if req.Async || req.CallbackURL != "" {
+			abortWithError(ctx, http.StatusBadRequest, ErrorCodeInvalidRequest,
+				"Streamed submissions are synchronous, remove async and callback_url")
 			return
 		}
 
-		code := &codecontainer.Code{
-			EncodedCode:    req.EncodedCode,
-			EncodedInput:   req.EncodedInput,
-			Language:       req.Language,
-			LanguageConfig: config.GetLanguageConfig(req.Language),
+		p, err := submitter.prepare(config, req, newFiles(req.Files), newCaller(ctx, auth))
+		if err != nil {
+			writeSubmitResponse(ctx, nil, err)
+			return
 		}
 
-		logger.Info("created a code execution request",
-			zap.Any("Language", code.Language),
+		stream := newEventStream(ctx)
+		_ = stream.send(StreamEvent{Type: StreamEventStarted, ID: p.submission.ID, Version: p.code.Version})
+		// Disconnecting cancels the request context, which kills the container.
+		response, err := submitter.execute(ctx, p, stream.output("stdout"), stream.output("stderr"))
+		if err != nil {
+			// The status is sent already, the error is the last event instead.
+			_ = stream.send(StreamEvent{Type: StreamEventError, Error: newErrorInfo(ctx, toAPIError(err, "Failed to execute code"))})
+			return
+		}
+		_ = stream.send(StreamEvent{Type: StreamEventResult, Result: response})
+	})
+
+	r.POST("/api/v1/submit/archive", func(ctx *gin.Context) {
+		config := configStore.Load()
+
+		maxArchiveSize := serverConfig.MaxArchiveSizeMB * 1024 * 1024
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxArchiveSize+multipartOverhead)
+
+		var form ArchiveRequest
+		if err := ctx.ShouldBind(&form); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				abortWithError(ctx, http.StatusRequestEntityTooLarge, ErrorCodePayloadTooLarge, "The archive is too large")
+				return
+			}
+			writeError(ctx, newBindingError(err), "")
+			return
+		}
+
+		logger.Info("received a request at",
+			zap.String("route", "/api/v1/submit/archive"),
+			zap.String("request id", ctx.GetString(requestIDKey)),
+			zap.String("language", string(form.Language)),
+			zap.String("archive", form.Archive.Filename),
+			zap.Int64("archive size", form.Archive.Size),
 		)
 
-		output, err := client.ExecuteCode(ctx, code)
+		archive, err := form.Archive.Open()
 		if err != nil {
-			logger.Error("Error executing code", zap.Error(err))
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute code"})
+			logger.Error("failed to open the archive", zap.Error(err))
+			writeError(ctx, err, "Failed to read the archive")
+			return
 		}
+		defer func() {
+			_ = archive.Close()
+		}()
 
-		logger.Info("request completed")
-		ctx.JSON(http.StatusOK, Response{
-			Output: output,
+		files, err := codecontainer.ReadArchive(archive, form.Archive.Size, codecontainer.ArchiveLimits{
+			MaxSize:  maxArchiveSize,
+			MaxFiles: codecontainer.MaxSubmissionFiles,
 		})
+		if err != nil {
+			logger.Error("invalid archive", zap.Error(err))
+			writeError(ctx, invalidSubmission(err), "")
+			return
+		}
+
+		var benchmark *BenchmarkRequest
+		if form.BenchmarkRuns != 0 {
+			benchmark = &BenchmarkRequest{Runs: form.BenchmarkRuns, Warmups: form.BenchmarkWarmups}
+		}
+		var deterministic *DeterministicRequest
+		if form.Deterministic {
+			deterministic = &DeterministicRequest{Seed: form.Seed, ImageDigest: form.ImageDigest}
+		}
+		req := Request{
+			EncodedInput:  base64.StdEncoding.EncodeToString([]byte(form.Input)),
+			InputID:       form.InputID,
+			Language:      form.Language,
+			Version:       form.Version,
+			Entrypoint:    form.Entrypoint,
+			Artifacts:     form.Artifacts,
+			NoCache:       form.NoCache,
+			Benchmark:     benchmark,
+			Deterministic: deterministic,
+			Async:         form.Async,
+			CallbackURL:   form.CallbackURL,
+		}
+		if err := validateArchiveRequest(req, files, config, serverConfig); err != nil {
+			writeError(ctx, err, "")
+			return
+		}
+		response, err := submitter.submit(ctx, config, req, files, newCaller(ctx, auth))
+		writeSubmitResponse(ctx, response, err)
+	})
+
+	r.POST("/api/v1/submissions/:id/cancel", func(ctx *gin.Context) {
+		if err := submitter.cancel(ctx, ctx.Param("id"), auth.identifyRequest(ctx)); err != nil {
+			if !errors.Is(err, errSubmissionNotFound) && !errors.Is(err, errSubmissionNotRunning) {
+				logger.Error("failed to cancel the submission", zap.Error(err))
+			}
+			writeError(ctx, err, "Failed to cancel the submission")
+			return
+		}
+		ctx.Status(http.StatusAccepted)
 	})
 
 	r.GET("/api/v1/languages", func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, gin.H{
-			"languages": config.GetSupportedLanguages(),
+			"languages": newCatalog(serverConfig, configStore.Load()),
 		})
 	})
+
+	r.GET("/api/v1/languages/:lang", func(ctx *gin.Context) {
+		imageConfig := configStore.Load()
+		lang := config.Language(ctx.Param("lang"))
+		if !imageConfig.IsLanguageSupported(lang) {
+			abortWithError(ctx, http.StatusNotFound, ErrorCodeNotFound, "Unsupported language")
+			return
+		}
+
+		ctx.JSON(http.StatusOK, newLanguageInfo(serverConfig, lang, imageConfig.GetLanguageConfig(lang)))
+	})
+
+	if submitter.inputs != nil {
+		registerInputRoutes(r, submitter.inputs, serverConfig)
+	}
+	if submissionStore != nil {
+		registerSubmissionRoutes(r, submissionStore, auth)
+	}
+}
+
+// registerInputRoutes registers the upload of inputs, which are referenced by submissions with their ID.
+func registerInputRoutes(r *gin.Engine, inputStore *inputs.Store, serverConfig *config.ServerConfig) {
+	r.POST("/api/v1/inputs", func(ctx *gin.Context) {
+		// The raw body is streamed to the store, it is never held in memory.
+		maxInputSize := serverConfig.MaxInputSizeMB * 1024 * 1024
+		input, err := inputStore.Save(ctx.Request.Body, maxInputSize)
+		if err != nil {
+			if errors.Is(err, inputs.ErrTooLarge) {
+				abortWithError(ctx, http.StatusRequestEntityTooLarge, ErrorCodePayloadTooLarge,
+					fmt.Sprintf("The input is larger than %d MB", serverConfig.MaxInputSizeMB))
+				return
+			}
+			logger.Error("failed to save the input", zap.Error(err))
+			writeError(ctx, err, "Failed to save the input")
+			return
+		}
+
+		logger.Info("uploaded an input",
+			zap.String("id", input.ID),
+			zap.Int64("size", input.Size),
+		)
+		ctx.JSON(http.StatusCreated, InputInfo{ID: input.ID, Size: input.Size})
+	})
+}
+
+// writeSubmitResponse answers a submission, asynchronous submissions are answered with 202.
+func writeSubmitResponse(ctx *gin.Context, response *Response, err error) {
+	switch {
+	case err != nil:
+		writeError(ctx, err, "Failed to execute code")
+	case response.Status == store.StatusRunning:
+		ctx.JSON(http.StatusAccepted, response)
+	default:
+		ctx.JSON(http.StatusOK, response)
+	}
+}
+
+// logRequest logs what a submission asks for, the code, the input and the files are only logged by their size.
+func logRequest(ctx *gin.Context, route string, req Request) {
+	logger.Info("received a request at",
+		zap.String("route", route),
+		zap.String("request id", ctx.GetString(requestIDKey)),
+		zap.String("language", string(req.Language)),
+		zap.String("version", req.Version),
+		zap.Int("code size", len(req.EncodedCode)),
+		zap.Int("input size", len(req.EncodedInput)),
+		zap.Int("files", len(req.Files)),
+	)
+}
+
+// newCaller identifies the caller by its tenant and the address of the connection. Forwarded headers aren't
+// trusted, they could be set by the caller.
+func newCaller(ctx *gin.Context, auth *authenticator) caller {
+	return caller{tenant: auth.identifyRequest(ctx).tenant, address: ctx.RemoteIP()}
+}
+
+func newFiles(files []File) []codecontainer.File {
+	var codeFiles []codecontainer.File
+	for _, file := range files {
+		codeFiles = append(codeFiles, codecontainer.File{
+			Path:           file.Path,
+			EncodedContent: file.EncodedContent,
+		})
+	}
+	return codeFiles
 }
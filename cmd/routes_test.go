@@ -0,0 +1,647 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"remote-code-engine/pkg/config"
+	codecontainer "remote-code-engine/pkg/container"
+	"remote-code-engine/pkg/inputs"
+	"remote-code-engine/pkg/store"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeClient returns the configured result or error instead of running a container.
+type fakeClient struct {
+	codecontainer.ContainerClient
+	result codecontainer.Result
+	err    error
+	// If set, executions signal started and block until release is closed.
+	started chan struct{}
+	release chan struct{}
+}
+
+func (f *fakeClient) StreamCode(ctx context.Context, code *codecontainer.Code, stdout, stderr io.Writer) (*codecontainer.Result, error) {
+	if f.release != nil {
+		f.started <- struct{}{}
+		<-f.release
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	result := f.result
+	_, _ = io.WriteString(stdout, result.Output)
+	return &result, nil
+}
+
+func newTestRouter(client codecontainer.ContainerClient, modify func(*config.ServerConfig)) *gin.Engine {
+	return newTestRouterWithStore(client, nil, modify)
+}
+
+// newTestRouterWithStore returns a router recording the submissions in the store.
+func newTestRouterWithStore(client codecontainer.ContainerClient, submissionStore store.Store, modify func(*config.ServerConfig)) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	serverConfig := config.DefaultServerConfig()
+	// Uploads are enabled by tests which set an input directory.
+	serverConfig.InputDir = ""
+	if modify != nil {
+		modify(&serverConfig)
+	}
+	var inputStore *inputs.Store
+	if serverConfig.InputDir != "" {
+		var err error
+		if inputStore, err = inputs.NewStore(serverConfig.InputDir); err != nil {
+			panic(err)
+		}
+	}
+	configStore := config.NewStore(&config.ImageConfig{
+		"python": config.LanguageConfig{
+			Extension: ".py",
+			Image:     "python:3.12",
+			Command:   "python3 {{FILE}} < {{INPUT}}",
+		},
+	})
+
+	r := gin.New()
+	RegisterRoutes(r, newSubmitter(client, &serverConfig, submissionStore, inputStore), &serverConfig, configStore, submissionStore)
+	return r
+}
+
+func newTestRequest(body string, header http.Header) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/submit", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	return req
+}
+
+func submitBody(language string) string {
+	return fmt.Sprintf(`{"language": %q, "code": %q}`, language, base64.StdEncoding.EncodeToString([]byte("print(1)")))
+}
+
+func decodeError(t *testing.T, recorder *httptest.ResponseRecorder) ErrorInfo {
+	t.Helper()
+	var body ErrorResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || body.Error == nil {
+		t.Fatalf("expected an error envelope, got %q: %v", recorder.Body.String(), err)
+	}
+	return *body.Error
+}
+
+func TestErrorResponses(t *testing.T) {
+	tests := []struct {
+		name           string
+		client         *fakeClient
+		body           string
+		encoding       string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "malformed JSON",
+			client:         &fakeClient{},
+			body:           `{"language": `,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   ErrorCodeInvalidRequest,
+		},
+		{
+			name:           "unsupported language",
+			client:         &fakeClient{},
+			body:           submitBody("cobol"),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   ErrorCodeUnsupportedLanguage,
+		},
+		{
+			name:           "code which isn't base64",
+			client:         &fakeClient{},
+			body:           `{"language": "python", "code": "print(1)"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   ErrorCodeInvalidEncoding,
+		},
+		{
+			name:           "invalid file path",
+			client:         &fakeClient{},
+			body:           `{"language": "python", "files": [{"path": "../main.py", "content": "cHJpbnQoMSk="}]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   ErrorCodeInvalidSubmission,
+		},
+		{
+			name:           "image missing",
+			client:         &fakeClient{err: fmt.Errorf("failed to create a container: %w", codecontainer.ErrImageNotFound)},
+			body:           submitBody("python"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   ErrorCodeImageNotFound,
+		},
+		{
+			name:           "image changed",
+			client:         &fakeClient{err: fmt.Errorf("failed to pin the image: %w", codecontainer.ErrImageChanged)},
+			body:           submitBody("python"),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   ErrorCodeImageChanged,
+		},
+		{
+			name:           "daemon unavailable",
+			client:         &fakeClient{err: fmt.Errorf("failed to create a container: %w", codecontainer.ErrDaemonUnavailable)},
+			body:           submitBody("python"),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   ErrorCodeDaemonUnavailable,
+		},
+		{
+			name:           "other failure",
+			client:         &fakeClient{err: errors.New("failed to create code and input files")},
+			body:           submitBody("python"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   ErrorCodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(tt.client, nil)
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, newTestRequest(tt.body, http.Header{requestIDHeader: {"req-1"}}))
+
+			if recorder.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+			info := decodeError(t, recorder)
+			if info.Code != tt.expectedCode {
+				t.Errorf("expected code %q, got %q", tt.expectedCode, info.Code)
+			}
+			if info.Message == "" {
+				t.Error("expected a message")
+			}
+			if info.RequestID != "req-1" || recorder.Header().Get(requestIDHeader) != "req-1" {
+				t.Errorf("expected the request ID req-1 in the body and the header, got %q and %q",
+					info.RequestID, recorder.Header().Get(requestIDHeader))
+			}
+		})
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	client := &fakeClient{started: make(chan struct{}), release: make(chan struct{})}
+	r := newTestRouter(client, func(s *config.ServerConfig) {
+		s.MaxConcurrentExecutions = 1
+		s.MaxQueuedExecutions = 0
+	})
+
+	done := make(chan int)
+	go func() {
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, newTestRequest(submitBody("python"), nil))
+		done <- recorder.Code
+	}()
+	<-client.started
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, newTestRequest(submitBody("python"), nil))
+	close(client.release)
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if info := decodeError(t, recorder); info.Code != ErrorCodeQueueFull {
+		t.Errorf("expected code %q, got %q", ErrorCodeQueueFull, info.Code)
+	}
+	if recorder.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+	if status := <-done; status != http.StatusOK {
+		t.Errorf("expected the running submission to finish with 200, got %d", status)
+	}
+}
+
+func TestQuotaExceeded(t *testing.T) {
+	r := newTestRouter(&fakeClient{result: codecontainer.Result{Verdict: codecontainer.VerdictOK}}, func(s *config.ServerConfig) {
+		s.TenantQuota = 1
+		s.TenantQuotaWindow = time.Minute
+		s.TenantTokens = []string{"cs101=t1"}
+	})
+
+	tenant := http.Header{authorizationHeader: {"Bearer t1"}}
+	expected := []int{http.StatusOK, http.StatusTooManyRequests}
+	for i, status := range expected {
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, newTestRequest(submitBody("python"), tenant))
+		if recorder.Code != status {
+			t.Fatalf("submission %d: expected status %d, got %d: %s", i, status, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, newTestRequest(submitBody("python"), tenant))
+	info := decodeError(t, recorder)
+	if info.Code != ErrorCodeQuotaExceeded {
+		t.Errorf("expected code %q, got %q", ErrorCodeQuotaExceeded, info.Code)
+	}
+	if recorder.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected to retry after 60 seconds, got %q", recorder.Header().Get("Retry-After"))
+	}
+
+	// Submissions without a tenant are limited by the address of the caller.
+	for i, status := range expected {
+		recorder = httptest.NewRecorder()
+		r.ServeHTTP(recorder, newTestRequest(submitBody("python"), nil))
+		if recorder.Code != status {
+			t.Fatalf("anonymous submission %d: expected status %d, got %d: %s", i, status, recorder.Code, recorder.Body.String())
+		}
+	}
+	// The tenant header isn't trusted, claiming other tenants doesn't escape the quota of the address.
+	for _, tenant := range []string{"cs102", "cs103"} {
+		recorder = httptest.NewRecorder()
+		r.ServeHTTP(recorder, newTestRequest(submitBody("python"), http.Header{tenantHeader: {tenant}}))
+		if recorder.Code != http.StatusTooManyRequests {
+			t.Errorf("expected a submission claiming the tenant %s to be limited, got %d: %s", tenant, recorder.Code, recorder.Body.String())
+		}
+	}
+	recorder = httptest.NewRecorder()
+	req := newTestRequest(submitBody("python"), nil)
+	req.RemoteAddr = "198.51.100.7:4321"
+	r.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected a submission from another address to pass, got %d", recorder.Code)
+	}
+}
+
+func TestQuotaChargedAfterQueue(t *testing.T) {
+	// The last execution signals its start without a receiver.
+	client := &fakeClient{started: make(chan struct{}, 2), release: make(chan struct{})}
+	r := newTestRouter(client, func(s *config.ServerConfig) {
+		s.MaxConcurrentExecutions = 1
+		s.MaxQueuedExecutions = 0
+		s.TenantQuota = 2
+		s.TenantQuotaWindow = time.Minute
+		s.TenantTokens = []string{"cs101=t1"}
+	})
+	tenant := http.Header{authorizationHeader: {"Bearer t1"}}
+
+	done := make(chan int)
+	go func() {
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, newTestRequest(submitBody("python"), tenant))
+		done <- recorder.Code
+	}()
+	<-client.started
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, newTestRequest(submitBody("python"), tenant))
+	close(client.release)
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected the full queue to reject the submission, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if status := <-done; status != http.StatusOK {
+		t.Fatalf("expected the running submission to finish with 200, got %d", status)
+	}
+
+	// The rejected submission didn't count against the quota.
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, newTestRequest(submitBody("python"), tenant))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected the second accepted submission to pass, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	r := newTestRouter(&fakeClient{}, nil)
+
+	for _, path := range []string{"/api/v1/unknown", "/api/v1/languages/cobol"} {
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected status 404, got %d", path, recorder.Code)
+		}
+		info := decodeError(t, recorder)
+		if info.Code != ErrorCodeNotFound {
+			t.Errorf("%s: expected code %q, got %q", path, ErrorCodeNotFound, info.Code)
+		}
+		// The ID is generated if the client didn't send one.
+		if info.RequestID == "" || info.RequestID != recorder.Header().Get(requestIDHeader) {
+			t.Errorf("%s: expected a generated request ID, got %q", path, info.RequestID)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/submissions/1/cancel", nil))
+	if recorder.Code != http.StatusNotFound || decodeError(t, recorder).Code != ErrorCodeNotFound {
+		t.Errorf("expected cancelling an unknown submission to answer not_found, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+// inputClient records the input the code is executed with.
+type inputClient struct {
+	codecontainer.ContainerClient
+	code *codecontainer.Code
+}
+
+func (c *inputClient) StreamCode(ctx context.Context, code *codecontainer.Code, stdout, stderr io.Writer) (*codecontainer.Result, error) {
+	c.code = code
+	return &codecontainer.Result{Verdict: codecontainer.VerdictOK}, nil
+}
+
+func TestUploadedInputs(t *testing.T) {
+	client := &inputClient{}
+	r := newTestRouter(client, func(s *config.ServerConfig) {
+		s.InputDir = t.TempDir()
+		s.MaxBodySizeMB = 1
+		s.MaxInputSizeMB = 2
+	})
+
+	upload := func(body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/inputs", strings.NewReader(body)))
+		return recorder
+	}
+
+	// Inputs larger than the body limit can be uploaded.
+	input := strings.Repeat("1 2 3\n", 300*1024)
+	recorder := upload(input)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var info InputInfo
+	if err := json.Unmarshal(recorder.Body.Bytes(), &info); err != nil {
+		t.Fatalf("failed to decode the response: %v", err)
+	}
+	if info.Size != int64(len(input)) {
+		t.Errorf("expected the size %d, got %d", len(input), info.Size)
+	}
+
+	body := fmt.Sprintf(`{"language": "python", "code": "cHJpbnQoMSk=", "input_id": %q}`, info.ID)
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, newTestRequest(body, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if client.code.InputID != info.ID || client.code.InputPath == "" {
+		t.Errorf("expected the code to use the uploaded input, got %q at %q", client.code.InputID, client.code.InputPath)
+	}
+
+	body = fmt.Sprintf(`{"language": "python", "code": "cHJpbnQoMSk=", "input_id": %q}`, strings.Repeat("0", 64))
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, newTestRequest(body, nil))
+	if recorder.Code != http.StatusBadRequest || decodeError(t, recorder).Code != ErrorCodeInputNotFound {
+		t.Errorf("expected an unknown input to answer input_not_found, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = upload(strings.Repeat("x", 2*1024*1024+1))
+	if recorder.Code != http.StatusRequestEntityTooLarge || decodeError(t, recorder).Code != ErrorCodePayloadTooLarge {
+		t.Errorf("expected an input over the limit to answer payload_too_large, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// The body is rejected before it is decoded.
+	body = `{"language": "python", "code": "cHJpbnQoMSk=", "input": "` + strings.Repeat("A", 1024*1024) + `"}`
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, newTestRequest(body, nil))
+	if recorder.Code != http.StatusRequestEntityTooLarge || decodeError(t, recorder).Code != ErrorCodePayloadTooLarge {
+		t.Errorf("expected a body over the limit to answer payload_too_large, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestTruncatedOutput(t *testing.T) {
+	r := newTestRouter(&fakeClient{result: codecontainer.Result{
+		Output:          "xxxx",
+		Verdict:         codecontainer.VerdictOutputLimitExceeded,
+		OutputTruncated: true,
+	}}, nil)
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, newTestRequest(submitBody("python"), nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response Response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode the response: %v", err)
+	}
+	if response.Verdict != string(codecontainer.VerdictOutputLimitExceeded) || !response.Truncated || response.Output != "xxxx" {
+		t.Errorf("expected the truncated head of the output, got %+v", response)
+	}
+}
+
+func TestResourceUsage(t *testing.T) {
+	r := newTestRouter(&fakeClient{result: codecontainer.Result{
+		Output:         "42",
+		Verdict:        codecontainer.VerdictOK,
+		Usage:          &codecontainer.ResourceUsage{CPUTime: 120 * time.Millisecond, PeakMemoryBytes: 8 * 1024 * 1024},
+		ContainerUsage: &codecontainer.ResourceUsage{CPUTime: 900 * time.Millisecond, PeakMemoryBytes: 64 * 1024 * 1024},
+	}}, nil)
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, newTestRequest(submitBody("python"), nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response Response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode the response: %v", err)
+	}
+	if response.Usage == nil || *response.Usage != (UsageInfo{CPUTimeMs: 120, PeakMemoryKB: 8192}) {
+		t.Errorf("expected the usage of the program, got %+v", response.Usage)
+	}
+	if response.ContainerUsage == nil || *response.ContainerUsage != (UsageInfo{CPUTimeMs: 900, PeakMemoryKB: 65536}) {
+		t.Errorf("expected the usage of the container, got %+v", response.ContainerUsage)
+	}
+}
+
+// benchmarkClient records the benchmark the code is executed with.
+type benchmarkClient struct {
+	fakeClient
+	benchmark *codecontainer.Benchmark
+}
+
+func (c *benchmarkClient) StreamCode(ctx context.Context, code *codecontainer.Code, stdout, stderr io.Writer) (*codecontainer.Result, error) {
+	c.benchmark = code.Benchmark
+	return c.fakeClient.StreamCode(ctx, code, stdout, stderr)
+}
+
+func TestBenchmark(t *testing.T) {
+	summary := codecontainer.Summary{Min: 10, Median: 12, P95: 15, Stddev: 1.5}
+	tests := []struct {
+		name             string
+		maxBenchmarkRuns int
+		benchmark        string
+		expectedStatus   int
+	}{
+		{name: "benchmark", maxBenchmarkRuns: 20, benchmark: `{"runs": 10, "warmups": 2}`, expectedStatus: http.StatusOK},
+		{name: "too many runs", maxBenchmarkRuns: 20, benchmark: `{"runs": 21}`, expectedStatus: http.StatusBadRequest},
+		{name: "no runs", maxBenchmarkRuns: 20, benchmark: `{"warmups": 2}`, expectedStatus: http.StatusBadRequest},
+		{name: "benchmarks disabled", benchmark: `{"runs": 10}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &benchmarkClient{fakeClient: fakeClient{result: codecontainer.Result{
+				Output:    "42",
+				Verdict:   codecontainer.VerdictOK,
+				Benchmark: &codecontainer.BenchmarkResult{Runs: 10, Warmups: 2, WallTimeMs: summary, CPUTimeMs: summary, PeakMemoryKB: summary},
+			}}}
+			r := newTestRouter(client, func(s *config.ServerConfig) {
+				s.MaxBenchmarkRuns = tt.maxBenchmarkRuns
+			})
+
+			body := `{"language": "python", "code": "cHJpbnQoNDIp", "benchmark": ` + tt.benchmark + `}`
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, newTestRequest(body, nil))
+			if recorder.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				if info := decodeError(t, recorder); info.Code != ErrorCodeInvalidSubmission {
+					t.Errorf("expected code %q, got %q", ErrorCodeInvalidSubmission, info.Code)
+				}
+				return
+			}
+
+			if client.benchmark == nil || *client.benchmark != (codecontainer.Benchmark{Runs: 10, Warmups: 2}) {
+				t.Errorf("expected 10 runs after 2 warmups, got %+v", client.benchmark)
+			}
+			var response Response
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode the response: %v", err)
+			}
+			if response.Benchmark == nil || response.Benchmark.Runs != 10 || response.Benchmark.CPUTimeMs != SummaryInfo(summary) {
+				t.Errorf("expected the statistics of the benchmark, got %+v", response.Benchmark)
+			}
+		})
+	}
+}
+
+// deterministicClient records the deterministic settings the code is executed with.
+type deterministicClient struct {
+	fakeClient
+	deterministic *codecontainer.Deterministic
+}
+
+func (c *deterministicClient) StreamCode(ctx context.Context, code *codecontainer.Code, stdout, stderr io.Writer) (*codecontainer.Result, error) {
+	c.deterministic = code.Deterministic
+	return c.fakeClient.StreamCode(ctx, code, stdout, stderr)
+}
+
+func TestDeterministic(t *testing.T) {
+	const digest = "sha256:4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1"
+	tests := []struct {
+		name           string
+		deterministic  string
+		expectedStatus int
+	}{
+		{name: "seed", deterministic: `{"seed": 42}`, expectedStatus: http.StatusOK},
+		{name: "recorded digest", deterministic: `{"seed": 42, "image_digest": "` + digest + `"}`, expectedStatus: http.StatusOK},
+		{name: "negative seed", deterministic: `{"seed": -1}`, expectedStatus: http.StatusBadRequest},
+		{name: "tag instead of a digest", deterministic: `{"seed": 42, "image_digest": "python:latest"}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &deterministicClient{fakeClient: fakeClient{result: codecontainer.Result{
+				Output:  "42",
+				Verdict: codecontainer.VerdictOK,
+				Environment: &codecontainer.Environment{
+					ImageDigest: digest,
+					Command:     []string{"sh", "-c", "python3 main.py"},
+					Env:         []string{"RCE_SEED=42"},
+					Hostname:    "rce-sandbox",
+					Seed:        42,
+					TimeOffset:  -24 * time.Hour,
+					Limits:      config.Limits{TimeLimit: 2 * time.Second},
+				},
+			}}}
+			r := newTestRouter(client, nil)
+
+			body := `{"language": "python", "code": "cHJpbnQoNDIp", "deterministic": ` + tt.deterministic + `}`
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, newTestRequest(body, nil))
+			if recorder.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				if info := decodeError(t, recorder); info.Code != ErrorCodeInvalidSubmission {
+					t.Errorf("expected code %q, got %q", ErrorCodeInvalidSubmission, info.Code)
+				}
+				return
+			}
+
+			if client.deterministic == nil || client.deterministic.Seed != 42 {
+				t.Errorf("expected a deterministic run with the seed 42, got %+v", client.deterministic)
+			}
+			var response Response
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode the response: %v", err)
+			}
+			environment := response.Environment
+			if environment == nil || environment.ImageDigest != digest || environment.TimeOffsetSeconds != -86400 {
+				t.Fatalf("expected the environment of the run, got %+v", environment)
+			}
+			if environment.Limits.TimeLimitMs != 2000 {
+				t.Errorf("expected the time limit of the run, got %+v", environment.Limits)
+			}
+		})
+	}
+}
+
+func TestCancelOwnSubmissions(t *testing.T) {
+	submissionStore, err := store.OpenBolt(filepath.Join(t.TempDir(), "submissions.db"))
+	if err != nil {
+		t.Fatalf("failed to open the store: %v", err)
+	}
+	defer func() {
+		_ = submissionStore.Close()
+	}()
+	client := &fakeClient{started: make(chan struct{}), release: make(chan struct{})}
+	defer close(client.release)
+	r := newTestRouterWithStore(client, submissionStore, func(s *config.ServerConfig) {
+		s.AdminToken = "secret"
+		s.TenantTokens = []string{"cs101=t1", "cs102=t2"}
+	})
+
+	submit := func() string {
+		recorder := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"language": "python", "code": %q, "async": true}`, base64.StdEncoding.EncodeToString([]byte("print(1)")))
+		r.ServeHTTP(recorder, newTestRequest(body, http.Header{authorizationHeader: {"Bearer t1"}}))
+		if recorder.Code != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		var response Response
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode the response: %v", err)
+		}
+		<-client.started
+		return response.ID
+	}
+	cancel := func(id, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/submissions/"+id+"/cancel", nil)
+		if authorization != "" {
+			req.Header.Set(authorizationHeader, authorization)
+		}
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	id := submit()
+	for _, authorization := range []string{"", "Bearer t2"} {
+		recorder := cancel(id, authorization)
+		if recorder.Code != http.StatusNotFound || decodeError(t, recorder).Code != ErrorCodeNotFound {
+			t.Errorf("expected cancelling with %q to answer not_found, got %d: %s", authorization, recorder.Code, recorder.Body.String())
+		}
+	}
+	if recorder := cancel(id, "Bearer t1"); recorder.Code != http.StatusAccepted {
+		t.Errorf("expected the tenant to cancel its submission, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := cancel(submit(), "Bearer secret"); recorder.Code != http.StatusAccepted {
+		t.Errorf("expected the admin to cancel the submission, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
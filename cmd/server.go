@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"remote-code-engine/pkg/cache"
 	"remote-code-engine/pkg/config"
 	codecontainer "remote-code-engine/pkg/container"
-	"time"
+	"remote-code-engine/pkg/inputs"
+	"remote-code-engine/pkg/store"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -14,43 +18,61 @@ import (
 
 var logger *zap.Logger
 
-const (
-	ADDR = ":9000"
-)
-
 func init() {
 	logger, _ = zap.NewProduction()
 }
 
-func StartServer(cli codecontainer.ContainerClient, config *config.ImageConfig) error {
-	r := gin.Default()
+func StartServer(cli codecontainer.ContainerClient, serverConfig *config.ServerConfig, configStore *config.Store, submissionStore store.Store, inputStore *inputs.Store) error {
+	// RegisterRoutes installs its own recovery, which answers with the error envelope.
+	r := gin.New()
+	r.Use(gin.Logger())
 	logger.Info("starting the server",
-		zap.String("Address", ADDR),
+		zap.String("Address", serverConfig.Address),
+		zap.String("gRPC address", serverConfig.GRPCAddress),
 	)
 
 	server := &http.Server{
-		Addr:         ":9000",
+		Addr:         serverConfig.Address,
 		Handler:      r,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 60 * time.Second,
+		ReadTimeout:  serverConfig.ReadTimeout,
+		WriteTimeout: serverConfig.WriteTimeout,
+	}
+
+	// The REST and the gRPC API share the submitter, so submissions can be cancelled through either.
+	submitter := newSubmitter(cli, serverConfig, submissionStore, inputStore)
+	RegisterRoutes(r, submitter, serverConfig, configStore, submissionStore)
+
+	if serverConfig.GRPCAddress != "" {
+		listener, err := net.Listen("tcp", serverConfig.GRPCAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen on the gRPC address: %w", err)
+		}
+		grpcServer := newGRPCServer(submitter, serverConfig, configStore, submissionStore)
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Error("the gRPC server stopped",
+					zap.Error(err),
+				)
+			}
+		}()
 	}
 
-	RegisterRoutes(r, cli, config)
 	return server.ListenAndServe()
 }
 
-func setupCodeDirectory(imageConfig config.ImageConfig) {
-	for lang := range imageConfig {
-		path := config.GetHostLanguageCodePath(lang)
+func setupCodeDirectory(serverConfig *config.ServerConfig, imageConfig *config.ImageConfig) error {
+	for lang := range *imageConfig {
+		path := serverConfig.GetHostLanguageCodePath(lang)
 		if err := os.MkdirAll(path, 0755); err != nil {
 			logger.Error("failed to create the code directory for the language",
 				zap.String("language", string(lang)),
 				zap.String("path", path),
 				zap.Error(err),
 			)
-			panic(err)
+			return err
 		}
 	}
+	return nil
 }
 
 func main() {
@@ -58,10 +80,20 @@ func main() {
 		_ = logger.Sync()
 	}()
 
-	ParseFlags()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate-config":
+			_ = logger.Sync()
+			os.Exit(runValidateConfig(os.Args[2:]))
+		case "populate-cache":
+			_ = logger.Sync()
+			os.Exit(runPopulateCache(os.Args[2:]))
+		}
+	}
+
+	flags := ParseFlags()
 
-	// This should be given as a command line argument.
-	imageConfig, err := config.LoadConfig("../config.yml")
+	configFile, err := config.LoadFile(flags.ConfigPath)
 	if err != nil {
 		logger.Error("failed to load the config file",
 			zap.Error(err),
@@ -69,13 +101,26 @@ func main() {
 		panic(err)
 	}
 
-	setupCodeDirectory(*imageConfig)
+	serverConfig, err := flags.LoadServerConfig(configFile)
+	if err != nil {
+		logger.Error("invalid server config",
+			zap.Error(err),
+		)
+		panic(err)
+	}
+
+	imageConfig := &configFile.Languages
+	if err = setupCodeDirectory(serverConfig, imageConfig); err != nil {
+		panic(err)
+	}
+	configStore := config.NewStore(imageConfig)
 
 	logger.Debug("loaded the config file",
+		zap.Any("server", serverConfig),
 		zap.Any("config", imageConfig),
 	)
 
-	cli, err := codecontainer.NewDockerClient(nil, logger)
+	cli, err := codecontainer.NewDockerClient(nil, serverConfig, logger)
 	if err != nil {
 		logger.Error("failed to create a docker client",
 			zap.Error(err),
@@ -83,10 +128,53 @@ func main() {
 		panic(err)
 	}
 
+	resultCache, err := cache.New(serverConfig.ResultCache, serverConfig.ResultCacheTTL,
+		serverConfig.ResultCacheSizeMB*1024*1024, serverConfig.ResultCacheDir)
+	if err != nil {
+		logger.Error("failed to create the result cache",
+			zap.Error(err),
+		)
+		panic(err)
+	}
+	cli = codecontainer.NewCachingClient(cli, resultCache, serverConfig, logger)
+
 	ctx, cancel := context.WithCancel(context.Background())
 
+	var submissionStore store.Store
+	if serverConfig.SubmissionDB != "" {
+		boltStore, err := store.OpenBolt(serverConfig.SubmissionDB)
+		if err != nil {
+			logger.Error("failed to open the submission history",
+				zap.String("path", serverConfig.SubmissionDB),
+				zap.Error(err),
+			)
+			panic(err)
+		}
+		defer func() {
+			_ = boltStore.Close()
+		}()
+		submissionStore = boltStore
+
+		if serverConfig.SubmissionRetention > 0 {
+			go store.RunRetention(ctx, submissionStore, serverConfig.SubmissionRetention, serverConfig.GCInterval, logger)
+		}
+	}
+
+	var inputStore *inputs.Store
+	if serverConfig.InputDir != "" {
+		inputStore, err = inputs.NewStore(serverConfig.InputDir)
+		if err != nil {
+			logger.Error("failed to open the input store",
+				zap.String("path", serverConfig.InputDir),
+				zap.Error(err),
+			)
+			panic(err)
+		}
+		go inputs.RunRetention(ctx, inputStore, serverConfig.InputRetention, serverConfig.GCInterval, logger)
+	}
+
 	go func() {
-		err = cli.FreeUpZombieContainers(ctx)
+		err := cli.FreeUpZombieContainers(ctx)
 		if err != nil {
 			logger.Error("failed to free up zombie containers",
 				zap.Error(err),
@@ -94,7 +182,20 @@ func main() {
 		}
 	}()
 
-	err = StartServer(cli, imageConfig)
+	prepare := func(imageConfig *config.ImageConfig) error {
+		return setupCodeDirectory(serverConfig, imageConfig)
+	}
+	reloader := config.NewReloader(flags.ConfigPath, configStore, prepare, logger)
+	go func() {
+		err := reloader.Watch(ctx)
+		if err != nil {
+			logger.Error("failed to watch the config file",
+				zap.Error(err),
+			)
+		}
+	}()
+
+	err = StartServer(cli, serverConfig, configStore, submissionStore, inputStore)
 	if err != nil {
 		logger.Error("failed to start the server",
 			zap.Error(err),
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// eventStream writes the events of a streamed submission as NDJSON, flushing every event right away.
+// Events are written by one goroutine at a time: the output while the program runs, the result afterwards.
+type eventStream struct {
+	ctx     *gin.Context
+	encoder *json.Encoder
+}
+
+func newEventStream(ctx *gin.Context) *eventStream {
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Status(http.StatusOK)
+	return &eventStream{
+		ctx:     ctx,
+		encoder: json.NewEncoder(ctx.Writer),
+	}
+}
+
+func (s *eventStream) send(event StreamEvent) error {
+	if err := s.encoder.Encode(event); err != nil {
+		logger.Error("failed to send the stream event",
+			zap.String("type", event.Type),
+			zap.Error(err),
+		)
+		return err
+	}
+	s.ctx.Writer.Flush()
+	return nil
+}
+
+// output returns a writer sending everything written to it as output events of the stream.
+func (s *eventStream) output(stream string) *outputWriter {
+	return &outputWriter{events: s, stream: stream}
+}
+
+type outputWriter struct {
+	events *eventStream
+	stream string
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	if err := w.events.send(StreamEvent{Type: StreamEventOutput, Stream: w.stream, Data: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"remote-code-engine/pkg/config"
+	codecontainer "remote-code-engine/pkg/container"
+	"remote-code-engine/pkg/store"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func newSubmission(id, tenant string, code *codecontainer.Code, limits config.Limits, createdAt time.Time) *store.Submission {
+	submission := &store.Submission{
+		ID:           id,
+		Tenant:       tenant,
+		Language:     code.Language,
+		Version:      code.Version,
+		Entrypoint:   code.Entrypoint,
+		EncodedInput: code.EncodedInput,
+		InputID:      code.InputID,
+		Limits:       limits,
+		CreatedAt:    createdAt,
+	}
+	if code.IsProject() {
+		for _, file := range code.Files {
+			encodedContent := file.EncodedContent
+			if file.Content != nil {
+				encodedContent = base64.StdEncoding.EncodeToString(file.Content)
+			}
+			submission.Files = append(submission.Files, store.File{
+				Path:           file.Path,
+				EncodedContent: encodedContent,
+			})
+		}
+	} else {
+		submission.EncodedCode = code.EncodedCode
+	}
+	return submission
+}
+
+// newStoredEnvironment records the conditions of a deterministic run in the history, the limits are recorded for
+// every submission.
+func newStoredEnvironment(environment *codecontainer.Environment) *store.Environment {
+	if environment == nil {
+		return nil
+	}
+	return &store.Environment{
+		ImageDigest: environment.ImageDigest,
+		Command:     environment.Command,
+		Env:         environment.Env,
+		Hostname:    environment.Hostname,
+		Seed:        environment.Seed,
+		TimeOffset:  environment.TimeOffset,
+	}
+}
+
+func newSubmissionInfo(submission store.Submission) SubmissionInfo {
+	return SubmissionInfo{
+		ID:         submission.ID,
+		Tenant:     submission.Tenant,
+		Language:   submission.Language,
+		Version:    submission.Version,
+		Verdict:    submission.Verdict,
+		ExitCode:   submission.ExitCode,
+		Cached:     submission.Cached,
+		CreatedAt:  submission.CreatedAt,
+		DurationMs: submission.Duration.Milliseconds(),
+	}
+}
+
+// getSubmission returns a submission of the tenant of the caller, the submissions of other tenants are reported as
+// not found. Admins get the submissions of every tenant.
+func getSubmission(ctx context.Context, submissionStore store.Store, id string, caller identity) (*store.Submission, error) {
+	submission, err := submissionStore.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !caller.admin && submission.Tenant != caller.tenant {
+		return nil, store.ErrNotFound
+	}
+	return submission, nil
+}
+
+// parseSubmissionFilter reads the filter of the submission list from the query parameters.
+func parseSubmissionFilter(ctx *gin.Context) (store.Filter, error) {
+	filter := store.Filter{
+		Tenant:   ctx.Query("tenant"),
+		Language: config.Language(ctx.Query("language")),
+		Verdict:  ctx.Query("verdict"),
+		Cursor:   ctx.Query("cursor"),
+	}
+
+	var err error
+	for name, t := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := ctx.Query(name); value != "" {
+			if *t, err = time.Parse(time.RFC3339, value); err != nil {
+				return filter, fmt.Errorf("invalid %s %q, use RFC 3339, e.g. 2024-01-02T15:04:05Z", name, value)
+			}
+		}
+	}
+
+	if value := ctx.Query("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit <= 0 || filter.Limit > store.MaxPageSize {
+			return filter, fmt.Errorf("invalid limit %q, use a number between 1 and %d", value, store.MaxPageSize)
+		}
+	}
+	return filter, nil
+}
+
+// registerSubmissionRoutes registers the history, callers see the submissions of the tenant they authenticated as.
+// Admins see every submission and the dead letters.
+func registerSubmissionRoutes(r *gin.Engine, submissionStore store.Store, auth *authenticator) {
+	r.GET("/api/v1/submissions", func(ctx *gin.Context) {
+		filter, err := parseSubmissionFilter(ctx)
+		if err != nil {
+			abortWithError(ctx, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+			return
+		}
+		if caller := auth.identifyRequest(ctx); !caller.admin {
+			if caller.tenant == "" || (filter.Tenant != "" && filter.Tenant != caller.tenant) {
+				abortWithError(ctx, http.StatusForbidden, ErrorCodeForbidden,
+					"Only the submissions of the tenant of the token can be listed without the admin token")
+				return
+			}
+			filter.Tenant = caller.tenant
+		}
+
+		submissions, next, err := submissionStore.List(ctx, filter)
+		if err != nil {
+			if errors.Is(err, store.ErrInvalidCursor) {
+				abortWithError(ctx, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+				return
+			}
+			logger.Error("failed to list the submissions", zap.Error(err))
+			writeError(ctx, err, "Failed to list the submissions")
+			return
+		}
+
+		infos := []SubmissionInfo{}
+		for _, submission := range submissions {
+			infos = append(infos, newSubmissionInfo(submission))
+		}
+		ctx.JSON(http.StatusOK, SubmissionList{
+			Submissions: infos,
+			NextCursor:  next,
+		})
+	})
+
+	r.GET("/api/v1/submissions/:id", func(ctx *gin.Context) {
+		submission, err := getSubmission(ctx, submissionStore, ctx.Param("id"), auth.identifyRequest(ctx))
+		if err != nil {
+			if !errors.Is(err, store.ErrNotFound) {
+				logger.Error("failed to get the submission", zap.Error(err))
+			}
+			writeError(ctx, err, "Failed to get the submission")
+			return
+		}
+
+		ctx.JSON(http.StatusOK, submission)
+	})
+
+	r.GET("/api/v1/webhooks/dead-letters", func(ctx *gin.Context) {
+		if !auth.identifyRequest(ctx).admin {
+			abortWithError(ctx, http.StatusForbidden, ErrorCodeForbidden, "The dead letters need the admin token")
+			return
+		}
+
+		deadLetters, err := submissionStore.ListDeadLetters(ctx)
+		if err != nil {
+			logger.Error("failed to list the dead letters", zap.Error(err))
+			writeError(ctx, err, "Failed to list the dead letters")
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"dead_letters": deadLetters})
+	})
+}
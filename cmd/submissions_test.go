@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"remote-code-engine/pkg/store"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSubmissionHistoryTenants(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	submissionStore, err := store.OpenBolt(filepath.Join(t.TempDir(), "submissions.db"))
+	if err != nil {
+		t.Fatalf("failed to open the store: %v", err)
+	}
+	defer func() {
+		_ = submissionStore.Close()
+	}()
+	for id, tenant := range map[string]string{"1": "cs101", "2": "cs102", "3": ""} {
+		submission := &store.Submission{ID: id, Tenant: tenant, Language: "python", CreatedAt: time.Now()}
+		if err := submissionStore.Save(context.Background(), submission); err != nil {
+			t.Fatalf("failed to save the submission: %v", err)
+		}
+	}
+
+	r := gin.New()
+	registerSubmissionRoutes(r, submissionStore, &authenticator{adminToken: "secret", tenants: map[string]string{"t1": "cs101"}})
+
+	tests := []struct {
+		name           string
+		path           string
+		tenant         string
+		authorization  string
+		expectedStatus int
+		expectedIDs    []string
+	}{
+		{name: "own submission", path: "/api/v1/submissions/1", authorization: "Bearer t1", expectedStatus: http.StatusOK},
+		{name: "submission of another tenant", path: "/api/v1/submissions/2", authorization: "Bearer t1", expectedStatus: http.StatusNotFound},
+		{name: "submission of a tenant without the token", path: "/api/v1/submissions/1", expectedStatus: http.StatusNotFound},
+		{name: "untrusted tenant header", path: "/api/v1/submissions/1", tenant: "cs101", expectedStatus: http.StatusNotFound},
+		{name: "submission without a tenant", path: "/api/v1/submissions/3", expectedStatus: http.StatusOK},
+		{name: "admin gets any submission", path: "/api/v1/submissions/2", authorization: "Bearer secret", expectedStatus: http.StatusOK},
+		{name: "wrong admin token", path: "/api/v1/submissions/2", authorization: "Bearer guess", expectedStatus: http.StatusNotFound},
+		{name: "list of the tenant", path: "/api/v1/submissions", authorization: "Bearer t1", expectedStatus: http.StatusOK, expectedIDs: []string{"1"}},
+		{name: "list of another tenant", path: "/api/v1/submissions?tenant=cs102", authorization: "Bearer t1", expectedStatus: http.StatusForbidden},
+		{name: "list without a tenant", path: "/api/v1/submissions", expectedStatus: http.StatusForbidden},
+		{name: "list with an untrusted tenant header", path: "/api/v1/submissions", tenant: "cs101", expectedStatus: http.StatusForbidden},
+		{name: "admin lists a tenant", path: "/api/v1/submissions?tenant=cs102", authorization: "Bearer secret", expectedStatus: http.StatusOK, expectedIDs: []string{"2"}},
+		{name: "dead letters without the admin token", path: "/api/v1/webhooks/dead-letters", authorization: "Bearer t1", expectedStatus: http.StatusForbidden},
+		{name: "dead letters of the admin", path: "/api/v1/webhooks/dead-letters", authorization: "Bearer secret", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.tenant != "" {
+				req.Header.Set(tenantHeader, tt.tenant)
+			}
+			if tt.authorization != "" {
+				req.Header.Set(authorizationHeader, tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+			if recorder.Code == http.StatusForbidden {
+				if info := decodeError(t, recorder); info.Code != ErrorCodeForbidden {
+					t.Errorf("expected the error code %s, got %s", ErrorCodeForbidden, info.Code)
+				}
+			}
+			if tt.expectedIDs == nil {
+				return
+			}
+
+			var list SubmissionList
+			if err := json.Unmarshal(recorder.Body.Bytes(), &list); err != nil {
+				t.Fatalf("failed to decode the list: %v", err)
+			}
+			var ids []string
+			for _, submission := range list.Submissions {
+				ids = append(ids, submission.ID)
+			}
+			if len(ids) != len(tt.expectedIDs) || ids[0] != tt.expectedIDs[0] {
+				t.Errorf("expected the submissions %v, got %v", tt.expectedIDs, ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"remote-code-engine/pkg/config"
+	codecontainer "remote-code-engine/pkg/container"
+	"remote-code-engine/pkg/inputs"
+	"remote-code-engine/pkg/limit"
+	"remote-code-engine/pkg/store"
+	"remote-code-engine/pkg/webhook"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// submitter executes submissions, records them in the history and delivers their results to callbacks.
+type submitter struct {
+	client       codecontainer.ContainerClient
+	serverConfig *config.ServerConfig
+	// nil if the submission history is disabled.
+	submissionStore store.Store
+	// nil if callbacks are disabled.
+	webhooks *webhook.Sender
+	queue    *limit.Queue
+	quota    *limit.Quota
+	// nil if uploaded inputs are disabled.
+	inputs *inputs.Store
+
+	// The running executions by submission ID, see runningSubmission.
+	running sync.Map
+}
+
+// runningSubmission can be cancelled by the callers of its tenant.
+type runningSubmission struct {
+	tenant string
+	cancel context.CancelFunc
+}
+
+func newSubmitter(client codecontainer.ContainerClient, serverConfig *config.ServerConfig, submissionStore store.Store, inputStore *inputs.Store) *submitter {
+	s := &submitter{
+		client:          client,
+		serverConfig:    serverConfig,
+		submissionStore: submissionStore,
+		inputs:          inputStore,
+		queue:           limit.NewQueue(serverConfig.MaxConcurrentExecutions, serverConfig.MaxQueuedExecutions),
+		quota:           limit.NewQuota(serverConfig.TenantQuota, serverConfig.TenantQuotaWindow),
+	}
+
+	if serverConfig.WebhookSecret != "" {
+		var deadLetters webhook.DeadLetterRecorder
+		if submissionStore != nil {
+			deadLetters = submissionStore
+		}
+		s.webhooks = webhook.NewSender(serverConfig.WebhookSecret, serverConfig.WebhookMaxAttempts,
+			serverConfig.WebhookInitialBackoff, serverConfig.WebhookAllowedHosts, deadLetters, logger)
+	}
+	return s
+}
+
+var (
+	errSubmissionNotFound   = errors.New("submission not found")
+	errSubmissionNotRunning = errors.New("the submission is not running")
+)
+
+// invalidSubmissionError rejects a submission because of the request, as opposed to failures of the server.
+type invalidSubmissionError struct {
+	err error
+}
+
+func (e *invalidSubmissionError) Error() string {
+	return e.err.Error()
+}
+
+func (e *invalidSubmissionError) Unwrap() error {
+	return e.err
+}
+
+func invalidSubmission(err error) error {
+	return &invalidSubmissionError{err: err}
+}
+
+// caller identifies who made a submission. Callers without a tenant are told apart by their address for the quota,
+// the tenant is only set if the caller authenticated or a trusted gateway sent it.
+type caller struct {
+	tenant  string
+	address string
+}
+
+// quotaKey returns the key the submissions of the caller are counted under, tenants and addresses can't collide.
+func (c caller) quotaKey() string {
+	if c.tenant != "" {
+		return "tenant:" + c.tenant
+	}
+	return "address:" + c.address
+}
+
+// pending is a validated submission holding a place in the execution queue until it is executed.
+type pending struct {
+	code       *codecontainer.Code
+	submission *store.Submission
+	ticket     *limit.Ticket
+}
+
+// submit executes and records a submission, files are used instead of the code of the request if there are any.
+// Asynchronous submissions are answered right away with the running status and executed in the background.
+func (s *submitter) submit(ctx context.Context, config *config.ImageConfig, req Request, files []codecontainer.File, caller caller) (*Response, error) {
+	p, err := s.prepare(config, req, files, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.Async && req.CallbackURL == "" {
+		return s.execute(ctx, p, io.Discard, io.Discard)
+	}
+
+	submission := p.submission
+	submission.Status = store.StatusRunning
+	s.record(submission)
+	go func() {
+		// The request is answered already, the execution is bounded by the time limit.
+		response, err := s.execute(context.Background(), p, io.Discard, io.Discard)
+		if err != nil {
+			response = &Response{ID: submission.ID, Status: store.StatusFinished, Verdict: submission.Verdict}
+		}
+		if req.CallbackURL != "" {
+			s.deliver(req.CallbackURL, response)
+		}
+	}()
+
+	return &Response{
+		ID:      submission.ID,
+		Status:  store.StatusRunning,
+		Version: p.code.Version,
+	}, nil
+}
+
+// prepare resolves and validates a submission and reserves its place in the execution queue. Asynchronous
+// submissions are rejected right away if the queue is full, instead of failing in the background. The submission
+// is counted against the quota of the caller once the queue accepted it.
+func (s *submitter) prepare(config *config.ImageConfig, req Request, files []codecontainer.File, caller caller) (*pending, error) {
+	createdAt := time.Now()
+
+	langConfig, version, err := config.Resolve(req.Language, req.Version)
+	if err != nil {
+		logger.Error("unsupported language",
+			zap.String("language", string(req.Language)),
+			zap.String("version", req.Version),
+			zap.Error(err),
+		)
+		return nil, invalidSubmission(err)
+	}
+	if req.Limits != nil {
+		langConfig.Limits, err = tightenLimits(langConfig.Limits, s.serverConfig.EffectiveLimits(langConfig.Limits), *req.Limits)
+		if err != nil {
+			return nil, invalidSubmission(err)
+		}
+	}
+
+	var benchmark *codecontainer.Benchmark
+	if req.Benchmark != nil {
+		if benchmark, err = s.newBenchmark(*req.Benchmark); err != nil {
+			return nil, invalidSubmission(err)
+		}
+	}
+
+	var deterministic *codecontainer.Deterministic
+	if req.Deterministic != nil {
+		if deterministic, err = newDeterministic(*req.Deterministic); err != nil {
+			return nil, invalidSubmission(err)
+		}
+	}
+
+	var inputPath string
+	if req.InputID != "" {
+		if s.inputs == nil {
+			return nil, invalidSubmission(errors.New("Uploaded inputs are disabled, send the input with the submission"))
+		}
+		if inputPath, err = s.inputs.Path(req.InputID); err != nil {
+			if errors.Is(err, inputs.ErrNotFound) {
+				return nil, invalidSubmission(err)
+			}
+			return nil, err
+		}
+	}
+
+	code := &codecontainer.Code{
+		EncodedCode:    req.EncodedCode,
+		EncodedInput:   req.EncodedInput,
+		InputID:        req.InputID,
+		InputPath:      inputPath,
+		Files:          files,
+		Entrypoint:     req.Entrypoint,
+		Artifacts:      req.Artifacts,
+		NoCache:        req.NoCache,
+		Benchmark:      benchmark,
+		Deterministic:  deterministic,
+		Encoding:       codecontainer.Encoding(req.Encoding),
+		Language:       req.Language,
+		Version:        version,
+		LanguageConfig: langConfig,
+	}
+
+	if err := code.Validate(); err != nil {
+		logger.Error("invalid submission", zap.Error(err))
+		return nil, invalidSubmission(err)
+	}
+
+	async := req.Async || req.CallbackURL != ""
+	if req.CallbackURL != "" {
+		if s.webhooks == nil {
+			return nil, invalidSubmission(errors.New("Callbacks are disabled, the server has no webhook secret"))
+		}
+		if err := s.webhooks.ValidateCallbackURL(req.CallbackURL); err != nil {
+			return nil, invalidSubmission(err)
+		}
+	} else if async && s.submissionStore == nil {
+		return nil, invalidSubmission(errors.New("Asynchronous submissions need a callback_url when the submission history is disabled"))
+	}
+
+	ticket, err := s.queue.Reserve()
+	if err != nil {
+		logger.Info("rejected a submission, the execution queue is full")
+		return nil, err
+	}
+	if retryAfter, err := s.quota.Allow(caller.quotaKey()); err != nil {
+		ticket.Release()
+		logger.Info("rejected a submission over the quota",
+			zap.String("tenant", caller.tenant),
+			zap.String("address", caller.address),
+		)
+		return nil, &quotaExceededError{retryAfter: retryAfter}
+	}
+
+	logger.Info("created a code execution request",
+		zap.Any("Language", code.Language),
+		zap.String("Version", code.Version),
+		zap.Int("Files", len(code.Files)),
+		zap.Bool("Async", async),
+	)
+
+	submission := newSubmission(uuid.New().String(), caller.tenant, code,
+		s.serverConfig.EffectiveLimits(code.Limits), createdAt)
+	submission.CallbackURL = req.CallbackURL
+	return &pending{code: code, submission: submission, ticket: ticket}, nil
+}
+
+// cancel kills a running submission, it finishes with the cancelled verdict. Like the history, submissions of
+// other tenants are not found unless the caller is the admin.
+func (s *submitter) cancel(ctx context.Context, id string, caller identity) error {
+	if value, ok := s.running.Load(id); ok {
+		running := value.(runningSubmission)
+		if !caller.admin && running.tenant != caller.tenant {
+			return errSubmissionNotFound
+		}
+		running.cancel()
+		logger.Info("cancelled the submission", zap.String("id", id))
+		return nil
+	}
+
+	if s.submissionStore != nil {
+		_, err := getSubmission(ctx, s.submissionStore, id, caller)
+		if err == nil {
+			return errSubmissionNotRunning
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("failed to get the submission: %w", err)
+		}
+	}
+	return errSubmissionNotFound
+}
+
+// execute waits for a free execution slot and runs the code, copying its output to stdout and stderr, and records
+// the finished submission. The execution can be cancelled by the ID of the submission while it waits or runs.
+func (s *submitter) execute(ctx context.Context, p *pending, stdout, stderr io.Writer) (*Response, error) {
+	code, submission := p.code, p.submission
+	defer p.ticket.Release()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.running.Store(submission.ID, runningSubmission{tenant: submission.Tenant, cancel: cancel})
+	defer s.running.Delete(submission.ID)
+
+	submission.Status = store.StatusFinished
+
+	var result *codecontainer.Result
+	var err error
+	if waitErr := p.ticket.Wait(ctx); waitErr != nil {
+		logger.Info("cancelled the submission while it was queued", zap.String("id", submission.ID))
+		result = &codecontainer.Result{Output: "Execution cancelled", Verdict: codecontainer.VerdictCancelled}
+	} else {
+		result, err = s.client.StreamCode(ctx, code, stdout, stderr)
+	}
+	if err != nil {
+		logger.Error("Error executing code", zap.Error(err))
+		submission.Verdict = string(codecontainer.VerdictInternalError)
+		s.record(submission)
+		return nil, err
+	}
+
+	submission.Verdict = string(result.Verdict)
+	submission.ExitCode = result.ExitCode
+	submission.Output = result.Output
+	submission.Cached = result.Cached
+	submission.Duration = result.Duration
+	submission.Environment = newStoredEnvironment(result.Environment)
+	recorded := s.record(submission)
+
+	logger.Info("request completed",
+		zap.String("Verdict", string(result.Verdict)),
+		zap.Int("Artifacts", len(result.Artifacts)),
+		zap.Bool("Cached", result.Cached),
+	)
+	response := &Response{
+		Status:             store.StatusFinished,
+		Output:             result.Output,
+		Verdict:            string(result.Verdict),
+		ExitCode:           result.ExitCode,
+		Version:            code.Version,
+		ArtifactsTruncated: result.ArtifactsTruncated,
+		Truncated:          result.OutputTruncated,
+		Usage:              newUsageInfo(result.Usage),
+		ContainerUsage:     newUsageInfo(result.ContainerUsage),
+		Benchmark:          newBenchmarkInfo(result.Benchmark),
+		Environment:        newEnvironmentInfo(s.serverConfig, result.Environment),
+		Cached:             result.Cached,
+	}
+	// Callbacks are matched to their submission by the ID, even without the history.
+	if recorded || submission.CallbackURL != "" {
+		response.ID = submission.ID
+	}
+	for _, artifact := range result.Artifacts {
+		response.Artifacts = append(response.Artifacts, ArtifactInfo{
+			Path:           artifact.Path,
+			EncodedContent: artifact.EncodedContent,
+			Size:           artifact.Size,
+		})
+	}
+	return response, nil
+}
+
+// newBenchmark checks the runs of a benchmark against the max benchmark runs of the server.
+func (s *submitter) newBenchmark(req BenchmarkRequest) (*codecontainer.Benchmark, error) {
+	maxRuns := s.serverConfig.MaxBenchmarkRuns
+	if maxRuns == 0 {
+		return nil, errors.New("Benchmarks are disabled on this server")
+	}
+
+	var errs []error
+	if req.Runs < 1 || req.Runs > maxRuns {
+		errs = append(errs, fmt.Errorf("benchmark.runs: %d is out of range, use a value between 1 and %d", req.Runs, maxRuns))
+	}
+	if req.Warmups < 0 || req.Warmups > maxRuns {
+		errs = append(errs, fmt.Errorf("benchmark.warmups: %d is out of range, use a value between 0 and %d", req.Warmups, maxRuns))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &codecontainer.Benchmark{Runs: req.Runs, Warmups: req.Warmups}, nil
+}
+
+// newDeterministic checks the seed and the expected image digest of a deterministic run.
+func newDeterministic(req DeterministicRequest) (*codecontainer.Deterministic, error) {
+	var errs []error
+	// The seed is passed as PYTHONHASHSEED as well, which takes up to 32 bits.
+	if req.Seed < 0 || req.Seed > math.MaxUint32 {
+		errs = append(errs, fmt.Errorf("deterministic.seed: %d is out of range, use a value between 0 and %d", req.Seed, uint32(math.MaxUint32)))
+	}
+	if req.ImageDigest != "" && !codecontainer.ValidImageDigest(req.ImageDigest) {
+		errs = append(errs, fmt.Errorf("deterministic.image_digest: %q is not an image digest, use sha256: and 64 hex digits", req.ImageDigest))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &codecontainer.Deterministic{Seed: req.Seed, ImageDigest: req.ImageDigest}, nil
+}
+
+func newEnvironmentInfo(serverConfig *config.ServerConfig, environment *codecontainer.Environment) *EnvironmentInfo {
+	if environment == nil {
+		return nil
+	}
+	return &EnvironmentInfo{
+		ImageDigest:       environment.ImageDigest,
+		Command:           environment.Command,
+		Env:               environment.Env,
+		Hostname:          environment.Hostname,
+		Seed:              environment.Seed,
+		TimeOffsetSeconds: int64(environment.TimeOffset / time.Second),
+		Limits:            newLimitsInfo(serverConfig, environment.Limits),
+	}
+}
+
+func newBenchmarkInfo(benchmark *codecontainer.BenchmarkResult) *BenchmarkInfo {
+	if benchmark == nil {
+		return nil
+	}
+	return &BenchmarkInfo{
+		Runs:         benchmark.Runs,
+		Warmups:      benchmark.Warmups,
+		WallTimeMs:   SummaryInfo(benchmark.WallTimeMs),
+		CPUTimeMs:    SummaryInfo(benchmark.CPUTimeMs),
+		PeakMemoryKB: SummaryInfo(benchmark.PeakMemoryKB),
+	}
+}
+
+func newUsageInfo(usage *codecontainer.ResourceUsage) *UsageInfo {
+	if usage == nil {
+		return nil
+	}
+	return &UsageInfo{
+		CPUTimeMs:    usage.CPUTime.Milliseconds(),
+		PeakMemoryKB: usage.PeakMemoryBytes / 1024,
+	}
+}
+
+// record saves the submission in the history, a failure is logged but doesn't fail the request.
+func (s *submitter) record(submission *store.Submission) bool {
+	if s.submissionStore == nil {
+		return false
+	}
+
+	if submission.Status == store.StatusFinished {
+		submission.FinishedAt = time.Now()
+	}
+	// The request may already be cancelled, the submission is recorded regardless.
+	if err := s.submissionStore.Save(context.Background(), submission); err != nil {
+		logger.Error("failed to record the submission",
+			zap.String("id", submission.ID),
+			zap.Error(err),
+		)
+		return false
+	}
+	return true
+}
+
+// deliver posts the response to the callback, failures are recorded as dead letters by the sender.
+func (s *submitter) deliver(callbackURL string, response *Response) {
+	payload, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("failed to marshal the webhook payload", zap.Error(err))
+		return
+	}
+	_ = s.webhooks.Deliver(context.Background(), callbackURL, response.ID, payload)
+}
@@ -1,13 +1,252 @@
 package main
 
-import "remote-code-engine/pkg/config"
+import (
+	"mime/multipart"
+	"remote-code-engine/pkg/config"
+	"time"
+)
 
+// Request is a JSON submission, the binding tags are checked by the validation middleware.
 type Request struct {
-	EncodedCode  string          `json:"code"`
-	EncodedInput string          `json:"input"`
-	Language     config.Language `json:"language"`
+	// Required unless the submission has files.
+	EncodedCode  string `json:"code" binding:"required_without=Files"`
+	EncodedInput string `json:"input"`
+	// ID of an input uploaded to POST /api/v1/inputs, used instead of input.
+	InputID  string          `json:"input_id,omitempty" binding:"excluded_with=EncodedInput"`
+	Language config.Language `json:"language" binding:"required"`
+	// Optional, the default version of the language is used if it is empty.
+	Version string `json:"version"`
+	// Encoding of the code, the input and the file contents: "base64" (the default), "utf8" for plain
+	// JSON strings or "base64url".
+	Encoding string `json:"encoding,omitempty"`
+
+	// Files of a multi-file submission, code is ignored if there are any.
+	Files []File `json:"files,omitempty" binding:"dive"`
+	// Optional path of the file to run, defaults to main<extension> or the only file with the extension of the language.
+	Entrypoint string `json:"entrypoint,omitempty"`
+
+	// Globs of the files written by the program which are returned, e.g. "out/*.csv".
+	Artifacts []string `json:"artifacts,omitempty"`
+	// Execute the code even if the result is cached, e.g. because the program uses randomness.
+	NoCache bool `json:"no_cache,omitempty"`
+	// Optional limits for this submission, they may only be tighter than the limits of the language.
+	Limits *LimitsInfo `json:"limits,omitempty"`
+	// Runs the program repeatedly and returns statistics of the runs, benchmarks are never cached.
+	Benchmark *BenchmarkRequest `json:"benchmark,omitempty"`
+	// Runs the code in a fixed environment which is recorded in the response, deterministic runs are never cached.
+	Deterministic *DeterministicRequest `json:"deterministic,omitempty"`
+
+	// Answer right away and execute the code in the background, the result is fetched from the
+	// submission history or posted to the callback URL. Implied by the callback URL.
+	Async       bool   `json:"async,omitempty"`
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// BenchmarkRequest runs the compiled program runs times after warmups unmeasured runs, both up to the max benchmark
+// runs of the server.
+type BenchmarkRequest struct {
+	Runs    int `json:"runs"`
+	Warmups int `json:"warmups,omitempty"`
+}
+
+// DeterministicRequest runs the code with the image pinned to its digest, a fixed env and hostname and random number
+// env vars seeded with the seed.
+type DeterministicRequest struct {
+	Seed int64 `json:"seed"`
+	// Optional digest of the image to run, e.g. the image_digest recorded for an earlier run. Used as long as the image exists.
+	ImageDigest string `json:"image_digest,omitempty"`
+}
+
+type File struct {
+	// Path relative to the submission directory, e.g. "src/util.h".
+	Path           string `json:"path" binding:"required"`
+	EncodedContent string `json:"content"`
+}
+
+// ArchiveRequest is a multipart form submitting a zip or tar.gz archive of a project.
+type ArchiveRequest struct {
+	Archive     *multipart.FileHeader `form:"archive" binding:"required"`
+	Language    config.Language       `form:"language" binding:"required"`
+	Version     string                `form:"version"`
+	Entrypoint  string                `form:"entrypoint"`
+	Artifacts   []string              `form:"artifacts"`
+	NoCache     bool                  `form:"no_cache"`
+	Async       bool                  `form:"async"`
+	CallbackURL string                `form:"callback_url"`
+	// Plain text, unlike the input of a JSON request.
+	Input   string `form:"input"`
+	InputID string `form:"input_id" binding:"excluded_with=Input"`
+	// A benchmark is run if the runs are set.
+	BenchmarkRuns    int `form:"benchmark_runs"`
+	BenchmarkWarmups int `form:"benchmark_warmups"`
+	// The run is deterministic if it is set, the seed and the image digest are ignored otherwise.
+	Deterministic bool   `form:"deterministic"`
+	Seed          int64  `form:"seed"`
+	ImageDigest   string `form:"image_digest"`
+}
+
+// InputInfo identifies an uploaded input, the ID is the SHA-256 of the content so uploading it again returns the same ID.
+type InputInfo struct {
+	ID   string `json:"input_id"`
+	Size int64  `json:"size"`
 }
 
 type Response struct {
-	Output string `json:"output"`
+	// ID of the recorded submission, empty if the history is disabled.
+	ID string `json:"id,omitempty"`
+	// "running" while an asynchronous submission is executed, "finished" afterwards.
+	Status    string         `json:"status"`
+	Output    string         `json:"output"`
+	Verdict   string         `json:"verdict"`
+	ExitCode  int            `json:"exit_code"`
+	Version   string         `json:"version,omitempty"`
+	Artifacts []ArtifactInfo `json:"artifacts,omitempty"`
+	// Set if some of the matching files were left out because of the artifact limits.
+	ArtifactsTruncated bool `json:"artifacts_truncated,omitempty"`
+	// Set if the output is cut off because the program exceeded the output limit.
+	Truncated bool `json:"truncated,omitempty"`
+	// Usage of the program, omitted if the command of the language doesn't report it.
+	Usage *UsageInfo `json:"usage,omitempty"`
+	// Usage of the whole container including the compilation, omitted if it exited before it was sampled.
+	ContainerUsage *UsageInfo `json:"container_usage,omitempty"`
+	// Statistics of a benchmark, omitted unless every run succeeded.
+	Benchmark *BenchmarkInfo `json:"benchmark,omitempty"`
+	// Conditions of a deterministic run, omitted for other runs.
+	Environment *EnvironmentInfo `json:"environment,omitempty"`
+	// Set if the result was served from the result cache.
+	Cached bool `json:"cached"`
+}
+
+// BenchmarkInfo summarizes the measured runs of a benchmark.
+type BenchmarkInfo struct {
+	Runs         int         `json:"runs"`
+	Warmups      int         `json:"warmups"`
+	WallTimeMs   SummaryInfo `json:"wall_time_ms"`
+	CPUTimeMs    SummaryInfo `json:"cpu_time_ms"`
+	PeakMemoryKB SummaryInfo `json:"peak_memory_kb"`
+}
+
+// EnvironmentInfo records the conditions of a deterministic run, submitting the same code and input with the seed
+// and the image digest reproduces the run.
+type EnvironmentInfo struct {
+	ImageDigest string   `json:"image_digest"`
+	Command     []string `json:"command"`
+	// Env passed to the container, on top of the env of the image.
+	Env      []string `json:"env"`
+	Hostname string   `json:"hostname"`
+	Seed     int64    `json:"seed"`
+	// Offset of the faked clock, omitted if the time isn't faked.
+	TimeOffsetSeconds int64      `json:"time_offset_seconds,omitempty"`
+	Limits            LimitsInfo `json:"limits"`
+}
+
+// SummaryInfo holds the statistics of a metric over the runs of a benchmark, the percentile is the nearest rank
+// and the standard deviation the one of a sample.
+type SummaryInfo struct {
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	P95    float64 `json:"p95"`
+	Stddev float64 `json:"stddev"`
+}
+
+// UsageInfo is the CPU time (user and system) and the peak resident memory of an execution.
+type UsageInfo struct {
+	CPUTimeMs    int64 `json:"cpu_time_ms"`
+	PeakMemoryKB int64 `json:"peak_memory_kb"`
+}
+
+// Types of the events of a streamed submission.
+const (
+	StreamEventStarted = "started"
+	StreamEventOutput  = "output"
+	StreamEventResult  = "result"
+	StreamEventError   = "error"
+)
+
+// StreamEvent is a line of the NDJSON response of POST /api/v1/submit/stream.
+type StreamEvent struct {
+	Type string `json:"type"`
+	// ID of the submission and the resolved version, set for the started event.
+	ID      string `json:"id,omitempty"`
+	Version string `json:"version,omitempty"`
+	// "stdout" or "stderr" and a chunk of the output, set for output events.
+	Stream string `json:"stream,omitempty"`
+	Data   string `json:"data,omitempty"`
+	// Set for the result event, the last event of a stream.
+	Result *Response `json:"result,omitempty"`
+	// Set for the error event if the execution failed after the stream started.
+	Error *ErrorInfo `json:"error,omitempty"`
+}
+
+type ArtifactInfo struct {
+	Path           string `json:"path"`
+	EncodedContent string `json:"content"`
+	Size           int64  `json:"size"`
+}
+
+// LanguageInfo describes a language in the catalog. Settings which can differ between the versions
+// of the language are described by the versions instead.
+type LanguageInfo struct {
+	Name           config.Language `json:"name"`
+	DisplayName    string          `json:"display_name"`
+	Extension      string          `json:"extension"`
+	EditorMode     string          `json:"editor_mode,omitempty"`
+	Template       string          `json:"template,omitempty"`
+	Flags          string          `json:"flags,omitempty"`
+	Limits         *LimitsInfo     `json:"limits,omitempty"`
+	DefaultVersion string          `json:"default_version,omitempty"`
+	Versions       []VersionInfo   `json:"versions,omitempty"`
+}
+
+type VersionInfo struct {
+	Name        string     `json:"name"`
+	DisplayName string     `json:"display_name"`
+	Flags       string     `json:"flags,omitempty"`
+	Limits      LimitsInfo `json:"limits"`
+}
+
+// LimitsInfo are the limits an execution runs with, the resource limits are omitted if resource constraints are disabled.
+type LimitsInfo struct {
+	TimeLimitMs int64 `json:"time_limit_ms"`
+	// Omitted if the CPU time isn't limited separately from the wall time.
+	CPUTimeLimitMs int64   `json:"cpu_time_limit_ms,omitempty"`
+	MemoryMB       int64   `json:"memory_mb,omitempty"`
+	CPUs           float64 `json:"cpus,omitempty"`
+	MaxProcesses   int64   `json:"max_processes,omitempty"`
+	MaxFileSizeMB  int64   `json:"max_file_size_mb,omitempty"`
+}
+
+// SubmissionInfo summarizes a recorded submission, the code, input and output are only returned for a single submission.
+type SubmissionInfo struct {
+	ID         string          `json:"id"`
+	Tenant     string          `json:"tenant,omitempty"`
+	Language   config.Language `json:"language"`
+	Version    string          `json:"version,omitempty"`
+	Verdict    string          `json:"verdict"`
+	ExitCode   int             `json:"exit_code"`
+	Cached     bool            `json:"cached"`
+	CreatedAt  time.Time       `json:"created_at"`
+	DurationMs int64           `json:"duration_ms"`
+}
+
+type SubmissionList struct {
+	Submissions []SubmissionInfo `json:"submissions"`
+	// Pass as the cursor to get the next page, empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ErrorResponse is the body of every error response.
+type ErrorResponse struct {
+	Error *ErrorInfo `json:"error"`
+}
+
+type ErrorInfo struct {
+	// Machine-readable code of the error, see the ErrorCode* constants.
+	Code string `json:"code"`
+	// Human-readable description, it may change between releases.
+	Message string `json:"message"`
+	// Optional context of the error, depending on the code.
+	Details map[string]any `json:"details,omitempty"`
+	// ID of the request, also sent in the X-Request-ID header. Include it when reporting an issue.
+	RequestID string `json:"request_id,omitempty"`
 }
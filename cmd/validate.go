@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"remote-code-engine/pkg/config"
+	codecontainer "remote-code-engine/pkg/container"
+	"time"
+)
+
+// runValidateConfig implements the validate-config subcommand, it returns the exit code of the process.
+func runValidateConfig(args []string) int {
+	flags := flag.NewFlagSet("validate-config", flag.ContinueOnError)
+	configPath := flags.String("config", "../config.yml", "Path to the config file to validate")
+	checkImages := flags.Bool("check-images", true, "Check that the images are present in the docker daemon")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	configFile, err := config.LoadFile(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	imageConfig := &configFile.Languages
+
+	if *checkImages {
+		cli, err := codecontainer.NewDockerClient(nil, &configFile.Server, logger)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := imageConfig.ValidateImages(ctx, cli.ImageExists); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid config file %s:\n%v\n", *configPath, err)
+			return 1
+		}
+	}
+
+	fmt.Printf("%s is valid, %d languages configured\n", *configPath, len(*imageConfig))
+	return 0
+}
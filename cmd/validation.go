@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"remote-code-engine/pkg/config"
+	codecontainer "remote-code-engine/pkg/container"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Key of the validated request in the gin context.
+const requestKey = "request"
+
+func init() {
+	// Report the fields which fail validation by their JSON names, or their form names for archives.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" {
+				name, _, _ = strings.Cut(field.Tag.Get("form"), ",")
+			}
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// validateSubmission decodes and validates a JSON submission before the handler runs, the handler gets it with
+// submittedRequest. The checks match the OpenAPI document, invalid fields are listed in the details of the error.
+// Bodies larger than the maximum body size are rejected while they are read.
+func validateSubmission(configStore *config.Store, serverConfig *config.ServerConfig) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, serverConfig.MaxBodySizeMB*1024*1024)
+
+		var req Request
+		if err := json.NewDecoder(ctx.Request.Body).Decode(&req); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				abortWithError(ctx, http.StatusRequestEntityTooLarge, ErrorCodePayloadTooLarge,
+					fmt.Sprintf("The request body is larger than %d MB, upload large inputs to /api/v1/inputs", serverConfig.MaxBodySizeMB))
+				return
+			}
+			abortWithError(ctx, http.StatusBadRequest, ErrorCodeInvalidRequest,
+				fmt.Sprintf("Failed to decode the request body: %v", err))
+			return
+		}
+
+		if err := validateRequest(req, configStore.Load(), serverConfig); err != nil {
+			writeError(ctx, err, "")
+			return
+		}
+		ctx.Set(requestKey, req)
+		ctx.Next()
+	}
+}
+
+// submittedRequest returns the request decoded by validateSubmission.
+func submittedRequest(ctx *gin.Context) Request {
+	return ctx.MustGet(requestKey).(Request)
+}
+
+// validateRequest checks the binding tags of the request and the constraints they can't express, it is shared
+// with the gRPC API.
+func validateRequest(req Request, imageConfig *config.ImageConfig, serverConfig *config.ServerConfig) *apiError {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return newBindingError(err)
+	}
+
+	codeSize := decodedSize(req.Encoding, req.EncodedCode)
+	if len(req.Files) > 0 {
+		codeSize = 0
+		for _, file := range req.Files {
+			codeSize += decodedSize(req.Encoding, file.EncodedContent)
+		}
+	}
+	return validateConstraints(req, codeSize, imageConfig, serverConfig)
+}
+
+// validateArchiveRequest checks the request of an archive, whose binding tags are checked when the form is bound.
+// The code is the files extracted from the archive.
+func validateArchiveRequest(req Request, files []codecontainer.File, imageConfig *config.ImageConfig, serverConfig *config.ServerConfig) *apiError {
+	var codeSize int64
+	for _, file := range files {
+		codeSize += int64(len(file.Content))
+	}
+	return validateConstraints(req, codeSize, imageConfig, serverConfig)
+}
+
+// validateConstraints checks the constraints of a request the binding tags can't express.
+func validateConstraints(req Request, codeSize int64, imageConfig *config.ImageConfig, serverConfig *config.ServerConfig) *apiError {
+	if !imageConfig.IsLanguageSupported(req.Language) {
+		apiErr := newAPIError(http.StatusBadRequest, ErrorCodeUnsupportedLanguage,
+			fmt.Sprintf("%v %q", config.ErrUnsupportedLanguage, req.Language))
+		apiErr.details = map[string]any{"supported_languages": imageConfig.GetSupportedLanguages()}
+		return apiErr
+	}
+
+	fields := map[string]string{}
+	if maxCodeSize := serverConfig.MaxCodeSizeMB * 1024 * 1024; codeSize > maxCodeSize {
+		fields["code"] = fmt.Sprintf("the code must not be larger than %d bytes", maxCodeSize)
+	}
+	if maxInputSize := serverConfig.MaxInputSizeMB * 1024 * 1024; decodedSize(req.Encoding, req.EncodedInput) > maxInputSize {
+		fields["input"] = fmt.Sprintf("the input must not be larger than %d bytes", maxInputSize)
+	}
+	if req.Limits != nil {
+		validateLimits(*req.Limits, fields)
+	}
+
+	if len(fields) > 0 {
+		return newValidationError(fields)
+	}
+	return nil
+}
+
+// limitRange is the range of a limit of a request, 0 keeps the limit of the language. The validation and the
+// OpenAPI document are built from the same ranges.
+type limitRange struct {
+	field    string
+	min, max float64
+	value    func(LimitsInfo) float64
+}
+
+// limitRanges are the ranges accepted for the limits of a language.
+var limitRanges = []limitRange{
+	{
+		field: "time_limit_ms",
+		min:   float64(config.MinTimeLimit.Milliseconds()),
+		max:   float64(config.MaxTimeLimit.Milliseconds()),
+		value: func(limits LimitsInfo) float64 { return float64(limits.TimeLimitMs) },
+	},
+	{
+		field: "cpu_time_limit_ms",
+		min:   float64(config.MinTimeLimit.Milliseconds()),
+		max:   float64(config.MaxTimeLimit.Milliseconds()),
+		value: func(limits LimitsInfo) float64 { return float64(limits.CPUTimeLimitMs) },
+	},
+	{
+		field: "memory_mb",
+		min:   config.MinMemoryMB,
+		max:   config.MaxMemoryMB,
+		value: func(limits LimitsInfo) float64 { return float64(limits.MemoryMB) },
+	},
+	{
+		field: "cpus",
+		max:   config.MaxCPUs,
+		value: func(limits LimitsInfo) float64 { return limits.CPUs },
+	},
+	{
+		field: "max_processes",
+		max:   config.MaxProcesses,
+		value: func(limits LimitsInfo) float64 { return float64(limits.MaxProcesses) },
+	},
+	{
+		field: "max_file_size_mb",
+		max:   config.MaxFileSizeLimit,
+		value: func(limits LimitsInfo) float64 { return float64(limits.MaxFileSizeMB) },
+	},
+}
+
+func (r limitRange) accepts(value float64) bool {
+	return value == 0 || (value >= r.min && value <= r.max)
+}
+
+func (r limitRange) String() string {
+	minimum, maximum := strconv.FormatFloat(r.min, 'f', -1, 64), strconv.FormatFloat(r.max, 'f', -1, 64)
+	if r.min == 0 {
+		return fmt.Sprintf("must be between 0 and %s", maximum)
+	}
+	return fmt.Sprintf("must be 0 or between %s and %s", minimum, maximum)
+}
+
+// validateLimits checks the limits of a request against the ranges accepted for the limits of a language.
+func validateLimits(limits LimitsInfo, fields map[string]string) {
+	for _, r := range limitRanges {
+		if !r.accepts(r.value(limits)) {
+			fields["limits."+r.field] = r.String()
+		}
+	}
+}
+
+// newValidationError lists the invalid fields by their JSON path in the details.
+func newValidationError(fields map[string]string) *apiError {
+	apiErr := newAPIError(http.StatusBadRequest, ErrorCodeInvalidRequest, "The request has invalid fields")
+	apiErr.details = map[string]any{"fields": fields}
+	return apiErr
+}
+
+// newBindingError lists the fields which fail their binding tags.
+func newBindingError(err error) *apiError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return newValidationError(fieldErrors(validationErrs))
+	}
+	return newAPIError(http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+}
+
+func fieldErrors(errs validator.ValidationErrors) map[string]string {
+	fields := map[string]string{}
+	for _, err := range errs {
+		// The namespace starts with the name of the struct, e.g. Request.files[0].path.
+		_, field, _ := strings.Cut(err.Namespace(), ".")
+		switch err.Tag() {
+		case "required":
+			fields[field] = "is required"
+		case "required_without":
+			fields[field] = "is required unless files are submitted"
+		case "excluded_with":
+			// Only the uploaded input excludes another field, the input.
+			fields[field] = "can't be combined with input"
+		default:
+			fields[field] = fmt.Sprintf("fails the %s check", err.Tag())
+		}
+	}
+	return fields
+}
+
+// decodedSize returns the size of the content once it is decoded, base64 sizes are rounded up to a multiple of 3.
+func decodedSize(encoding, content string) int64 {
+	if codecontainer.Encoding(encoding) == codecontainer.EncodingUTF8 {
+		return int64(len(content))
+	}
+	return int64(base64.StdEncoding.DecodedLen(len(content)))
+}
+
+// tightenLimits overrides the limits of the language with the limits of a request, which may not be looser than
+// the effective limits of the language.
+func tightenLimits(limits, effective config.Limits, requested LimitsInfo) (config.Limits, error) {
+	override := config.Limits{
+		TimeLimit:     time.Duration(requested.TimeLimitMs) * time.Millisecond,
+		CPUTimeLimit:  time.Duration(requested.CPUTimeLimitMs) * time.Millisecond,
+		MemoryMB:      requested.MemoryMB,
+		CPUs:          requested.CPUs,
+		MaxProcesses:  requested.MaxProcesses,
+		MaxFileSizeMB: requested.MaxFileSizeMB,
+	}
+
+	var errs []error
+	if override.TimeLimit > effective.TimeLimit {
+		errs = append(errs, fmt.Errorf("limits.time_limit_ms: %d exceeds the limit of the language, %d",
+			requested.TimeLimitMs, effective.TimeLimit.Milliseconds()))
+	}
+	if effective.CPUTimeLimit != 0 && override.CPUTimeLimit > effective.CPUTimeLimit {
+		errs = append(errs, fmt.Errorf("limits.cpu_time_limit_ms: %d exceeds the limit of the language, %d",
+			requested.CPUTimeLimitMs, effective.CPUTimeLimit.Milliseconds()))
+	}
+	if effective.MemoryMB != 0 && override.MemoryMB > effective.MemoryMB {
+		errs = append(errs, fmt.Errorf("limits.memory_mb: %d exceeds the limit of the language, %d",
+			override.MemoryMB, effective.MemoryMB))
+	}
+	if effective.CPUs != 0 && override.CPUs > effective.CPUs {
+		errs = append(errs, fmt.Errorf("limits.cpus: %g exceeds the limit of the language, %g",
+			override.CPUs, effective.CPUs))
+	}
+	if effective.MaxProcesses != 0 && override.MaxProcesses > effective.MaxProcesses {
+		errs = append(errs, fmt.Errorf("limits.max_processes: %d exceeds the limit of the language, %d",
+			override.MaxProcesses, effective.MaxProcesses))
+	}
+	if effective.MaxFileSizeMB != 0 && override.MaxFileSizeMB > effective.MaxFileSizeMB {
+		errs = append(errs, fmt.Errorf("limits.max_file_size_mb: %d exceeds the limit of the language, %d",
+			override.MaxFileSizeMB, effective.MaxFileSizeMB))
+	}
+	if len(errs) > 0 {
+		return limits, errors.Join(errs...)
+	}
+	return limits.Merge(override), nil
+}
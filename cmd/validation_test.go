@@ -0,0 +1,373 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"remote-code-engine/pkg/config"
+	codecontainer "remote-code-engine/pkg/container"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// limitsClient records the limits the code is executed with.
+type limitsClient struct {
+	codecontainer.ContainerClient
+	limits config.Limits
+}
+
+func (c *limitsClient) StreamCode(ctx context.Context, code *codecontainer.Code, stdout, stderr io.Writer) (*codecontainer.Result, error) {
+	c.limits = code.Limits
+	return &codecontainer.Result{Verdict: codecontainer.VerdictOK}, nil
+}
+
+func TestValidateSubmission(t *testing.T) {
+	const maxSize = 1024 * 1024
+	code := base64.StdEncoding.EncodeToString([]byte("print(1)"))
+	tests := []struct {
+		name           string
+		body           string
+		expectedCode   string
+		expectedFields []string
+	}{
+		{
+			name:           "missing code",
+			body:           `{"language": "python"}`,
+			expectedCode:   ErrorCodeInvalidRequest,
+			expectedFields: []string{"code"},
+		},
+		{
+			name:           "missing language",
+			body:           `{"code": "` + code + `"}`,
+			expectedCode:   ErrorCodeInvalidRequest,
+			expectedFields: []string{"language"},
+		},
+		{
+			name:           "file without a path",
+			body:           `{"language": "python", "files": [{"content": "` + code + `"}]}`,
+			expectedCode:   ErrorCodeInvalidRequest,
+			expectedFields: []string{"files[0].path"},
+		},
+		{
+			name:           "input too large",
+			body:           `{"language": "python", "code": "` + code + `", "input": "` + strings.Repeat("A", base64.StdEncoding.EncodedLen(maxSize)+4) + `"}`,
+			expectedCode:   ErrorCodeInvalidRequest,
+			expectedFields: []string{"input"},
+		},
+		{
+			name:           "code too large",
+			body:           `{"language": "python", "encoding": "utf8", "code": "` + strings.Repeat("x", maxSize+1) + `"}`,
+			expectedCode:   ErrorCodeInvalidRequest,
+			expectedFields: []string{"code"},
+		},
+		{
+			name:           "input and uploaded input",
+			body:           `{"language": "python", "code": "` + code + `", "input": "MQ==", "input_id": "` + strings.Repeat("0", 64) + `"}`,
+			expectedCode:   ErrorCodeInvalidRequest,
+			expectedFields: []string{"input_id"},
+		},
+		{
+			name:           "limits out of range",
+			body:           `{"language": "python", "code": "` + code + `", "limits": {"time_limit_ms": 10, "memory_mb": 1000000}}`,
+			expectedCode:   ErrorCodeInvalidRequest,
+			expectedFields: []string{"limits.time_limit_ms", "limits.memory_mb"},
+		},
+		{
+			name:           "cpu time limit out of range",
+			body:           `{"language": "python", "code": "` + code + `", "limits": {"cpu_time_limit_ms": 1}}`,
+			expectedCode:   ErrorCodeInvalidRequest,
+			expectedFields: []string{"limits.cpu_time_limit_ms"},
+		},
+		{
+			name:         "unsupported language",
+			body:         `{"language": "cobol", "code": "` + code + `"}`,
+			expectedCode: ErrorCodeUnsupportedLanguage,
+		},
+		{
+			name:         "limits looser than the language",
+			body:         `{"language": "python", "code": "` + code + `", "limits": {"time_limit_ms": 120000}}`,
+			expectedCode: ErrorCodeInvalidSubmission,
+		},
+	}
+
+	r := newTestRouter(&fakeClient{}, func(s *config.ServerConfig) {
+		s.MaxCodeSizeMB = 1
+		s.MaxInputSizeMB = 1
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, newTestRequest(tt.body, nil))
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d: %s", recorder.Code, recorder.Body.String())
+			}
+
+			info := decodeError(t, recorder)
+			if info.Code != tt.expectedCode {
+				t.Errorf("expected code %q, got %q: %s", tt.expectedCode, info.Code, info.Message)
+			}
+			fields, _ := info.Details["fields"].(map[string]any)
+			if len(fields) != len(tt.expectedFields) {
+				t.Errorf("expected the invalid fields %v, got %v", tt.expectedFields, fields)
+			}
+			for _, field := range tt.expectedFields {
+				if _, ok := fields[field]; !ok {
+					t.Errorf("expected %s to be invalid, got %v", field, fields)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateArchiveSubmission(t *testing.T) {
+	const maxSize = 1024 * 1024
+	tests := []struct {
+		name           string
+		fields         map[string]string
+		expectedCode   string
+		expectedFields []string
+	}{
+		{
+			name:           "missing language",
+			fields:         map[string]string{},
+			expectedCode:   ErrorCodeInvalidRequest,
+			expectedFields: []string{"language"},
+		},
+		{
+			name:           "input and uploaded input",
+			fields:         map[string]string{"language": "python", "input": "1", "input_id": strings.Repeat("0", 64)},
+			expectedCode:   ErrorCodeInvalidRequest,
+			expectedFields: []string{"input_id"},
+		},
+		{
+			name:           "input too large",
+			fields:         map[string]string{"language": "python", "input": strings.Repeat("x", maxSize+1)},
+			expectedCode:   ErrorCodeInvalidRequest,
+			expectedFields: []string{"input"},
+		},
+		{
+			name:         "unsupported language",
+			fields:       map[string]string{"language": "cobol"},
+			expectedCode: ErrorCodeUnsupportedLanguage,
+		},
+	}
+
+	r := newTestRouter(&fakeClient{}, func(s *config.ServerConfig) {
+		s.MaxInputSizeMB = 1
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			for name, value := range tt.fields {
+				if err := form.WriteField(name, value); err != nil {
+					t.Fatalf("failed to write the field %s: %v", name, err)
+				}
+			}
+			part, err := form.CreateFormFile("archive", "project.zip")
+			if err != nil {
+				t.Fatalf("failed to create the archive field: %v", err)
+			}
+			archive := zip.NewWriter(part)
+			file, err := archive.Create("main.py")
+			if err != nil {
+				t.Fatalf("failed to add a file to the archive: %v", err)
+			}
+			_, _ = file.Write([]byte("print(1)"))
+			if err := archive.Close(); err != nil {
+				t.Fatalf("failed to write the archive: %v", err)
+			}
+			if err := form.Close(); err != nil {
+				t.Fatalf("failed to write the form: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/submit/archive", &body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d: %s", recorder.Code, recorder.Body.String())
+			}
+
+			info := decodeError(t, recorder)
+			if info.Code != tt.expectedCode {
+				t.Errorf("expected code %q, got %q: %s", tt.expectedCode, info.Code, info.Message)
+			}
+			fields, _ := info.Details["fields"].(map[string]any)
+			if len(fields) != len(tt.expectedFields) {
+				t.Errorf("expected the invalid fields %v, got %v", tt.expectedFields, fields)
+			}
+			for _, field := range tt.expectedFields {
+				if _, ok := fields[field]; !ok {
+					t.Errorf("expected %s to be invalid, got %v", field, fields)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   LimitsInfo
+		expected map[string]string
+	}{
+		{name: "language limits", limits: LimitsInfo{}, expected: map[string]string{}},
+		{name: "within the ranges", limits: LimitsInfo{TimeLimitMs: 100, MemoryMB: 6, CPUs: 0.5, MaxProcesses: 1}, expected: map[string]string{}},
+		{
+			name:   "below the minimum",
+			limits: LimitsInfo{TimeLimitMs: 99, CPUs: -1},
+			expected: map[string]string{
+				"limits.time_limit_ms": "must be 0 or between 100 and 600000",
+				"limits.cpus":          "must be between 0 and 64",
+			},
+		},
+		{
+			name:   "above the maximum",
+			limits: LimitsInfo{MemoryMB: 65537, MaxProcesses: 4097, MaxFileSizeMB: 1025},
+			expected: map[string]string{
+				"limits.memory_mb":        "must be 0 or between 6 and 65536",
+				"limits.max_processes":    "must be between 0 and 4096",
+				"limits.max_file_size_mb": "must be between 0 and 1024",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := map[string]string{}
+			validateLimits(tt.limits, fields)
+			if !reflect.DeepEqual(fields, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, fields)
+			}
+		})
+	}
+}
+
+func TestRequestLimits(t *testing.T) {
+	client := &limitsClient{}
+	r := newTestRouter(client, func(s *config.ServerConfig) {
+		s.ResourceConstraints = true
+	})
+
+	body := `{"language": "python", "code": "cHJpbnQoMSk=", "limits": {"time_limit_ms": 2000, "cpu_time_limit_ms": 1000, "memory_mb": 64}}`
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, newTestRequest(body, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	expected := config.Limits{TimeLimit: 2 * time.Second, CPUTimeLimit: time.Second, MemoryMB: 64}
+	if client.limits != expected {
+		t.Errorf("expected the limits %+v, got %+v", expected, client.limits)
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	r := newTestRouter(&fakeClient{}, func(s *config.ServerConfig) {
+		s.InputDir = t.TempDir()
+	})
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", recorder.Code)
+	}
+
+	var document struct {
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Enum       []string                  `json:"enum"`
+				Properties map[string]map[string]any `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &document); err != nil {
+		t.Fatalf("failed to decode the document: %v", err)
+	}
+
+	if languages := document.Components.Schemas["Language"].Enum; len(languages) != 1 || languages[0] != "python" {
+		t.Errorf("expected the configured languages in the enum, got %v", languages)
+	}
+
+	// Every route is documented, the submission history routes are registered in the full server only.
+	routes := append(r.Routes(), routesOf(func(r *gin.Engine) {
+		registerSubmissionRoutes(r, nil, &authenticator{})
+	})...)
+	for _, route := range routes {
+		path := openAPIPath(route.Path)
+		if _, ok := document.Paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("%s %s is not documented", route.Method, path)
+		}
+	}
+
+	// The ranges of the limits are the ranges of the validation.
+	limits := document.Components.Schemas["RequestLimits"].Properties
+	for _, r := range limitRanges {
+		property := limits[r.field]
+		if r.min == 0 {
+			if property["minimum"] != r.min || property["maximum"] != r.max {
+				t.Errorf("expected %s to be between 0 and %g, got %v", r.field, r.max, property)
+			}
+			continue
+		}
+		expected := []any{
+			map[string]any{"enum": []any{0.0}},
+			map[string]any{"minimum": r.min, "maximum": r.max},
+		}
+		if !reflect.DeepEqual(property["anyOf"], expected) {
+			t.Errorf("expected %s to be 0 or between %g and %g, got %v", r.field, r.min, r.max, property)
+		}
+	}
+
+	// The fields of the request match the schema.
+	properties := document.Components.Schemas["Request"].Properties
+	requestType := reflectJSONFields(Request{})
+	if len(properties) != len(requestType) {
+		t.Errorf("expected the properties %v, got %v", requestType, properties)
+	}
+	for _, field := range requestType {
+		if _, ok := properties[field]; !ok {
+			t.Errorf("%s of the request is not documented", field)
+		}
+	}
+}
+
+// routesOf returns the routes registered by register.
+func routesOf(register func(*gin.Engine)) gin.RoutesInfo {
+	r := gin.New()
+	register(r)
+	return r.Routes()
+}
+
+// openAPIPath converts the parameters of a gin path, e.g. /submissions/:id to /submissions/{id}.
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func reflectJSONFields(v any) []string {
+	var fields []string
+	t := reflect.TypeOf(v)
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields = append(fields, name)
+	}
+	return fields
+}
@@ -0,0 +1,39 @@
+// Package cache stores execution results by key with a time to live and a size bound.
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Cache is safe for concurrent use. Entries expire after the TTL and the least recently
+// used entries are evicted once the size bound is exceeded.
+type Cache interface {
+	// Get returns the value stored for the key, if it is present and hasn't expired.
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte) error
+}
+
+// Kinds of caches, see New.
+const (
+	KindNone   = ""
+	KindMemory = "memory"
+	KindDisk   = "disk"
+)
+
+var ErrUnknownKind = errors.New("unknown cache kind")
+
+// New creates a cache of the kind, dir is only used by disk caches. It returns nil for KindNone.
+func New(kind string, ttl time.Duration, maxBytes int64, dir string) (Cache, error) {
+	switch kind {
+	case KindNone:
+		return nil, nil
+	case KindMemory:
+		return NewMemory(ttl, maxBytes), nil
+	case KindDisk:
+		return NewDisk(dir, ttl, maxBytes)
+	default:
+		return nil, fmt.Errorf("%w %q, use %q or %q", ErrUnknownKind, kind, KindMemory, KindDisk)
+	}
+}
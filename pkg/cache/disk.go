@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Keys are used as file names.
+var diskKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Disk stores every entry in a file of its directory, so the cache survives restarts. The modification
+// time of a file is its last use: it is set when the entry is stored and refreshed when it is read.
+type Disk struct {
+	// Serializes the eviction, the entries themselves are written atomically.
+	mu       sync.Mutex
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	now      func() time.Time
+}
+
+func NewDisk(dir string, ttl time.Duration, maxBytes int64) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create the cache directory: %w", err)
+	}
+
+	return &Disk{
+		dir:      dir,
+		ttl:      ttl,
+		maxBytes: maxBytes,
+		now:      time.Now,
+	}, nil
+}
+
+func (d *Disk) Get(key string) ([]byte, bool) {
+	if !diskKeyPattern.MatchString(key) {
+		return nil, false
+	}
+
+	path := filepath.Join(d.dir, key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if d.now().Sub(info.ModTime()) > d.ttl {
+		_ = os.Remove(path)
+		return nil, false
+	}
+
+	value, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	now := d.now()
+	_ = os.Chtimes(path, now, now)
+	return value, true
+}
+
+// Set stores the value and evicts the expired and least recently used entries, values larger than the
+// size bound are not stored.
+func (d *Disk) Set(key string, value []byte) error {
+	if !diskKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid cache key %q", key)
+	}
+	if int64(len(value)) > d.maxBytes {
+		return nil
+	}
+
+	f, err := os.CreateTemp(d.dir, ".tmp-"+key+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create the cache file: %w", err)
+	}
+	_, err = f.Write(value)
+	err = errors.Join(err, f.Close())
+	if err == nil {
+		now := d.now()
+		err = os.Chtimes(f.Name(), now, now)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(d.dir, key))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("failed to write the cache file: %w", err)
+	}
+
+	return d.evict()
+}
+
+func (d *Disk) evict() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read the cache directory: %w", err)
+	}
+
+	var files []os.FileInfo
+	var size int64
+	for _, entry := range entries {
+		if !diskKeyPattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if d.now().Sub(info.ModTime()) > d.ttl {
+			_ = os.Remove(filepath.Join(d.dir, info.Name()))
+			continue
+		}
+		files = append(files, info)
+		size += info.Size()
+	}
+
+	// Least recently used first
+	slices.SortFunc(files, func(a, b os.FileInfo) int {
+		return a.ModTime().Compare(b.ModTime())
+	})
+	for _, info := range files {
+		if size <= d.maxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(d.dir, info.Name())); err == nil {
+			size -= info.Size()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDisk(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	d, err := NewDisk(dir, time.Minute, 10)
+	if err != nil {
+		t.Fatalf("failed to create the cache: %v", err)
+	}
+	d.now = func() time.Time { return now }
+
+	if err := d.Set("a", []byte("aaaa")); err != nil {
+		t.Fatalf("failed to set a: %v", err)
+	}
+	now = now.Add(time.Second)
+	if err := d.Set("b", []byte("bbbb")); err != nil {
+		t.Fatalf("failed to set b: %v", err)
+	}
+	now = now.Add(time.Second)
+	if value, ok := d.Get("a"); !ok || string(value) != "aaaa" {
+		t.Errorf("expected a to be cached, got %q, %v", value, ok)
+	}
+
+	now = now.Add(time.Second)
+	if err := d.Set("c", []byte("cccc")); err != nil {
+		t.Fatalf("failed to set c: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b")); !os.IsNotExist(err) {
+		t.Error("expected the least recently used entry b to be evicted")
+	}
+	if _, ok := d.Get("a"); !ok {
+		t.Error("expected a to be kept")
+	}
+
+	if err := d.Set("../escape", []byte("x")); err == nil {
+		t.Error("expected an error for a key which isn't a plain file name")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := d.Get("c"); ok {
+		t.Error("expected c to expire")
+	}
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(KindNone, time.Minute, 10, "")
+	if err != nil || c != nil {
+		t.Errorf("expected no cache, got %v, %v", c, err)
+	}
+	if _, err := New("redis", time.Minute, 10, ""); err == nil {
+		t.Error("expected an error for an unknown kind")
+	}
+}
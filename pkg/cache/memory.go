@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// Memory is an in-memory LRU cache.
+type Memory struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	maxBytes int64
+	size     int64
+	// Most recently used entries are at the front.
+	entries *list.List
+	index   map[string]*list.Element
+	now     func() time.Time
+}
+
+func NewMemory(ttl time.Duration, maxBytes int64) *Memory {
+	return &Memory{
+		ttl:      ttl,
+		maxBytes: maxBytes,
+		entries:  list.New(),
+		index:    map[string]*list.Element{},
+		now:      time.Now,
+	}
+}
+
+func (m *Memory) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.index[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*memoryEntry)
+	if m.now().After(entry.expires) {
+		m.remove(element)
+		return nil, false
+	}
+
+	m.entries.MoveToFront(element)
+	return entry.value, true
+}
+
+// Set stores the value, values larger than the size bound are not stored.
+func (m *Memory) Set(key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.index[key]; ok {
+		m.remove(element)
+	}
+	if int64(len(value)) > m.maxBytes {
+		return nil
+	}
+
+	m.index[key] = m.entries.PushFront(&memoryEntry{
+		key:     key,
+		value:   value,
+		expires: m.now().Add(m.ttl),
+	})
+	m.size += int64(len(value))
+
+	for m.size > m.maxBytes {
+		m.remove(m.entries.Back())
+	}
+	return nil
+}
+
+func (m *Memory) remove(element *list.Element) {
+	entry := m.entries.Remove(element).(*memoryEntry)
+	delete(m.index, entry.key)
+	m.size -= int64(len(entry.value))
+}
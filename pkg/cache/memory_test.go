@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	now := time.Now()
+	m := NewMemory(time.Minute, 10)
+	m.now = func() time.Time { return now }
+
+	if err := m.Set("a", []byte("aaaa")); err != nil {
+		t.Fatalf("failed to set a: %v", err)
+	}
+	if err := m.Set("b", []byte("bbbb")); err != nil {
+		t.Fatalf("failed to set b: %v", err)
+	}
+	if value, ok := m.Get("a"); !ok || string(value) != "aaaa" {
+		t.Errorf("expected a to be cached, got %q, %v", value, ok)
+	}
+
+	// b is the least recently used entry now and has to make room for c.
+	if err := m.Set("c", []byte("cccc")); err != nil {
+		t.Fatalf("failed to set c: %v", err)
+	}
+	if _, ok := m.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if _, ok := m.Get("a"); !ok {
+		t.Error("expected a to be kept")
+	}
+
+	if err := m.Set("large", []byte("larger than the cache")); err != nil {
+		t.Fatalf("failed to set large: %v", err)
+	}
+	if _, ok := m.Get("large"); ok {
+		t.Error("expected a value larger than the cache not to be stored")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := m.Get("c"); ok {
+		t.Error("expected c to expire")
+	}
+}
@@ -0,0 +1,452 @@
+// Package client is a Go client of the REST API of the engine.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sent with every request if the client has a tenant, the engine only trusts it behind a gateway which sets it.
+// Authenticate with the token of the tenant otherwise, see WithToken.
+const TenantHeader = "X-Tenant-ID"
+
+const requestIDHeader = "X-Request-ID"
+
+// Errors matched by the errors of the client with errors.Is.
+var (
+	// The engine rejected the submission, e.g. an unsupported language or an invalid file path.
+	ErrInvalidSubmission = errors.New("invalid submission")
+	ErrNotFound          = errors.New("not found")
+	// The submission can't be cancelled because it has finished.
+	ErrNotRunning = errors.New("submission is not running")
+	// The image with the digest Deterministic.ImageDigest was removed from the server.
+	ErrImageChanged = errors.New("image changed")
+	ErrTooLarge     = errors.New("request too large")
+	// The engine is overloaded or unavailable, returned once the retries are used up.
+	ErrUnavailable = errors.New("engine unavailable")
+	// The engine failed to execute the code, the code itself may be fine.
+	ErrExecutionFailed = errors.New("execution failed")
+)
+
+// APIError is an error response of the engine.
+type APIError struct {
+	StatusCode int
+	// Machine-readable code of the error, e.g. "unsupported_language" or "queue_full", see the API documentation.
+	// Empty if the response had no error body, e.g. from a proxy.
+	Code    string
+	Message string
+	// ID of the request assigned by the engine, include it when reporting an issue.
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("rce: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+	}
+	return fmt.Sprintf("rce: %d %s: %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Code, e.Message)
+}
+
+// Is matches the error against the Err* variable of its status code.
+func (e *APIError) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return target == ErrInvalidSubmission
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusConflict:
+		if e.Code == "image_changed" {
+			return target == ErrImageChanged
+		}
+		return target == ErrNotRunning
+	case http.StatusRequestEntityTooLarge:
+		return target == ErrTooLarge
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return target == ErrUnavailable
+	case http.StatusInternalServerError:
+		return target == ErrExecutionFailed
+	}
+	return false
+}
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	tenant     string
+	token      string
+	// Requests answered with 429 or 503 are retried this many times, waiting for the Retry-After
+	// header or else for retryBackoff, doubled after every attempt.
+	maxRetries   int
+	retryBackoff time.Duration
+	pollInterval time.Duration
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client, the default has no timeout since executions can take a while,
+// bound the calls with their context instead.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTenant records the submissions for the tenant, e.g. a course.
+func WithTenant(tenant string) Option {
+	return func(c *Client) {
+		c.tenant = tenant
+	}
+}
+
+// WithToken sends the bearer token with every request, the submissions are recorded for the tenant of the token.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithRetries sets how often requests rejected with 429 or 503 are retried and the backoff used if the
+// engine doesn't send a Retry-After header. 0 retries disables retrying.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryBackoff = backoff
+	}
+}
+
+// WithPollInterval sets how often Wait polls the submission.
+func WithPollInterval(interval time.Duration) Option {
+	return func(c *Client) {
+		c.pollInterval = interval
+	}
+}
+
+// New returns a client of the engine at baseURL, e.g. "http://localhost:9000".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		httpClient:   &http.Client{},
+		maxRetries:   3,
+		retryBackoff: 500 * time.Millisecond,
+		pollInterval: 500 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Submit executes the submission and returns its result.
+func (c *Client) Submit(ctx context.Context, submission *Submission) (*Result, error) {
+	var resp response
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/submit", newRequest(submission, false), &resp); err != nil {
+		return nil, err
+	}
+	return newResult(&resp), nil
+}
+
+// SubmitAsync submits the submission to be executed in the background and returns its ID, which is passed to
+// Wait, Get and Cancel. The engine needs the submission history unless the submission has a callback URL.
+func (c *Client) SubmitAsync(ctx context.Context, submission *Submission) (string, error) {
+	var resp response
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/submit", newRequest(submission, true), &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// Get returns the submission from the history of the engine.
+func (c *Client) Get(ctx context.Context, id string) (*Record, error) {
+	var record Record
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/submissions/"+url.PathEscape(id), nil, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Wait polls the submission until it has finished or ctx is done.
+func (c *Client) Wait(ctx context.Context, id string) (*Record, error) {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		record, err := c.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if record.Finished() {
+			return record, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cancel kills a running submission, it finishes with the cancelled verdict.
+func (c *Client) Cancel(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodPost, "/api/v1/submissions/"+url.PathEscape(id)+"/cancel", nil, nil)
+}
+
+// Stream executes the submission and copies its output to stdout and stderr while the program runs.
+// Cancelling ctx kills the program.
+func (c *Client) Stream(ctx context.Context, submission *Submission, stdout, stderr io.Writer) (*Result, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/api/v1/submit/stream", newRequest(submission, false))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event streamEvent
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("rce: the stream ended without a result: %w", io.ErrUnexpectedEOF)
+			}
+			return nil, fmt.Errorf("rce: failed to read the stream: %w", err)
+		}
+
+		switch event.Type {
+		case "output":
+			w := stdout
+			if event.Stream == "stderr" {
+				w = stderr
+			}
+			if _, err := io.WriteString(w, event.Data); err != nil {
+				return nil, fmt.Errorf("rce: failed to write the output: %w", err)
+			}
+		case "result":
+			if event.Result == nil {
+				return nil, fmt.Errorf("rce: the result event has no result")
+			}
+			return newResult(event.Result), nil
+		case "error":
+			if event.Error == nil {
+				return nil, fmt.Errorf("rce: the error event has no error: %w", ErrExecutionFailed)
+			}
+			return nil, fmt.Errorf("rce: %s: %s: %w", event.Error.Code, event.Error.Message, ErrExecutionFailed)
+		}
+	}
+}
+
+// UploadInput streams a large input to the engine, submissions reference it by the ID of the returned input.
+// Unlike the other requests the upload isn't retried, since the content can't be read twice.
+func (c *Client) UploadInput(ctx context.Context, content io.Reader) (*Input, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/inputs", content)
+	if err != nil {
+		return nil, fmt.Errorf("rce: failed to create the request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	c.setAuthHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rce: failed to send the request: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, readAPIError(resp)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var input Input
+	if err := json.NewDecoder(resp.Body).Decode(&input); err != nil {
+		return nil, fmt.Errorf("rce: failed to decode the response: %w", err)
+	}
+	return &input, nil
+}
+
+// Languages returns the languages supported by the engine.
+func (c *Client) Languages(ctx context.Context) ([]Language, error) {
+	var resp struct {
+		Languages []Language `json:"languages"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/languages", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Languages, nil
+}
+
+// doJSON sends the request and decodes the response into out unless it is nil.
+func (c *Client) doJSON(ctx context.Context, method, path string, body, out any) error {
+	resp, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("rce: failed to decode the response: %w", err)
+	}
+	return nil
+}
+
+// setAuthHeaders sets the token and the tenant of the client.
+func (c *Client) setAuthHeaders(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.tenant != "" {
+		req.Header.Set(TenantHeader, c.tenant)
+	}
+}
+
+// do sends the request, retrying it while the engine answers with 429 or 503. Error responses are
+// returned as *APIError, the body of a successful response has to be closed by the caller.
+func (c *Client) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("rce: failed to encode the request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("rce: failed to create the request: %w", err)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		c.setAuthHeaders(req)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("rce: failed to send the request: %w", err)
+		}
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		apiErr := readAPIError(resp)
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+		if !retryable || attempt >= c.maxRetries {
+			return nil, apiErr
+		}
+
+		delay := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if delay < 0 {
+			delay = c.retryBackoff << attempt
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func readAPIError(resp *http.Response) *APIError {
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    http.StatusText(resp.StatusCode),
+		RequestID:  resp.Header.Get(requestIDHeader),
+	}
+	var body errorResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err == nil && body.Error != nil {
+		apiErr.Code = body.Error.Code
+		apiErr.Message = body.Error.Message
+		if body.Error.RequestID != "" {
+			apiErr.RequestID = body.Error.RequestID
+		}
+	}
+	return apiErr
+}
+
+// retryAfter parses a Retry-After header, either seconds or an HTTP date. It returns -1 if the header is
+// missing or invalid.
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return -1
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return -1
+}
+
+func newRequest(submission *Submission, async bool) *request {
+	req := &request{
+		Code:          base64.StdEncoding.EncodeToString(submission.Code),
+		Input:         base64.StdEncoding.EncodeToString(submission.Input),
+		InputID:       submission.InputID,
+		Language:      submission.Language,
+		Version:       submission.Version,
+		Entrypoint:    submission.Entrypoint,
+		Artifacts:     submission.Artifacts,
+		NoCache:       submission.NoCache,
+		Limits:        submission.Limits,
+		Benchmark:     submission.Benchmark,
+		Deterministic: submission.Deterministic,
+		Async:         async,
+	}
+	if async {
+		req.CallbackURL = submission.CallbackURL
+	}
+	for _, f := range submission.Files {
+		req.Files = append(req.Files, file{
+			Path:    f.Path,
+			Content: base64.StdEncoding.EncodeToString(f.Content),
+		})
+	}
+	return req
+}
+
+func newResult(resp *response) *Result {
+	result := &Result{
+		ID:                 resp.ID,
+		Status:             resp.Status,
+		Output:             resp.Output,
+		Verdict:            resp.Verdict,
+		ExitCode:           resp.ExitCode,
+		Version:            resp.Version,
+		ArtifactsTruncated: resp.ArtifactsTruncated,
+		Truncated:          resp.Truncated,
+		Usage:              resp.Usage,
+		ContainerUsage:     resp.ContainerUsage,
+		Benchmark:          resp.Benchmark,
+		Environment:        resp.Environment,
+		Cached:             resp.Cached,
+	}
+	for _, a := range resp.Artifacts {
+		// The engine encodes the artifacts, a broken one is returned empty rather than failing the result.
+		content, _ := base64.StdEncoding.DecodeString(a.Content)
+		result.Artifacts = append(result.Artifacts, Artifact{
+			Path:    a.Path,
+			Content: content,
+			Size:    a.Size,
+		})
+	}
+	return result
+}
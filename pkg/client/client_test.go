@@ -0,0 +1,376 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubmit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode the request: %v", err)
+		}
+		if req.Code != "cHJpbnQoMSk=" || req.Input != "" || req.Async {
+			t.Errorf("unexpected request %+v", req)
+		}
+		if r.Header.Get(TenantHeader) != "cs101" {
+			t.Errorf("expected the tenant cs101, got %q", r.Header.Get(TenantHeader))
+		}
+		if r.Header.Get("Authorization") != "Bearer t1" {
+			t.Errorf("expected the token t1, got %q", r.Header.Get("Authorization"))
+		}
+		fmt.Fprint(w, `{"id": "1", "status": "finished", "output": "1\n", "verdict": "ok",
+			"artifacts": [{"path": "out.txt", "content": "aGk=", "size": 2}], "usage": {"cpu_time_ms": 20, "peak_memory_kb": 8192}}`)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, WithTenant("cs101"), WithToken("t1"))
+	result, err := client.Submit(context.Background(), &Submission{Language: "python", Code: []byte("print(1)")})
+	if err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if result.Verdict != VerdictOK || result.Output != "1\n" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if len(result.Artifacts) != 1 || string(result.Artifacts[0].Content) != "hi" {
+		t.Errorf("expected the decoded artifact 'hi', got %+v", result.Artifacts)
+	}
+	if result.Usage == nil || *result.Usage != (Usage{CPUTimeMs: 20, PeakMemoryKB: 8192}) || result.ContainerUsage != nil {
+		t.Errorf("expected the usage of the program only, got %+v and %+v", result.Usage, result.ContainerUsage)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected error
+		code     string
+		message  string
+	}{
+		{
+			name:     "invalid submission",
+			status:   http.StatusBadRequest,
+			body:     `{"error": {"code": "unsupported_language", "message": "unsupported language \"cobol\"", "request_id": "42"}}`,
+			expected: ErrInvalidSubmission,
+			code:     "unsupported_language",
+			message:  `unsupported language "cobol"`,
+		},
+		{
+			name:     "not found",
+			status:   http.StatusNotFound,
+			body:     `{"error": {"code": "not_found", "message": "Submission not found", "request_id": "42"}}`,
+			expected: ErrNotFound,
+			code:     "not_found",
+			message:  "Submission not found",
+		},
+		{
+			name:     "not running",
+			status:   http.StatusConflict,
+			body:     `{"error": {"code": "not_running", "message": "The submission is not running", "request_id": "42"}}`,
+			expected: ErrNotRunning,
+			code:     "not_running",
+			message:  "The submission is not running",
+		},
+		{
+			name:     "image changed",
+			status:   http.StatusConflict,
+			body:     `{"error": {"code": "image_changed", "message": "The image of the language has a different digest than image_digest", "request_id": "42"}}`,
+			expected: ErrImageChanged,
+			code:     "image_changed",
+			message:  "The image of the language has a different digest than image_digest",
+		},
+		{
+			name:     "body without an error",
+			status:   http.StatusInternalServerError,
+			body:     `oops`,
+			expected: ErrExecutionFailed,
+			message:  "Internal Server Error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			err := New(server.URL).Cancel(context.Background(), "1")
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status || apiErr.Code != tt.code || apiErr.Message != tt.message {
+				t.Errorf("expected an API error with status %d, code %q and message %q, got %v", tt.status, tt.code, tt.message, err)
+			}
+			if tt.code != "" && apiErr.RequestID != "42" {
+				t.Errorf("expected the request ID 42, got %q", apiErr.RequestID)
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           int
+		failures         int32
+		maxRetries       int
+		expectedErr      error
+		expectedAttempts int32
+	}{
+		{
+			name:             "recovers after 429",
+			status:           http.StatusTooManyRequests,
+			failures:         2,
+			maxRetries:       3,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "gives up after the retries",
+			status:           http.StatusServiceUnavailable,
+			failures:         10,
+			maxRetries:       2,
+			expectedErr:      ErrUnavailable,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "no retries for other errors",
+			status:           http.StatusBadRequest,
+			failures:         10,
+			maxRetries:       3,
+			expectedErr:      ErrInvalidSubmission,
+			expectedAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts.Add(1) <= tt.failures {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(tt.status)
+					return
+				}
+				fmt.Fprint(w, `{"languages": [{"name": "go"}]}`)
+			}))
+			defer server.Close()
+
+			client := New(server.URL, WithRetries(tt.maxRetries, time.Hour))
+			_, err := client.Languages(context.Background())
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected %v, got %v", tt.expectedErr, err)
+			}
+			if attempts.Load() != tt.expectedAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.expectedAttempts, attempts.Load())
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		header   string
+		expected time.Duration
+	}{
+		{header: "", expected: -1},
+		{header: "3", expected: 3 * time.Second},
+		{header: "Mon, 01 Jan 2024 00:00:10 GMT", expected: 10 * time.Second},
+		{header: "Sun, 31 Dec 2023 00:00:00 GMT", expected: 0},
+		{header: "soon", expected: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if delay := retryAfter(tt.header, now); delay != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, delay)
+			}
+		})
+	}
+}
+
+func TestWait(t *testing.T) {
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/submissions/42" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		status := StatusRunning
+		if polls.Add(1) == 3 {
+			status = StatusFinished
+		}
+		fmt.Fprintf(w, `{"id": "42", "status": %q, "verdict": "ok"}`, status)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, WithPollInterval(time.Millisecond))
+	record, err := client.Wait(context.Background(), "42")
+	if err != nil {
+		t.Fatalf("failed to wait for the submission: %v", err)
+	}
+	if !record.Finished() || polls.Load() != 3 {
+		t.Errorf("expected the submission to finish after 3 polls, got %+v after %d polls", record, polls.Load())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := New(server.URL).Wait(ctx, "42"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancellation of the context, got %v", err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name           string
+		events         string
+		expectedStdout string
+		expectedStderr string
+		expectedErr    error
+	}{
+		{
+			name: "result",
+			events: `{"type": "started", "id": "1"}
+{"type": "output", "stream": "stdout", "data": "hello "}
+{"type": "output", "stream": "stderr", "data": "warning"}
+{"type": "output", "stream": "stdout", "data": "world"}
+{"type": "result", "result": {"id": "1", "status": "finished", "verdict": "ok"}}
+`,
+			expectedStdout: "hello world",
+			expectedStderr: "warning",
+		},
+		{
+			name: "execution failed",
+			events: `{"type": "started", "id": "1"}
+{"type": "error", "error": {"code": "daemon_unavailable", "message": "The container runtime is unavailable, retry later"}}
+`,
+			expectedErr: ErrExecutionFailed,
+		},
+		{
+			name: "stream cut off",
+			events: `{"type": "started", "id": "1"}
+`,
+			expectedErr: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/submit/stream" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				w.Header().Set("Content-Type", "application/x-ndjson")
+				fmt.Fprint(w, tt.events)
+			}))
+			defer server.Close()
+
+			var stdout, stderr strings.Builder
+			result, err := New(server.URL).Stream(context.Background(), &Submission{Language: "go"}, &stdout, &stderr)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("expected %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to stream: %v", err)
+			}
+			if result.Verdict != VerdictOK || stdout.String() != tt.expectedStdout || stderr.String() != tt.expectedStderr {
+				t.Errorf("unexpected result %+v with stdout %q and stderr %q", result, stdout.String(), stderr.String())
+			}
+		})
+	}
+}
+
+func TestUploadInput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		if r.URL.Path != "/api/v1/inputs" || string(content) != "1 2 3\n" {
+			t.Errorf("unexpected upload to %s: %q", r.URL.Path, content)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"input_id": "abc", "size": 6}`)
+	}))
+	defer server.Close()
+
+	input, err := New(server.URL).UploadInput(context.Background(), strings.NewReader("1 2 3\n"))
+	if err != nil {
+		t.Fatalf("failed to upload the input: %v", err)
+	}
+	if input.ID != "abc" || input.Size != 6 {
+		t.Errorf("unexpected input %+v", input)
+	}
+}
+
+func TestBenchmark(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode the request: %v", err)
+		}
+		if req.Benchmark == nil || *req.Benchmark != (Benchmark{Runs: 5, Warmups: 1}) {
+			t.Errorf("expected 5 runs after 1 warmup, got %+v", req.Benchmark)
+		}
+		fmt.Fprint(w, `{"status": "finished", "verdict": "ok", "benchmark": {"runs": 5, "warmups": 1,
+			"wall_time_ms": {"min": 10, "median": 11, "p95": 14, "stddev": 1.5},
+			"cpu_time_ms": {"min": 9, "median": 10, "p95": 12, "stddev": 1},
+			"peak_memory_kb": {"min": 2048, "median": 2048, "p95": 2048, "stddev": 0}}}`)
+	}))
+	defer server.Close()
+
+	result, err := New(server.URL).Submit(context.Background(), &Submission{
+		Language:  "python",
+		Code:      []byte("print(1)"),
+		Benchmark: &Benchmark{Runs: 5, Warmups: 1},
+	})
+	if err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if result.Benchmark == nil || result.Benchmark.WallTimeMs != (Summary{Min: 10, Median: 11, P95: 14, Stddev: 1.5}) {
+		t.Errorf("expected the statistics of the benchmark, got %+v", result.Benchmark)
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode the request: %v", err)
+		}
+		if req.Deterministic == nil || req.Deterministic.Seed != 42 {
+			t.Errorf("expected a deterministic run with the seed 42, got %+v", req.Deterministic)
+		}
+		fmt.Fprint(w, `{"status": "finished", "verdict": "ok", "environment": {"image_digest": "sha256:abc",
+			"command": ["sh", "-c", "python3 main.py"], "env": ["RCE_SEED=42"], "hostname": "rce-sandbox", "seed": 42,
+			"limits": {"time_limit_ms": 2000}}}`)
+	}))
+	defer server.Close()
+
+	result, err := New(server.URL).Submit(context.Background(), &Submission{
+		Language:      "python",
+		Code:          []byte("print(1)"),
+		Deterministic: &Deterministic{Seed: 42},
+	})
+	if err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if result.Environment == nil || result.Environment.ImageDigest != "sha256:abc" || result.Environment.Limits.TimeLimitMs != 2000 {
+		t.Errorf("expected the environment of the run, got %+v", result.Environment)
+	}
+}
@@ -0,0 +1,268 @@
+package client
+
+import "time"
+
+// Statuses of a submission.
+const (
+	StatusRunning  = "running"
+	StatusFinished = "finished"
+)
+
+// Verdicts of a finished submission.
+const (
+	VerdictOK                = "ok"
+	VerdictCompilationError  = "compilation_error"
+	VerdictRuntimeError      = "runtime_error"
+	VerdictTimeLimitExceeded = "time_limit_exceeded"
+	// The kernel killed the program because it ran out of memory.
+	VerdictMemoryLimitExceeded = "memory_limit_exceeded"
+	// The output is cut off, see Result.Truncated.
+	VerdictOutputLimitExceeded = "output_limit_exceeded"
+	VerdictInternalError       = "internal_error"
+	VerdictCancelled           = "cancelled"
+)
+
+// Submission is the code to execute. Code, input and files are encoded by the client.
+type Submission struct {
+	Language string
+	// Optional, the default version of the language is used if it is empty.
+	Version string
+	Code    []byte
+	Input   []byte
+	// ID of an input uploaded with UploadInput, used instead of Input.
+	InputID string
+	// Files of a multi-file submission, Code is ignored if there are any.
+	Files []File
+	// Optional path of the file to run.
+	Entrypoint string
+	// Globs of the files written by the program which are returned, e.g. "out/*.csv".
+	Artifacts []string
+	// Execute the code even if the result is cached.
+	NoCache bool
+	// Optional limits for this submission, they may only be tighter than the limits of the language.
+	// Zero limits keep the limits of the language.
+	Limits *Limits
+	// Runs the program repeatedly and returns statistics of the runs in Result.Benchmark.
+	Benchmark *Benchmark
+	// Runs the code in a fixed environment which is recorded in Result.Environment.
+	Deterministic *Deterministic
+	// The result is posted to this URL once the submission is finished, only used by SubmitAsync.
+	CallbackURL string
+}
+
+// Input is an input uploaded with UploadInput.
+type Input struct {
+	// SHA-256 of the content, uploading the same content again returns the same ID.
+	ID   string `json:"input_id"`
+	Size int64  `json:"size"`
+}
+
+// Benchmark runs the compiled program Runs times after Warmups unmeasured runs, both are limited by the engine.
+type Benchmark struct {
+	Runs    int `json:"runs"`
+	Warmups int `json:"warmups,omitempty"`
+}
+
+// Deterministic runs the code with the image pinned to its digest, a fixed env and hostname and random number env
+// vars seeded with Seed.
+type Deterministic struct {
+	Seed int64 `json:"seed"`
+	// Optional digest of the image to run, e.g. Environment.ImageDigest of an earlier run. The submission fails with
+	// the image_changed error code if the image was removed since.
+	ImageDigest string `json:"image_digest,omitempty"`
+}
+
+type File struct {
+	// Path relative to the submission directory, e.g. "src/util.h".
+	Path    string
+	Content []byte
+}
+
+// Result of a submission.
+type Result struct {
+	// Empty if the submission history of the engine is disabled.
+	ID       string
+	Status   string
+	Output   string
+	Verdict  string
+	ExitCode int
+	Version  string
+	// Files written by the program which match the artifact globs.
+	Artifacts []Artifact
+	// Set if some of the matching files were left out because of the artifact limits.
+	ArtifactsTruncated bool
+	// Set if the output is cut off because the program exceeded the output limit of the engine.
+	Truncated bool
+	// Usage of the program, nil if the command of the language doesn't report it.
+	Usage *Usage
+	// Usage of the whole container including the compilation, nil if it exited before it was sampled.
+	ContainerUsage *Usage
+	// Statistics of a benchmark, nil unless every run succeeded.
+	Benchmark *BenchmarkResult
+	// Conditions of a deterministic run, nil for other runs.
+	Environment *Environment
+	// Set if the result was served from the result cache of the engine.
+	Cached bool
+}
+
+// BenchmarkResult summarizes the measured runs of a benchmark.
+type BenchmarkResult struct {
+	Runs         int     `json:"runs"`
+	Warmups      int     `json:"warmups"`
+	WallTimeMs   Summary `json:"wall_time_ms"`
+	CPUTimeMs    Summary `json:"cpu_time_ms"`
+	PeakMemoryKB Summary `json:"peak_memory_kb"`
+}
+
+// Summary holds the statistics of a metric over the runs of a benchmark.
+type Summary struct {
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	P95    float64 `json:"p95"`
+	Stddev float64 `json:"stddev"`
+}
+
+// Environment records the conditions of a deterministic run, submitting the same code and input with the seed and
+// the image digest reproduces the run.
+type Environment struct {
+	ImageDigest string   `json:"image_digest"`
+	Command     []string `json:"command"`
+	// Env passed to the container, on top of the env of the image.
+	Env      []string `json:"env"`
+	Hostname string   `json:"hostname"`
+	Seed     int64    `json:"seed"`
+	// Offset of the faked clock, 0 if the time isn't faked.
+	TimeOffsetSeconds int64  `json:"time_offset_seconds"`
+	Limits            Limits `json:"limits"`
+}
+
+// Usage is the CPU time (user and system) and the peak resident memory of an execution.
+type Usage struct {
+	CPUTimeMs    int64 `json:"cpu_time_ms"`
+	PeakMemoryKB int64 `json:"peak_memory_kb"`
+}
+
+type Artifact struct {
+	Path    string
+	Content []byte
+	Size    int64
+}
+
+// Record is a submission recorded in the history of the engine.
+type Record struct {
+	ID         string        `json:"id"`
+	Tenant     string        `json:"tenant"`
+	Language   string        `json:"language"`
+	Version    string        `json:"version"`
+	Status     string        `json:"status"`
+	Verdict    string        `json:"verdict"`
+	ExitCode   int           `json:"exit_code"`
+	Output     string        `json:"output"`
+	Cached     bool          `json:"cached"`
+	CreatedAt  time.Time     `json:"created_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Duration   time.Duration `json:"duration_ns"`
+}
+
+// Finished reports whether the submission has finished executing.
+func (r *Record) Finished() bool {
+	return r.Status == StatusFinished
+}
+
+// Language describes a language supported by the engine.
+type Language struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Extension   string `json:"extension"`
+	EditorMode  string `json:"editor_mode"`
+	Template    string `json:"template"`
+	// Flags and limits of languages without versions, the versions describe them otherwise.
+	Flags          string    `json:"flags"`
+	Limits         *Limits   `json:"limits"`
+	DefaultVersion string    `json:"default_version"`
+	Versions       []Version `json:"versions"`
+}
+
+type Version struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Flags       string `json:"flags"`
+	Limits      Limits `json:"limits"`
+}
+
+// Limits an execution runs with, the resource limits are 0 if the engine doesn't enforce them.
+type Limits struct {
+	TimeLimitMs int64 `json:"time_limit_ms"`
+	// 0 if the CPU time isn't limited separately from the wall time.
+	CPUTimeLimitMs int64   `json:"cpu_time_limit_ms"`
+	MemoryMB       int64   `json:"memory_mb"`
+	CPUs           float64 `json:"cpus"`
+	MaxProcesses   int64   `json:"max_processes"`
+	MaxFileSizeMB  int64   `json:"max_file_size_mb"`
+}
+
+// Wire formats of the REST API.
+
+type request struct {
+	Code          string         `json:"code"`
+	Input         string         `json:"input"`
+	InputID       string         `json:"input_id,omitempty"`
+	Language      string         `json:"language"`
+	Version       string         `json:"version,omitempty"`
+	Files         []file         `json:"files,omitempty"`
+	Entrypoint    string         `json:"entrypoint,omitempty"`
+	Artifacts     []string       `json:"artifacts,omitempty"`
+	NoCache       bool           `json:"no_cache,omitempty"`
+	Limits        *Limits        `json:"limits,omitempty"`
+	Benchmark     *Benchmark     `json:"benchmark,omitempty"`
+	Deterministic *Deterministic `json:"deterministic,omitempty"`
+	Async         bool           `json:"async,omitempty"`
+	CallbackURL   string         `json:"callback_url,omitempty"`
+}
+
+type file struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+type response struct {
+	ID                 string           `json:"id"`
+	Status             string           `json:"status"`
+	Output             string           `json:"output"`
+	Verdict            string           `json:"verdict"`
+	ExitCode           int              `json:"exit_code"`
+	Version            string           `json:"version"`
+	Artifacts          []artifact       `json:"artifacts"`
+	ArtifactsTruncated bool             `json:"artifacts_truncated"`
+	Truncated          bool             `json:"truncated"`
+	Usage              *Usage           `json:"usage"`
+	ContainerUsage     *Usage           `json:"container_usage"`
+	Benchmark          *BenchmarkResult `json:"benchmark"`
+	Environment        *Environment     `json:"environment"`
+	Cached             bool             `json:"cached"`
+}
+
+type artifact struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Size    int64  `json:"size"`
+}
+
+type streamEvent struct {
+	Type   string    `json:"type"`
+	ID     string    `json:"id"`
+	Stream string    `json:"stream"`
+	Data   string    `json:"data"`
+	Result *response `json:"result"`
+	Error  *apiError `json:"error"`
+}
+
+type errorResponse struct {
+	Error *apiError `json:"error"`
+}
+
+type apiError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id"`
+}
@@ -1,9 +1,15 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
-	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,36 +18,180 @@ type Language string
 
 // Supported languages
 const (
-	Golang Language = "golang"
-	Cpp    Language = "cpp"
+	Golang     Language = "golang"
+	Cpp        Language = "cpp"
+	C          Language = "c"
+	Python     Language = "python"
+	Java       Language = "java"
+	Rust       Language = "rust"
+	JavaScript Language = "javascript"
 )
 
-var (
-	BaseCodePath        string
-	ResourceConstraints bool
+// Placeholders which are replaced in the command before creating the code container.
+const (
+	PlaceholderLanguage = "{{LANGUAGE}}"
+	PlaceholderFile     = "{{FILE}}"
+	PlaceholderInput    = "{{INPUT}}"
+	PlaceholderVersion  = "{{VERSION}}"
+	PlaceholderFlags    = "{{FLAGS}}"
+
+	// Entry point of the submission relative to PlaceholderDir, which is the directory holding all the files.
+	PlaceholderEntrypoint = "{{ENTRYPOINT}}"
+	PlaceholderDir        = "{{DIR}}"
 )
 
 type LanguageConfig struct {
+	// Metadata for editors, returned by the language catalog.
+	DisplayName string `yaml:"display_name,omitempty"`
+	// Mode name of the language in Monaco and CodeMirror, e.g. "cpp" or "go".
+	EditorMode string `yaml:"editor_mode,omitempty"`
+	// Hello world program shown in a new editor.
+	Template string `yaml:"template,omitempty"`
+
 	Extension string `yaml:"extension"`
-	Image     string `yaml:"image"`
-	Command   string `yaml:"command"`
+	Image     string `yaml:"image,omitempty"`
+	Command   string `yaml:"command,omitempty"`
+	// Command for multi-file submissions, defaults to the command.
+	ProjectCommand string `yaml:"project_command,omitempty"`
+	// Compiler flags, passed to the command through the {{FLAGS}} placeholder.
+	Flags  string `yaml:"flags,omitempty"`
+	Limits Limits `yaml:"limits,omitempty"`
+
+	// Directories of the host mounted read-only into the containers, e.g. a prepopulated Go module cache,
+	// so that submissions can use libraries although the containers have no network.
+	Caches []Cache `yaml:"caches,omitempty"`
+	// Environment variables of the containers, e.g. pointing the toolchain to the caches.
+	Env map[string]string `yaml:"env,omitempty"`
+
+	// A language may offer several toolchain versions, each of them overriding the settings above.
+	// The default version is used when a submission doesn't ask for a specific one.
+	DefaultVersion string                   `yaml:"default_version,omitempty"`
+	Versions       map[string]VersionConfig `yaml:"versions,omitempty"`
+}
+
+type VersionConfig struct {
+	DisplayName string `yaml:"display_name,omitempty"`
+
+	Image          string `yaml:"image,omitempty"`
+	Command        string `yaml:"command,omitempty"`
+	ProjectCommand string `yaml:"project_command,omitempty"`
+	Flags          string `yaml:"flags,omitempty"`
+	Limits         Limits `yaml:"limits,omitempty"`
+}
+
+// Cache is a dependency cache shared by all the versions of a language.
+type Cache struct {
+	// Absolute path of the directory on the host.
+	Source string `yaml:"source"`
+	// Absolute path the directory is mounted at in the containers.
+	Target string `yaml:"target"`
+	// Dependencies the populate-cache subcommand downloads into the source directory: Go modules
+	// as "path@version" or URLs of files, e.g. single-header C++ libraries.
+	Modules []string `yaml:"modules,omitempty"`
+}
+
+// IsURLModule reports whether the module is a file to download rather than a Go module.
+func IsURLModule(module string) bool {
+	return strings.HasPrefix(module, "https://") || strings.HasPrefix(module, "http://")
+}
+
+// Limits restrict the resources of a single execution. Zero values fall back to the defaults.
+type Limits struct {
+	// Wall time after which the container is killed, capped at the max execution time of the server.
+	TimeLimit time.Duration `yaml:"time_limit,omitempty"`
+	// CPU time (user and system) the program may use, enforced by run-code.sh for the program only.
+	// 0 doesn't limit the CPU time separately from the wall time.
+	CPUTimeLimit time.Duration `yaml:"cpu_time_limit,omitempty"`
+
+	// The remaining limits are only applied when resource constraints are enabled.
+	MemoryMB      int64   `yaml:"memory_mb,omitempty"`
+	CPUs          float64 `yaml:"cpus,omitempty"`
+	MaxProcesses  int64   `yaml:"max_processes,omitempty"`
+	MaxFileSizeMB int64   `yaml:"max_file_size_mb,omitempty"`
+}
+
+// Limits used when neither the language nor the version configures them. There is no default
+// time limit, executions run up to the max execution time of the server.
+var DefaultLimits = Limits{
+	MemoryMB:      500,
+	CPUs:          1,
+	MaxProcesses:  128,
+	MaxFileSizeMB: 20,
 }
 
 type ImageConfig map[Language]LanguageConfig
 
-func LoadConfig(configPath string) (*ImageConfig, error) {
+// The server settings live under this key of the config file, every other key is a language.
+const serverSection = "server"
+
+// File is the content of the config file.
+type File struct {
+	Server    ServerConfig
+	Languages ImageConfig
+}
+
+// LoadFile loads and validates the config file. Server settings which are not set in the
+// file keep their defaults.
+func LoadFile(configPath string) (*File, error) {
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read the config file: %w", err)
 	}
 
-	var config ImageConfig
-	err = yaml.Unmarshal(data, &config)
-	if err != nil {
+	var sections map[string]yaml.Node
+	if err = yaml.Unmarshal(data, &sections); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal the config file: %w", err)
 	}
 
-	return &config, nil
+	file := &File{
+		Server:    DefaultServerConfig(),
+		Languages: ImageConfig{},
+	}
+	languages := map[string]yaml.Node{}
+	for key, node := range sections {
+		if key == serverSection {
+			if err = decodeStrict(&node, &file.Server); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal the server section of the config file: %w", err)
+			}
+			continue
+		}
+		languages[key] = node
+	}
+
+	if err = decodeStrict(languages, &file.Languages); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the config file: %w", err)
+	}
+
+	if err := errors.Join(file.Server.Validate(), file.Languages.Validate()); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
+	}
+
+	return file, nil
+}
+
+// LoadConfig loads the languages of the config file.
+func LoadConfig(configPath string) (*ImageConfig, error) {
+	file, err := LoadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return &file.Languages, nil
+}
+
+// decodeStrict decodes value into out rejecting unknown fields, a typo in a field name
+// would otherwise silently fall back to the default.
+func decodeStrict(value any, out any) error {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
 }
 
 func (c *ImageConfig) GetLanguageConfig(lang Language) LanguageConfig {
@@ -53,18 +203,23 @@ func (c *ImageConfig) IsLanguageSupported(lang Language) bool {
 	return ok
 }
 
+// GetSupportedLanguages returns the languages in a stable order.
 func (c *ImageConfig) GetSupportedLanguages() []Language {
-	languages := make([]Language, 0, len(*c))
-	for lang := range *c {
-		languages = append(languages, lang)
-	}
-	return languages
+	return slices.Sorted(maps.Keys(*c))
 }
 
-func GetHostLanguageCodePath(lang Language) string {
-	return filepath.Join(BaseCodePath, string(lang))
+// GetCommand returns the command for single-file or multi-file submissions.
+func (l LanguageConfig) GetCommand(project bool) string {
+	if project && l.ProjectCommand != "" {
+		return l.ProjectCommand
+	}
+	return l.Command
 }
 
-func IsResourceConstraintsEnabled() bool {
-	return ResourceConstraints
+// GetDisplayName returns the display name of the language, falling back to its name.
+func (l LanguageConfig) GetDisplayName(lang Language) string {
+	if l.DisplayName != "" {
+		return l.DisplayName
+	}
+	return string(lang)
 }
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -17,12 +21,21 @@ func TestLoadConfig(t *testing.T) {
 		Golang: {
 			Extension: ".go",
 			Image:     "golang:latest",
-			Command:   "go build",
+			Command:   "go run {{FILE}} < {{INPUT}}",
 		},
 		Cpp: {
 			Extension: ".cpp",
 			Image:     "cpp:latest",
-			Command:   "g++ -o main",
+			Command:   "g++ {{FLAGS}} {{FILE}} -o main && ./main < {{INPUT}}",
+			Limits: Limits{
+				TimeLimit: 5 * time.Second,
+				MemoryMB:  256,
+			},
+			DefaultVersion: "c++17",
+			Versions: map[string]VersionConfig{
+				"c++17": {Flags: "-std=c++17"},
+				"c++20": {Image: "cpp20:latest", Flags: "-std=c++20", Limits: Limits{MemoryMB: 512}},
+			},
 		},
 	}
 
@@ -56,14 +69,15 @@ func TestLoadConfig(t *testing.T) {
 			t.Fatalf("language %s not found in loaded config", lang)
 		}
 
-		if loadedLangConfig != expectedConfig {
+		if !reflect.DeepEqual(loadedLangConfig, expectedConfig) {
 			t.Errorf("expected config for language %s: %+v, got: %+v", lang, expectedConfig, loadedLangConfig)
 		}
 	}
 }
 
 func TestGetHostLanguageCodePath(t *testing.T) {
-	BaseCodePath = "/base/path"
+	serverConfig := DefaultServerConfig()
+	serverConfig.CodeDir = "/base/path"
 
 	tests := []struct {
 		lang     Language
@@ -75,10 +89,196 @@ func TestGetHostLanguageCodePath(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(string(tt.lang), func(t *testing.T) {
-			result := GetHostLanguageCodePath(tt.lang)
+			result := serverConfig.GetHostLanguageCodePath(tt.lang)
 			if result != tt.expected {
 				t.Errorf("expected %s, got %s", tt.expected, result)
 			}
 		})
 	}
 }
+
+func TestLoadConfigRejectsUnknownFields(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := "golang:\n  extension: \".go\"\n  image: \"golang:latest\"\n  comand: \"go run {{FILE}}\"\n"
+	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write the config file: %v", err)
+	}
+
+	_, err := LoadConfig(configPath)
+	if err == nil || !strings.Contains(err.Error(), "comand") {
+		t.Errorf("expected an error about the unknown field, got: %v", err)
+	}
+}
+
+func TestLoadRepositoryConfig(t *testing.T) {
+	if _, err := LoadConfig("../../config.yml"); err != nil {
+		t.Errorf("expected the config of the repository to be valid: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := LanguageConfig{Extension: ".go", Image: "golang:latest", Command: "go run {{FILE}} < {{INPUT}}"}
+	with := func(modify func(*LanguageConfig)) LanguageConfig {
+		langConfig := valid
+		modify(&langConfig)
+		return langConfig
+	}
+
+	tests := []struct {
+		name    string
+		config  ImageConfig
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			config: ImageConfig{Golang: valid},
+		},
+		{
+			name:    "no languages",
+			config:  ImageConfig{},
+			wantErr: "no languages configured",
+		},
+		{
+			name:    "invalid language name",
+			config:  ImageConfig{"Go Lang": valid},
+			wantErr: "Go Lang: language names",
+		},
+		{
+			name:    "missing image",
+			config:  ImageConfig{Golang: with(func(c *LanguageConfig) { c.Image = "" })},
+			wantErr: "golang.image: image is required",
+		},
+		{
+			name:    "missing command",
+			config:  ImageConfig{Golang: with(func(c *LanguageConfig) { c.Command = "" })},
+			wantErr: "golang.command: command is required",
+		},
+		{
+			name:    "missing file placeholder",
+			config:  ImageConfig{Golang: with(func(c *LanguageConfig) { c.Command = "go run main.go" })},
+			wantErr: "golang.command: {{FILE}} or {{ENTRYPOINT}} is missing",
+		},
+		{
+			name: "invalid project command",
+			config: ImageConfig{Golang: with(func(c *LanguageConfig) {
+				c.ProjectCommand = "cd {{DIR}} && go run ./{{ENTRYPIONT}}"
+			})},
+			wantErr: "golang.project_command: unknown placeholder {{ENTRYPIONT}}, did you mean {{ENTRYPOINT}}?",
+		},
+		{
+			name:    "unknown placeholder",
+			config:  ImageConfig{Golang: with(func(c *LanguageConfig) { c.Command = "go run {{FILE}} < {{INPTU}}" })},
+			wantErr: "golang.command: unknown placeholder {{INPTU}}, did you mean {{INPUT}}?",
+		},
+		{
+			name: "relative cache source",
+			config: ImageConfig{Golang: with(func(c *LanguageConfig) {
+				c.Caches = []Cache{{Source: "cache/go", Target: "/go/pkg/mod"}}
+			})},
+			wantErr: `golang.caches[0].source: "cache/go" must be an absolute path`,
+		},
+		{
+			name: "duplicate cache target",
+			config: ImageConfig{Golang: with(func(c *LanguageConfig) {
+				c.Caches = []Cache{{Source: "/a", Target: "/go/pkg/mod"}, {Source: "/b", Target: "/go/pkg/mod/"}}
+			})},
+			wantErr: `golang.caches[1].target: "/go/pkg/mod/" is already used by another cache`,
+		},
+		{
+			name: "cache module without a version",
+			config: ImageConfig{Golang: with(func(c *LanguageConfig) {
+				c.Caches = []Cache{{Source: "/a", Target: "/go/pkg/mod", Modules: []string{"github.com/google/uuid"}}}
+			})},
+			wantErr: `golang.caches[0].modules: "github.com/google/uuid" is neither a URL nor a Go module with a version`,
+		},
+		{
+			name:    "invalid env name",
+			config:  ImageConfig{Golang: with(func(c *LanguageConfig) { c.Env = map[string]string{"GO-FLAGS": "-mod=mod"} })},
+			wantErr: "golang.env.GO-FLAGS: environment variable names may only contain letters, digits and underscores",
+		},
+		{
+			name:    "missing extension",
+			config:  ImageConfig{Golang: with(func(c *LanguageConfig) { c.Extension = "" })},
+			wantErr: "golang.extension: extension is required",
+		},
+		{
+			name:    "extension without a dot",
+			config:  ImageConfig{Golang: with(func(c *LanguageConfig) { c.Extension = "go" })},
+			wantErr: `golang.extension: "go" must start with a dot, use ".go"`,
+		},
+		{
+			name:    "duplicate extension",
+			config:  ImageConfig{Golang: valid, "golang1.21": valid},
+			wantErr: `golang1.21.extension: ".go" is already used by golang`,
+		},
+		{
+			name:    "time limit out of range",
+			config:  ImageConfig{Golang: with(func(c *LanguageConfig) { c.Limits.TimeLimit = time.Hour })},
+			wantErr: "golang.limits.time_limit: 1h0m0s is out of range",
+		},
+		{
+			name:    "cpu time limit out of range",
+			config:  ImageConfig{Golang: with(func(c *LanguageConfig) { c.Limits.CPUTimeLimit = time.Millisecond })},
+			wantErr: "golang.limits.cpu_time_limit: 1ms is out of range",
+		},
+		{
+			name:    "memory out of range",
+			config:  ImageConfig{Golang: with(func(c *LanguageConfig) { c.Limits.MemoryMB = 1 })},
+			wantErr: "golang.limits.memory_mb: 1 is out of range",
+		},
+		{
+			name:    "negative processes",
+			config:  ImageConfig{Golang: with(func(c *LanguageConfig) { c.Limits.MaxProcesses = -1 })},
+			wantErr: "golang.limits.max_processes: -1 is out of range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateImages(t *testing.T) {
+	config := ImageConfig{
+		Golang: {Extension: ".go", Image: "golang:latest", Command: "go run {{FILE}}"},
+		Cpp:    {Extension: ".cpp", Image: "cpp:latest", Command: "g++ {{FILE}}"},
+	}
+
+	present := map[string]bool{"golang:latest": true}
+	err := config.ValidateImages(context.Background(), func(_ context.Context, image string) (bool, error) {
+		return present[image], nil
+	})
+	if err == nil || !strings.Contains(err.Error(), `cpp.image: "cpp:latest" is not present`) {
+		t.Errorf("expected an error about the missing cpp image, got: %v", err)
+	}
+
+	present["cpp:latest"] = true
+	err = config.ValidateImages(context.Background(), func(_ context.Context, image string) (bool, error) {
+		return present[image], nil
+	})
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+func TestGetSupportedLanguagesIsSorted(t *testing.T) {
+	config := ImageConfig{"python": {}, Golang: {}, Cpp: {}, "c": {}}
+
+	languages := config.GetSupportedLanguages()
+	expected := []Language{"c", Cpp, Golang, "python"}
+	if !reflect.DeepEqual(languages, expected) {
+		t.Errorf("expected %v, got %v", expected, languages)
+	}
+}
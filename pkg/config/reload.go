@@ -0,0 +1,148 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// Editors and config management tools usually write a file in several steps, wait for them to settle down.
+const reloadDebounce = 500 * time.Millisecond
+
+// Reloader reloads the config file into a Store whenever the file changes or the process receives SIGHUP.
+type Reloader struct {
+	path   string
+	store  *Store
+	logger *zap.Logger
+
+	// prepare is called with the new config before it is swapped in, e.g. to create the code directories.
+	// The new config is discarded if it returns an error.
+	prepare func(*ImageConfig) error
+}
+
+func NewReloader(path string, store *Store, prepare func(*ImageConfig) error, logger *zap.Logger) *Reloader {
+	return &Reloader{
+		path:    path,
+		store:   store,
+		logger:  logger,
+		prepare: prepare,
+	}
+}
+
+// Reload loads and validates the config file and swaps it in. The config in use is kept if anything fails.
+func (r *Reloader) Reload() error {
+	config, err := LoadConfig(r.path)
+	if err != nil {
+		return err
+	}
+
+	if r.prepare != nil {
+		if err := r.prepare(config); err != nil {
+			return fmt.Errorf("failed to prepare the new config: %w", err)
+		}
+	}
+
+	r.store.Swap(config)
+	r.logger.Info("reloaded the config file",
+		zap.String("path", r.path),
+		zap.Any("languages", config.GetSupportedLanguages()),
+	)
+	return nil
+}
+
+// configVersion tells versions of the config file apart without reading it, by the file the path resolves to, its
+// size and its modification time.
+type configVersion struct {
+	target  string
+	size    int64
+	modTime time.Time
+}
+
+// version returns the version of the config file, the zero version if it can't be resolved.
+func (r *Reloader) version() configVersion {
+	target, err := filepath.EvalSymlinks(r.path)
+	if err != nil {
+		return configVersion{}
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return configVersion{target: target}
+	}
+	return configVersion{target: target, size: info.Size(), modTime: info.ModTime()}
+}
+
+// Watch reloads the config on file changes and SIGHUP until the context is cancelled.
+func (r *Reloader) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create the file watcher: %w", err)
+	}
+	defer func() {
+		_ = watcher.Close()
+	}()
+
+	// Watch the directory rather than the file, editors and kubernetes config maps replace the file
+	// instead of writing to it which would silently end a watch on the file itself. A config map links the
+	// file to ..data/<file> and swaps the ..data link on updates, so every event in the directory is
+	// followed by a check whether the path resolves to another version of the file.
+	current := r.version()
+	if err := watcher.Add(filepath.Dir(r.path)); err != nil {
+		return fmt.Errorf("failed to watch the config directory: %w", err)
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("stopping the config watcher")
+			return nil
+		case <-hangup:
+			r.logger.Info("received SIGHUP, reloading the config file")
+			current = r.version()
+			r.reload()
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+			debounce.Reset(reloadDebounce)
+		case <-debounce.C:
+			version := r.version()
+			if version == current {
+				continue
+			}
+			current = version
+			r.logger.Info("config file changed, reloading", zap.String("path", r.path))
+			r.reload()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			r.logger.Error("error while watching the config file", zap.Error(err))
+		}
+	}
+}
+
+func (r *Reloader) reload() {
+	if err := r.Reload(); err != nil {
+		r.logger.Error("failed to reload the config file, keeping the current config",
+			zap.String("path", r.path),
+			zap.Error(err),
+		)
+	}
+}
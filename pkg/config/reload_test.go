@@ -0,0 +1,176 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const validConfig = `golang:
+  extension: ".go"
+  image: "golang:latest"
+  command: "go run {{FILE}}"
+`
+
+const updatedConfig = validConfig + `cpp:
+  extension: ".cpp"
+  image: "cpp:latest"
+  command: "g++ {{FILE}}"
+`
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write the config file: %v", err)
+	}
+}
+
+func newTestReloader(t *testing.T, prepare func(*ImageConfig) error) (*Reloader, *Store, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	writeConfig(t, path, validConfig)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load the config: %v", err)
+	}
+
+	store := NewStore(config)
+	return NewReloader(path, store, prepare, zap.NewNop()), store, path
+}
+
+func TestReload(t *testing.T) {
+	reloader, store, path := newTestReloader(t, nil)
+	previous := store.Load()
+
+	writeConfig(t, path, updatedConfig)
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("failed to reload the config: %v", err)
+	}
+
+	if !store.Load().IsLanguageSupported(Cpp) {
+		t.Error("expected the reloaded config to support cpp")
+	}
+	if previous.IsLanguageSupported(Cpp) {
+		t.Error("expected the previous config to be left untouched")
+	}
+}
+
+func TestReloadKeepsConfigOnError(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		prepare func(*ImageConfig) error
+	}{
+		{
+			name:    "invalid yaml",
+			content: "golang: [",
+		},
+		{
+			name:    "invalid config",
+			content: "golang:\n  extension: \".go\"\n",
+		},
+		{
+			name:    "prepare fails",
+			content: updatedConfig,
+			prepare: func(*ImageConfig) error { return errors.New("failed") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reloader, store, path := newTestReloader(t, tt.prepare)
+			previous := store.Load()
+
+			writeConfig(t, path, tt.content)
+			if err := reloader.Reload(); err == nil {
+				t.Fatal("expected an error, but got none")
+			}
+
+			if store.Load() != previous {
+				t.Error("expected the current config to be kept")
+			}
+		})
+	}
+}
+
+// startWatching watches the config until the returned function is called.
+func startWatching(t *testing.T, reloader *Reloader) func() {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := reloader.Watch(ctx); err != nil {
+			t.Errorf("failed to watch the config: %v", err)
+		}
+	}()
+	// Give the watcher a moment to start watching the directory.
+	time.Sleep(100 * time.Millisecond)
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// waitForReload waits until the updated config is swapped in.
+func waitForReload(t *testing.T, store *Store) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !store.Load().IsLanguageSupported(Cpp) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the config to be reloaded after the file changed")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestWatchReloadsOnChange(t *testing.T) {
+	reloader, store, path := newTestReloader(t, nil)
+	defer startWatching(t, reloader)()
+
+	writeConfig(t, path, updatedConfig)
+	waitForReload(t, store)
+}
+
+func TestWatchReloadsOnConfigMapUpdate(t *testing.T) {
+	// A config map mounts config.yml -> ..data/config.yml and ..data -> a directory of the current version.
+	dir := t.TempDir()
+	for version, content := range map[string]string{"..v1": validConfig, "..v2": updatedConfig} {
+		if err := os.Mkdir(filepath.Join(dir, version), 0755); err != nil {
+			t.Fatalf("failed to create the version directory: %v", err)
+		}
+		writeConfig(t, filepath.Join(dir, version, "config.yml"), content)
+	}
+	symlink := func(target, name string) {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatalf("failed to create the link: %v", err)
+		}
+	}
+	symlink("..v1", "..data")
+	symlink("..data/config.yml", "config.yml")
+
+	path := filepath.Join(dir, "config.yml")
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load the config: %v", err)
+	}
+	store := NewStore(config)
+	defer startWatching(t, NewReloader(path, store, nil, zap.NewNop()))()
+
+	// The update swaps the ..data link, config.yml itself is left alone.
+	symlink("..v2", "..data_tmp")
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatalf("failed to swap the link: %v", err)
+	}
+	waitForReload(t, store)
+}
@@ -0,0 +1,434 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerConfig holds the settings of the server. They are loaded from the server section of the config
+// file and can be overridden by environment variables and then by flags, see the env and flag tags.
+// Unlike the languages they are not reloaded, changing them requires a restart.
+type ServerConfig struct {
+	Address string `yaml:"address" env:"RCE_ADDRESS" flag:"address" usage:"Address the server listens on"`
+	// The gRPC API is served on its own address, empty disables it.
+	GRPCAddress  string        `yaml:"grpc_address" env:"RCE_GRPC_ADDRESS" flag:"grpc-address" usage:"Address the gRPC server listens on, disabled if empty"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" env:"RCE_READ_TIMEOUT" flag:"read-timeout" usage:"Timeout for reading a request"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"RCE_WRITE_TIMEOUT" flag:"write-timeout" usage:"Timeout for writing a response, must be longer than the maximum execution time"`
+
+	// Executions are killed after this long, languages may configure a lower time limit but never a higher one.
+	MaxExecutionTime time.Duration `yaml:"max_execution_time" env:"RCE_MAX_EXECUTION_TIME" flag:"max-execution-time" usage:"Time after which an execution is killed"`
+
+	// Interval in which the zombie containers and the stale files are cleaned up.
+	GCInterval time.Duration `yaml:"gc_interval" env:"RCE_GC_INTERVAL" flag:"gc-interval" usage:"Interval of the garbage collector"`
+
+	// Path where the code files are mounted in the containers.
+	TargetMountPath string `yaml:"target_mount_path" env:"RCE_TARGET_MOUNT_PATH" flag:"target-mount-path" usage:"Path the code files are mounted at in the containers"`
+
+	// The code of every language is staged in a directory of this directory. The garbage collector sweeps every
+	// directory in it, it must not be shared with other files.
+	CodeDir             string `yaml:"code_dir" env:"RCE_CODE_DIR" flag:"code-dir" usage:"Base path to store the code files"`
+	ResourceConstraints bool   `yaml:"resource_constraints" env:"RCE_RESOURCE_CONSTRAINTS" flag:"resource-constraints" usage:"Enable resource constraints"`
+
+	// Staged files which haven't been modified for this long are deleted by the garbage collector.
+	FileRetention time.Duration `yaml:"file_retention" env:"RCE_FILE_RETENTION" flag:"file-retention" usage:"Time after which the stale code files are deleted"`
+
+	// Once the staged files use more than this many megabytes the garbage collector ignores
+	// the retention period and cleans up aggressively. 0 disables the high-water mark.
+	DiskHighWaterMarkMB int64 `yaml:"disk_high_water_mark_mb" env:"RCE_DISK_HIGH_WATER_MARK_MB" flag:"disk-high-water-mark" usage:"Disk usage of the code files in MB which triggers an aggressive cleanup, 0 disables it"`
+
+	// Uploaded archives and the files extracted from them may not be larger than this.
+	MaxArchiveSizeMB int64 `yaml:"max_archive_size_mb" env:"RCE_MAX_ARCHIVE_SIZE_MB" flag:"max-archive-size" usage:"Maximum size of an uploaded archive and of the files extracted from it in MB"`
+
+	// Total size of the artifacts returned for a submission, files which don't fit are left out.
+	MaxArtifactSizeMB int64 `yaml:"max_artifact_size_mb" env:"RCE_MAX_ARTIFACT_SIZE_MB" flag:"max-artifact-size" usage:"Maximum total size of the artifacts returned for a submission in MB"`
+
+	// JSON request bodies larger than this are rejected before they are decoded. The code is limited to the decoded
+	// size of the code or of all files, the input to the decoded size of the input or of an uploaded input.
+	MaxBodySizeMB  int64 `yaml:"max_body_size_mb" env:"RCE_MAX_BODY_SIZE_MB" flag:"max-body-size" usage:"Maximum size of a JSON request body in MB"`
+	MaxCodeSizeMB  int64 `yaml:"max_code_size_mb" env:"RCE_MAX_CODE_SIZE_MB" flag:"max-code-size" usage:"Maximum size of the code of a submission in MB"`
+	MaxInputSizeMB int64 `yaml:"max_input_size_mb" env:"RCE_MAX_INPUT_SIZE_MB" flag:"max-input-size" usage:"Maximum size of the input of a submission in MB"`
+
+	// Each of stdout and stderr is cut off after this many kilobytes, the program is killed once it writes more.
+	MaxOutputSizeKB int64 `yaml:"max_output_size_kb" env:"RCE_MAX_OUTPUT_SIZE_KB" flag:"max-output-size" usage:"Maximum size of stdout and of stderr of an execution in KB"`
+
+	// A benchmark runs the program up to this many times, and as many warmups before, 0 disables benchmarks.
+	MaxBenchmarkRuns int `yaml:"max_benchmark_runs" env:"RCE_MAX_BENCHMARK_RUNS" flag:"max-benchmark-runs" usage:"Maximum number of runs of a benchmark, benchmarks are disabled if 0"`
+
+	// The clock of deterministic runs is shifted by this offset in whole seconds, e.g. -8760h, by preloading the
+	// faketime library in the container. 0 leaves the clock alone, the images must contain the library otherwise.
+	DeterministicTimeOffset time.Duration `yaml:"deterministic_time_offset" env:"RCE_DETERMINISTIC_TIME_OFFSET" flag:"deterministic-time-offset" usage:"Offset of the clock of deterministic runs, the time isn't faked if 0"`
+	FaketimeLibrary         string        `yaml:"faketime_library" env:"RCE_FAKETIME_LIBRARY" flag:"faketime-library" usage:"Path of libfaketime in the images, preloaded to fake the clock of deterministic runs"`
+
+	// Inputs uploaded ahead of the submissions are stored in this directory, empty disables uploads.
+	// They are deleted once they haven't been used for the retention period.
+	InputDir       string        `yaml:"input_dir" env:"RCE_INPUT_DIR" flag:"input-dir" usage:"Directory of the uploaded inputs, uploads are disabled if empty"`
+	InputRetention time.Duration `yaml:"input_retention" env:"RCE_INPUT_RETENTION" flag:"input-retention" usage:"Time after which unused uploaded inputs are deleted"`
+
+	// Results of identical submissions are served from this cache, "memory" or "disk". Empty disables the cache.
+	ResultCache       string        `yaml:"result_cache" env:"RCE_RESULT_CACHE" flag:"result-cache" usage:"Result cache, memory or disk, disabled if empty"`
+	ResultCacheTTL    time.Duration `yaml:"result_cache_ttl" env:"RCE_RESULT_CACHE_TTL" flag:"result-cache-ttl" usage:"Time after which cached results expire"`
+	ResultCacheSizeMB int64         `yaml:"result_cache_size_mb" env:"RCE_RESULT_CACHE_SIZE_MB" flag:"result-cache-size" usage:"Maximum size of the result cache in MB"`
+	ResultCacheDir    string        `yaml:"result_cache_dir" env:"RCE_RESULT_CACHE_DIR" flag:"result-cache-dir" usage:"Directory of the disk result cache"`
+
+	// Every submission is recorded in this database, empty disables the history.
+	// Callers read the submissions of their tenant, the admin token reads every submission.
+	SubmissionDB string `yaml:"submission_db" env:"RCE_SUBMISSION_DB" flag:"submission-db" usage:"Path of the submission history database, disabled if empty"`
+	// Recorded submissions are deleted after this long, 0 keeps them forever.
+	SubmissionRetention time.Duration `yaml:"submission_retention" env:"RCE_SUBMISSION_RETENTION" flag:"submission-retention" usage:"Time after which recorded submissions are deleted, 0 keeps them forever"`
+
+	// Executions beyond the maximum wait for a free slot, submissions beyond the queue are rejected. 0 doesn't limit them.
+	MaxConcurrentExecutions int `yaml:"max_concurrent_executions" env:"RCE_MAX_CONCURRENT_EXECUTIONS" flag:"max-concurrent-executions" usage:"Maximum number of executions running at once, 0 doesn't limit them"`
+	MaxQueuedExecutions     int `yaml:"max_queued_executions" env:"RCE_MAX_QUEUED_EXECUTIONS" flag:"max-queued-executions" usage:"Maximum number of executions waiting for a free slot"`
+
+	// Submissions a tenant may make per window, callers without a tenant are limited by their address. 0 disables the quota.
+	TenantQuota       int           `yaml:"tenant_quota" env:"RCE_TENANT_QUOTA" flag:"tenant-quota" usage:"Submissions a tenant may make per quota window, 0 disables the quota"`
+	TenantQuotaWindow time.Duration `yaml:"tenant_quota_window" env:"RCE_TENANT_QUOTA_WINDOW" flag:"tenant-quota-window" usage:"Window of the tenant quota"`
+
+	// Key of the HMAC-SHA256 signature of the webhooks, callbacks are rejected if it is empty.
+	// It is never logged, prefer the environment variable over the config file.
+	WebhookSecret         string        `yaml:"webhook_secret" env:"RCE_WEBHOOK_SECRET" flag:"webhook-secret" usage:"Secret the webhooks are signed with, callbacks are disabled if empty" json:"-"`
+	WebhookMaxAttempts    int           `yaml:"webhook_max_attempts" env:"RCE_WEBHOOK_MAX_ATTEMPTS" flag:"webhook-max-attempts" usage:"Delivery attempts of a webhook before it is recorded as a dead letter"`
+	WebhookInitialBackoff time.Duration `yaml:"webhook_initial_backoff" env:"RCE_WEBHOOK_INITIAL_BACKOFF" flag:"webhook-initial-backoff" usage:"Time before the first retry of a webhook, doubled after every failed attempt"`
+	// Callbacks to loopback, private and link-local addresses are rejected unless their host is in this list,
+	// e.g. an LMS in the same network. The env and the flag take a comma separated list.
+	WebhookAllowedHosts []string `yaml:"webhook_allowed_hosts" env:"RCE_WEBHOOK_ALLOWED_HOSTS" flag:"webhook-allowed-hosts" usage:"Comma separated hosts whose callbacks may use loopback, private or link-local addresses"`
+
+	// Bearer token of the admin, who reads the submissions of every tenant and the dead letters. Empty disables
+	// the admin. It is never logged, prefer the environment variable over the config file.
+	AdminToken string `yaml:"admin_token" env:"RCE_ADMIN_TOKEN" flag:"admin-token" usage:"Bearer token reading the submissions of every tenant and the dead letters, disabled if empty" json:"-"`
+	// Bearer tokens of the tenants as "tenant=token", a caller sending the token of a tenant submits as the tenant
+	// and reads its submissions. The env and the flag take a comma separated list, they are never logged.
+	TenantTokens []string `yaml:"tenant_tokens" env:"RCE_TENANT_TOKENS" flag:"tenant-tokens" usage:"Comma separated bearer tokens of the tenants as tenant=token" json:"-"`
+	// Take the tenant of callers without a token from the X-Tenant-ID header. Only enable it behind a gateway which
+	// authenticates the callers and sets the header, anyone could claim any tenant otherwise.
+	TrustTenantHeader bool `yaml:"trust_tenant_header" env:"RCE_TRUST_TENANT_HEADER" flag:"trust-tenant-header" usage:"Trust the X-Tenant-ID header, only behind a gateway which sets it"`
+}
+
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		Address:           ":9000",
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		MaxExecutionTime:  60 * time.Second,
+		GCInterval:        5 * time.Minute,
+		TargetMountPath:   "/container/code",
+		CodeDir:           "/tmp/rce-code",
+		FileRetention:     5 * time.Minute,
+		MaxArchiveSizeMB:  10,
+		MaxArtifactSizeMB: 10,
+		MaxBodySizeMB:     16,
+		MaxCodeSizeMB:     1,
+		MaxInputSizeMB:    64,
+		MaxOutputSizeKB:   1024,
+		MaxBenchmarkRuns:  20,
+		FaketimeLibrary:   "/usr/lib/faketime/libfaketime.so.1",
+		InputDir:          "/tmp/rce-inputs",
+		InputRetention:    24 * time.Hour,
+		ResultCacheTTL:    time.Hour,
+		ResultCacheSizeMB: 64,
+		ResultCacheDir:    "/tmp/rce-result-cache",
+
+		SubmissionRetention: 30 * 24 * time.Hour,
+
+		MaxQueuedExecutions: 100,
+		TenantQuotaWindow:   time.Minute,
+
+		WebhookMaxAttempts:    5,
+		WebhookInitialBackoff: time.Second,
+	}
+}
+
+func (s *ServerConfig) Validate() error {
+	var errs []error
+	if s.Address == "" {
+		errs = append(errs, errors.New("server.address: address is required, e.g. \":9000\""))
+	}
+	if s.GRPCAddress != "" && s.GRPCAddress == s.Address {
+		errs = append(errs, fmt.Errorf("server.grpc_address: %q is the address of the REST API, use a different one", s.GRPCAddress))
+	}
+	if s.ReadTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("server.read_timeout: %s must be positive", s.ReadTimeout))
+	}
+	if s.MaxExecutionTime < MinTimeLimit || s.MaxExecutionTime > MaxTimeLimit {
+		errs = append(errs, fmt.Errorf("server.max_execution_time: %s is out of range, use a value between %s and %s",
+			s.MaxExecutionTime, MinTimeLimit, MaxTimeLimit))
+	}
+	if s.WriteTimeout < s.MaxExecutionTime {
+		errs = append(errs, fmt.Errorf("server.write_timeout: %s is shorter than the max_execution_time %s, responses would be cut off",
+			s.WriteTimeout, s.MaxExecutionTime))
+	}
+	if s.GCInterval <= 0 {
+		errs = append(errs, fmt.Errorf("server.gc_interval: %s must be positive", s.GCInterval))
+	}
+	if !filepath.IsAbs(s.TargetMountPath) {
+		errs = append(errs, fmt.Errorf("server.target_mount_path: %q must be an absolute path", s.TargetMountPath))
+	}
+	if s.CodeDir == "" {
+		errs = append(errs, errors.New("server.code_dir: code_dir is required"))
+	} else if dir := filepath.Clean(s.CodeDir); dir == "/" || dir == filepath.Clean(os.TempDir()) {
+		// Every directory in the code directory is swept by the garbage collector.
+		errs = append(errs, fmt.Errorf("server.code_dir: %s must be a directory of its own, e.g. /tmp/rce-code", s.CodeDir))
+	} else {
+		// The garbage collector sweeps every directory in the code directory.
+		for _, dir := range []struct{ field, path string }{
+			{"input_dir", s.InputDir},
+			{"result_cache_dir", s.ResultCacheDir},
+			{"submission_db", s.SubmissionDB},
+		} {
+			if dir.path != "" && isWithin(dir.path, s.CodeDir) {
+				errs = append(errs, fmt.Errorf("server.%s: %s is inside the code_dir %s, the garbage collector would delete it",
+					dir.field, dir.path, s.CodeDir))
+			}
+		}
+	}
+	if s.FileRetention <= 0 {
+		errs = append(errs, fmt.Errorf("server.file_retention: %s must be positive", s.FileRetention))
+	}
+	if s.DiskHighWaterMarkMB < 0 {
+		errs = append(errs, fmt.Errorf("server.disk_high_water_mark_mb: %d must not be negative", s.DiskHighWaterMarkMB))
+	}
+	if s.MaxArchiveSizeMB <= 0 {
+		errs = append(errs, fmt.Errorf("server.max_archive_size_mb: %d must be positive", s.MaxArchiveSizeMB))
+	}
+	if s.MaxArtifactSizeMB <= 0 {
+		errs = append(errs, fmt.Errorf("server.max_artifact_size_mb: %d must be positive", s.MaxArtifactSizeMB))
+	}
+	if s.MaxBodySizeMB <= 0 {
+		errs = append(errs, fmt.Errorf("server.max_body_size_mb: %d must be positive", s.MaxBodySizeMB))
+	}
+	if s.MaxCodeSizeMB <= 0 {
+		errs = append(errs, fmt.Errorf("server.max_code_size_mb: %d must be positive", s.MaxCodeSizeMB))
+	}
+	if s.MaxInputSizeMB <= 0 {
+		errs = append(errs, fmt.Errorf("server.max_input_size_mb: %d must be positive", s.MaxInputSizeMB))
+	}
+	if s.MaxOutputSizeKB <= 0 {
+		errs = append(errs, fmt.Errorf("server.max_output_size_kb: %d must be positive", s.MaxOutputSizeKB))
+	}
+	if s.MaxBenchmarkRuns < 0 || s.MaxBenchmarkRuns > MaxBenchmarkRunsLimit {
+		errs = append(errs, fmt.Errorf("server.max_benchmark_runs: %d is out of range, use a value between 0 and %d",
+			s.MaxBenchmarkRuns, MaxBenchmarkRunsLimit))
+	}
+	if s.DeterministicTimeOffset%time.Second != 0 {
+		errs = append(errs, fmt.Errorf("server.deterministic_time_offset: %s must be a whole number of seconds", s.DeterministicTimeOffset))
+	}
+	if s.DeterministicTimeOffset != 0 && !path.IsAbs(s.FaketimeLibrary) {
+		errs = append(errs, fmt.Errorf("server.faketime_library: %q must be an absolute path to fake the time", s.FaketimeLibrary))
+	}
+	if s.InputDir != "" && s.InputRetention <= 0 {
+		errs = append(errs, fmt.Errorf("server.input_retention: %s must be positive", s.InputRetention))
+	}
+	if s.SubmissionRetention < 0 {
+		errs = append(errs, fmt.Errorf("server.submission_retention: %s must not be negative", s.SubmissionRetention))
+	}
+	if s.MaxConcurrentExecutions < 0 {
+		errs = append(errs, fmt.Errorf("server.max_concurrent_executions: %d must not be negative", s.MaxConcurrentExecutions))
+	}
+	if s.MaxQueuedExecutions < 0 {
+		errs = append(errs, fmt.Errorf("server.max_queued_executions: %d must not be negative", s.MaxQueuedExecutions))
+	}
+	if s.TenantQuota < 0 {
+		errs = append(errs, fmt.Errorf("server.tenant_quota: %d must not be negative", s.TenantQuota))
+	}
+	if s.TenantQuota > 0 && s.TenantQuotaWindow <= 0 {
+		errs = append(errs, fmt.Errorf("server.tenant_quota_window: %s must be positive", s.TenantQuotaWindow))
+	}
+	if s.WebhookMaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("server.webhook_max_attempts: %d must be at least 1", s.WebhookMaxAttempts))
+	}
+	if s.WebhookInitialBackoff <= 0 {
+		errs = append(errs, fmt.Errorf("server.webhook_initial_backoff: %s must be positive", s.WebhookInitialBackoff))
+	}
+	if _, err := s.ParseTenantTokens(); err != nil {
+		errs = append(errs, fmt.Errorf("server.tenant_tokens: %w", err))
+	}
+	switch s.ResultCache {
+	case "":
+	case "memory", "disk":
+		if s.ResultCacheTTL <= 0 {
+			errs = append(errs, fmt.Errorf("server.result_cache_ttl: %s must be positive", s.ResultCacheTTL))
+		}
+		if s.ResultCacheSizeMB <= 0 {
+			errs = append(errs, fmt.Errorf("server.result_cache_size_mb: %d must be positive", s.ResultCacheSizeMB))
+		}
+		if s.ResultCache == "disk" && s.ResultCacheDir == "" {
+			errs = append(errs, errors.New("server.result_cache_dir: result_cache_dir is required for the disk cache"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("server.result_cache: %q is not a result cache, use \"memory\", \"disk\" or leave it empty", s.ResultCache))
+	}
+	return errors.Join(errs...)
+}
+
+// isWithin reports whether path is dir or inside it.
+func isWithin(path, dir string) bool {
+	path, pathErr := filepath.Abs(path)
+	dir, dirErr := filepath.Abs(dir)
+	if pathErr != nil || dirErr != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ParseTenantTokens returns the tenants of the tenant tokens by token.
+func (s *ServerConfig) ParseTenantTokens() (map[string]string, error) {
+	tenants := map[string]string{}
+	for _, entry := range s.TenantTokens {
+		tenant, token, ok := strings.Cut(entry, "=")
+		if !ok || tenant == "" || token == "" {
+			return nil, errors.New("use tenant=token for every tenant")
+		}
+		if _, ok := tenants[token]; ok || token == s.AdminToken {
+			return nil, fmt.Errorf("the token of %s is used more than once", tenant)
+		}
+		tenants[token] = tenant
+	}
+	return tenants, nil
+}
+
+// EffectiveLimits returns the limits an execution runs with: the defaults overridden by limits, with the time
+// limit capped at the max execution time. Only the time limits are enforced without resource constraints.
+func (s *ServerConfig) EffectiveLimits(limits Limits) Limits {
+	effective := Limits{}
+	if s.ResourceConstraints {
+		effective = DefaultLimits.Merge(limits)
+	}
+
+	effective.TimeLimit = s.MaxExecutionTime
+	effective.CPUTimeLimit = limits.CPUTimeLimit
+	if limits.TimeLimit > 0 && limits.TimeLimit < s.MaxExecutionTime {
+		effective.TimeLimit = limits.TimeLimit
+	}
+	return effective
+}
+
+func (s *ServerConfig) GetHostLanguageCodePath(lang Language) string {
+	return filepath.Join(s.CodeDir, string(lang))
+}
+
+// ApplyEnv overrides the settings whose environment variable is set.
+func (s *ServerConfig) ApplyEnv(lookupEnv func(string) (string, bool)) error {
+	var errs []error
+	forEachSetting(s, func(field reflect.StructField, value reflect.Value) {
+		env, ok := lookupEnv(field.Tag.Get("env"))
+		if !ok {
+			return
+		}
+		if err := setSetting(value, env); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field.Tag.Get("env"), err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// RegisterFlags registers a flag for every setting, the current values are used as the defaults.
+func (s *ServerConfig) RegisterFlags(flags *flag.FlagSet) {
+	forEachSetting(s, func(field reflect.StructField, value reflect.Value) {
+		flags.Var(settingValue{value}, field.Tag.Get("flag"), field.Tag.Get("usage"))
+	})
+}
+
+// ApplyFlags overrides the settings with the values of the flags which were set explicitly.
+// from has to be the config the flags were registered on.
+func (s *ServerConfig) ApplyFlags(flags *flag.FlagSet, from *ServerConfig) {
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	source := reflect.ValueOf(from).Elem()
+	forEachSetting(s, func(field reflect.StructField, value reflect.Value) {
+		if set[field.Tag.Get("flag")] {
+			value.Set(source.FieldByIndex(field.Index))
+		}
+	})
+}
+
+func forEachSetting(s *ServerConfig, fn func(field reflect.StructField, value reflect.Value)) {
+	config := reflect.ValueOf(s).Elem()
+	for i := range config.NumField() {
+		fn(config.Type().Field(i), config.Field(i))
+	}
+}
+
+func setSetting(value reflect.Value, s string) error {
+	switch value.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		value.SetInt(i)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported setting type %s", value.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", value.Type())
+	}
+	return nil
+}
+
+// settingValue adapts a setting to the flag.Value interface.
+type settingValue struct {
+	value reflect.Value
+}
+
+func (v settingValue) String() string {
+	if !v.value.IsValid() {
+		return ""
+	}
+	if items, ok := v.value.Interface().([]string); ok {
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.value.Interface())
+}
+
+func (v settingValue) Set(s string) error {
+	return setSetting(v.value, s)
+}
+
+// IsBoolFlag allows boolean settings to be passed without a value, e.g. --resource-constraints.
+func (v settingValue) IsBoolFlag() bool {
+	return v.value.IsValid() && v.value.Kind() == reflect.Bool
+}
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFileServerSection(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	content := "server:\n  address: \":8080\"\n  max_execution_time: 30s\n" + validConfig
+	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write the config file: %v", err)
+	}
+
+	file, err := LoadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to load the config file: %v", err)
+	}
+
+	if file.Server.Address != ":8080" || file.Server.MaxExecutionTime != 30*time.Second {
+		t.Errorf("expected the settings of the server section, got %+v", file.Server)
+	}
+	if file.Server.ReadTimeout != DefaultServerConfig().ReadTimeout {
+		t.Errorf("expected the default read timeout, got %s", file.Server.ReadTimeout)
+	}
+	if file.Languages.IsLanguageSupported(serverSection) || !file.Languages.IsLanguageSupported(Golang) {
+		t.Errorf("expected only golang to be a language, got %v", file.Languages.GetSupportedLanguages())
+	}
+}
+
+func TestLoadFileServerSectionRejectsUnknownFields(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	content := "server:\n  adress: \":8080\"\n" + validConfig
+	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write the config file: %v", err)
+	}
+
+	if _, err := LoadFile(configPath); err == nil || !strings.Contains(err.Error(), "adress") {
+		t.Errorf("expected an error about the unknown field, got: %v", err)
+	}
+}
+
+func TestServerConfigLayering(t *testing.T) {
+	// The config file sets the address and the read timeout.
+	serverConfig := DefaultServerConfig()
+	serverConfig.Address = ":8080"
+	serverConfig.ReadTimeout = 20 * time.Second
+
+	env := map[string]string{
+		"RCE_READ_TIMEOUT":          "30s",
+		"RCE_WRITE_TIMEOUT":         "2m",
+		"RCE_RESOURCE_CONSTRAINTS":  "true",
+		"RCE_WEBHOOK_ALLOWED_HOSTS": "lms.internal, grader.internal",
+	}
+	err := serverConfig.ApplyEnv(func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+	if err != nil {
+		t.Fatalf("failed to apply the environment variables: %v", err)
+	}
+
+	flagConfig := DefaultServerConfig()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagConfig.RegisterFlags(flags)
+	if err := flags.Parse([]string{"--write-timeout", "3m", "--code-dir", "/srv/code"}); err != nil {
+		t.Fatalf("failed to parse the flags: %v", err)
+	}
+	serverConfig.ApplyFlags(flags, &flagConfig)
+
+	expected := DefaultServerConfig()
+	expected.Address = ":8080"              // file
+	expected.ReadTimeout = 30 * time.Second // environment overrides the file
+	expected.ResourceConstraints = true     // environment
+	expected.WriteTimeout = 3 * time.Minute // flag overrides the environment
+	expected.CodeDir = "/srv/code"          // flag
+	expected.WebhookAllowedHosts = []string{"lms.internal", "grader.internal"}
+	if !reflect.DeepEqual(serverConfig, expected) {
+		t.Errorf("expected %+v, got %+v", expected, serverConfig)
+	}
+}
+
+func TestServerConfigApplyEnvInvalidValue(t *testing.T) {
+	serverConfig := DefaultServerConfig()
+	err := serverConfig.ApplyEnv(func(key string) (string, bool) {
+		return "soon", key == "RCE_GC_INTERVAL"
+	})
+	if err == nil || !strings.Contains(err.Error(), "RCE_GC_INTERVAL") {
+		t.Errorf("expected an error about RCE_GC_INTERVAL, got: %v", err)
+	}
+}
+
+func TestServerConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*ServerConfig)
+		wantErr string
+	}{
+		{
+			name:   "defaults",
+			modify: func(*ServerConfig) {},
+		},
+		{
+			name:    "missing address",
+			modify:  func(s *ServerConfig) { s.Address = "" },
+			wantErr: "server.address",
+		},
+		{
+			name:    "gRPC on the REST address",
+			modify:  func(s *ServerConfig) { s.GRPCAddress = s.Address },
+			wantErr: "server.grpc_address",
+		},
+		{
+			name:    "write timeout shorter than the execution",
+			modify:  func(s *ServerConfig) { s.WriteTimeout = 10 * time.Second },
+			wantErr: "server.write_timeout",
+		},
+		{
+			name:    "relative mount path",
+			modify:  func(s *ServerConfig) { s.TargetMountPath = "code" },
+			wantErr: "server.target_mount_path",
+		},
+		{
+			name:    "max execution time out of range",
+			modify:  func(s *ServerConfig) { s.MaxExecutionTime = time.Hour; s.WriteTimeout = 2 * time.Hour },
+			wantErr: "server.max_execution_time",
+		},
+		{
+			name:    "tenant quota without a window",
+			modify:  func(s *ServerConfig) { s.TenantQuota = 10; s.TenantQuotaWindow = 0 },
+			wantErr: "server.tenant_quota_window",
+		},
+		{
+			name:    "negative benchmark runs",
+			modify:  func(s *ServerConfig) { s.MaxBenchmarkRuns = -1 },
+			wantErr: "server.max_benchmark_runs",
+		},
+		{
+			name:    "shared code directory",
+			modify:  func(s *ServerConfig) { s.CodeDir = "/tmp/" },
+			wantErr: "server.code_dir",
+		},
+		{
+			name:    "input directory inside the code directory",
+			modify:  func(s *ServerConfig) { s.InputDir = "/tmp/rce-code/inputs" },
+			wantErr: "server.input_dir",
+		},
+		{
+			name:    "result cache directory inside the code directory",
+			modify:  func(s *ServerConfig) { s.ResultCacheDir = "/tmp/rce-code/" },
+			wantErr: "server.result_cache_dir",
+		},
+		{
+			name:    "submission database inside the code directory",
+			modify:  func(s *ServerConfig) { s.SubmissionDB = "/tmp/rce-code/history/submissions.db" },
+			wantErr: "server.submission_db",
+		},
+		{
+			name:   "directory next to the code directory",
+			modify: func(s *ServerConfig) { s.InputDir = "/tmp/rce-code-inputs" },
+		},
+		{
+			name:    "tenant token without a tenant",
+			modify:  func(s *ServerConfig) { s.TenantTokens = []string{"s3cret"} },
+			wantErr: "server.tenant_tokens",
+		},
+		{
+			name:    "tenant token of two tenants",
+			modify:  func(s *ServerConfig) { s.TenantTokens = []string{"cs101=s3cret", "cs102=s3cret"} },
+			wantErr: "server.tenant_tokens",
+		},
+		{
+			name: "tenant token of the admin",
+			modify: func(s *ServerConfig) {
+				s.AdminToken = "s3cret"
+				s.TenantTokens = []string{"cs101=s3cret"}
+			},
+			wantErr: "server.tenant_tokens",
+		},
+		{
+			name:    "fractional time offset",
+			modify:  func(s *ServerConfig) { s.DeterministicTimeOffset = 1500 * time.Millisecond },
+			wantErr: "server.deterministic_time_offset",
+		},
+		{
+			name: "relative faketime library",
+			modify: func(s *ServerConfig) {
+				s.DeterministicTimeOffset = -24 * time.Hour
+				s.FaketimeLibrary = "libfaketime.so.1"
+			},
+			wantErr: "server.faketime_library",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConfig := DefaultServerConfig()
+			tt.modify(&serverConfig)
+
+			err := serverConfig.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEffectiveLimits(t *testing.T) {
+	tests := []struct {
+		name                string
+		resourceConstraints bool
+		limits              Limits
+		expected            Limits
+	}{
+		{
+			name:     "only the time limit without resource constraints",
+			limits:   Limits{MemoryMB: 100},
+			expected: Limits{TimeLimit: time.Minute},
+		},
+		{
+			name:     "lower time limit",
+			limits:   Limits{TimeLimit: 5 * time.Second},
+			expected: Limits{TimeLimit: 5 * time.Second},
+		},
+		{
+			name:     "cpu time limit without resource constraints",
+			limits:   Limits{CPUTimeLimit: 2 * time.Second, MemoryMB: 100},
+			expected: Limits{TimeLimit: time.Minute, CPUTimeLimit: 2 * time.Second},
+		},
+		{
+			name:     "time limit capped at the max execution time",
+			limits:   Limits{TimeLimit: 2 * time.Minute},
+			expected: Limits{TimeLimit: time.Minute},
+		},
+		{
+			name:                "defaults with resource constraints",
+			resourceConstraints: true,
+			expected:            Limits{TimeLimit: time.Minute, MemoryMB: 500, CPUs: 1, MaxProcesses: 128, MaxFileSizeMB: 20},
+		},
+		{
+			name:                "language limits with resource constraints",
+			resourceConstraints: true,
+			limits:              Limits{MemoryMB: 100, CPUs: 0.5},
+			expected:            Limits{TimeLimit: time.Minute, MemoryMB: 100, CPUs: 0.5, MaxProcesses: 128, MaxFileSizeMB: 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConfig := DefaultServerConfig()
+			serverConfig.MaxExecutionTime = time.Minute
+			serverConfig.ResourceConstraints = tt.resourceConstraints
+
+			if result := serverConfig.EffectiveLimits(tt.limits); result != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, result)
+			}
+		})
+	}
+}
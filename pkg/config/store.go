@@ -0,0 +1,24 @@
+package config
+
+import "sync/atomic"
+
+// Store holds the image config currently in use. Readers should Load it once per request
+// so that a request keeps using the same config even if it gets swapped in the meantime.
+type Store struct {
+	current atomic.Pointer[ImageConfig]
+}
+
+func NewStore(config *ImageConfig) *Store {
+	s := &Store{}
+	s.current.Store(config)
+	return s
+}
+
+func (s *Store) Load() *ImageConfig {
+	return s.current.Load()
+}
+
+// Swap replaces the config in use and returns the previous one.
+func (s *Store) Swap(config *ImageConfig) *ImageConfig {
+	return s.current.Swap(config)
+}
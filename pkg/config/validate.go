@@ -0,0 +1,270 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Ranges accepted for the limits of a language.
+const (
+	MinTimeLimit     = 100 * time.Millisecond
+	MaxTimeLimit     = 10 * time.Minute
+	MinMemoryMB      = 6 // Docker refuses to start containers with less memory.
+	MaxMemoryMB      = 64 * 1024
+	MaxCPUs          = 64
+	MaxProcesses     = 4096
+	MaxFileSizeLimit = 1024
+)
+
+// Upper bound of max_benchmark_runs, all the runs of a benchmark share the time limit of the execution.
+const MaxBenchmarkRunsLimit = 1000
+
+var (
+	// Languages are used as directory names, keep them simple.
+	languageNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.+-]*$`)
+	versionNamePattern  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.+-]*$`)
+	placeholderPattern  = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+	envNamePattern      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	supportedPlaceholders = []string{
+		PlaceholderLanguage, PlaceholderFile, PlaceholderInput, PlaceholderVersion, PlaceholderFlags,
+		PlaceholderEntrypoint, PlaceholderDir,
+	}
+)
+
+// Validate checks the config for mistakes that would otherwise only show up when code is submitted.
+// All the problems are reported at once, each prefixed with the field it was found in.
+func (c ImageConfig) Validate() error {
+	if len(c) == 0 {
+		return errors.New("no languages configured, add at least one language to the config file")
+	}
+
+	var errs []error
+	extensions := map[string]Language{}
+	for _, lang := range slices.Sorted(maps.Keys(c)) {
+		langConfig := c[lang]
+		errs = append(errs, validateLanguage(lang, langConfig)...)
+
+		if other, ok := extensions[langConfig.Extension]; ok && langConfig.Extension != "" {
+			errs = append(errs, fmt.Errorf("%s.extension: %q is already used by %s, every language needs a unique extension",
+				lang, langConfig.Extension, other))
+		}
+		extensions[langConfig.Extension] = lang
+	}
+	return errors.Join(errs...)
+}
+
+func validateLanguage(lang Language, langConfig LanguageConfig) []error {
+	var errs []error
+	if !languageNamePattern.MatchString(string(lang)) {
+		errs = append(errs, fmt.Errorf("%s: language names may only contain lowercase letters, digits and '_.+-'", lang))
+	}
+
+	switch {
+	case langConfig.Extension == "":
+		errs = append(errs, fmt.Errorf("%s.extension: extension is required, e.g. \".%s\"", lang, lang))
+	case !strings.HasPrefix(langConfig.Extension, "."):
+		errs = append(errs, fmt.Errorf("%s.extension: %q must start with a dot, use \".%s\"",
+			lang, langConfig.Extension, langConfig.Extension))
+	case strings.ContainsAny(langConfig.Extension, `/\ `):
+		errs = append(errs, fmt.Errorf("%s.extension: %q must not contain slashes or spaces", lang, langConfig.Extension))
+	}
+
+	errs = append(errs, validateCaches(lang, langConfig.Caches)...)
+	for _, name := range slices.Sorted(maps.Keys(langConfig.Env)) {
+		if !envNamePattern.MatchString(name) {
+			errs = append(errs, fmt.Errorf("%s.env.%s: environment variable names may only contain letters, digits and underscores", lang, name))
+		}
+	}
+
+	if len(langConfig.Versions) == 0 {
+		return append(errs, validateExecution(string(lang), langConfig)...)
+	}
+
+	if langConfig.DefaultVersion == "" {
+		errs = append(errs, fmt.Errorf("%s.default_version: default_version is required when versions are configured, use one of %s",
+			lang, strings.Join(langConfig.GetVersions(), ", ")))
+	} else if _, ok := langConfig.Versions[langConfig.DefaultVersion]; !ok {
+		errs = append(errs, fmt.Errorf("%s.default_version: %q is not one of the versions, use one of %s",
+			lang, langConfig.DefaultVersion, strings.Join(langConfig.GetVersions(), ", ")))
+	}
+
+	for _, version := range langConfig.GetVersions() {
+		field := fmt.Sprintf("%s.versions.%s", lang, version)
+		if !versionNamePattern.MatchString(version) {
+			errs = append(errs, fmt.Errorf("%s: version names may only contain letters, digits and '_.+-'", field))
+		}
+		errs = append(errs, validateExecution(field, langConfig.ResolveVersion(version))...)
+	}
+	return errs
+}
+
+// validateExecution validates the settings a language or a version needs to execute code, field is used as the prefix of the errors.
+func validateExecution(field string, langConfig LanguageConfig) []error {
+	var errs []error
+	if strings.TrimSpace(langConfig.Image) == "" {
+		errs = append(errs, fmt.Errorf("%s.image: image is required", field))
+	}
+
+	errs = append(errs, validateCommand(field+".command", langConfig.Command)...)
+	if langConfig.ProjectCommand != "" {
+		errs = append(errs, validateCommand(field+".project_command", langConfig.ProjectCommand)...)
+	}
+	errs = append(errs, validateLimits(field, langConfig.Limits)...)
+	return errs
+}
+
+func validateCommand(field string, command string) []error {
+	if strings.TrimSpace(command) == "" {
+		return []error{fmt.Errorf("%s: command is required, e.g. \"exec /usr/bin/run-code.sh %s %s %s\"",
+			field, PlaceholderLanguage, PlaceholderFile, PlaceholderInput)}
+	}
+
+	var errs []error
+	for _, match := range placeholderPattern.FindAllStringSubmatch(command, -1) {
+		placeholder := "{{" + match[1] + "}}"
+		if slices.Contains(supportedPlaceholders, placeholder) {
+			continue
+		}
+
+		err := fmt.Errorf("%s: unknown placeholder %s", field, match[0])
+		if suggestion := closestPlaceholder(match[1]); suggestion != "" {
+			err = fmt.Errorf("%w, did you mean %s?", err, suggestion)
+		}
+		errs = append(errs, fmt.Errorf("%w (supported: %s)", err, strings.Join(supportedPlaceholders, ", ")))
+	}
+
+	if !strings.Contains(command, PlaceholderFile) && !strings.Contains(command, PlaceholderEntrypoint) {
+		errs = append(errs, fmt.Errorf("%s: %s or %s is missing, the command has no way to find the code file",
+			field, PlaceholderFile, PlaceholderEntrypoint))
+	}
+	return errs
+}
+
+func validateCaches(lang Language, caches []Cache) []error {
+	var errs []error
+	targets := map[string]bool{}
+	for i, cache := range caches {
+		field := fmt.Sprintf("%s.caches[%d]", lang, i)
+		if !path.IsAbs(cache.Source) {
+			errs = append(errs, fmt.Errorf("%s.source: %q must be an absolute path", field, cache.Source))
+		}
+		if !path.IsAbs(cache.Target) || path.Clean(cache.Target) == "/" {
+			errs = append(errs, fmt.Errorf("%s.target: %q must be an absolute path other than /", field, cache.Target))
+		} else if targets[path.Clean(cache.Target)] {
+			errs = append(errs, fmt.Errorf("%s.target: %q is already used by another cache", field, cache.Target))
+		}
+		targets[path.Clean(cache.Target)] = true
+
+		for _, module := range cache.Modules {
+			if !IsURLModule(module) && !strings.Contains(module, "@") {
+				errs = append(errs, fmt.Errorf("%s.modules: %q is neither a URL nor a Go module with a version, e.g. \"github.com/google/uuid@v1.6.0\"",
+					field, module))
+			}
+		}
+	}
+	return errs
+}
+
+func validateLimits(field string, limits Limits) []error {
+	var errs []error
+	if limits.TimeLimit != 0 && (limits.TimeLimit < MinTimeLimit || limits.TimeLimit > MaxTimeLimit) {
+		errs = append(errs, fmt.Errorf("%s.limits.time_limit: %s is out of range, use a value between %s and %s",
+			field, limits.TimeLimit, MinTimeLimit, MaxTimeLimit))
+	}
+	if limits.CPUTimeLimit != 0 && (limits.CPUTimeLimit < MinTimeLimit || limits.CPUTimeLimit > MaxTimeLimit) {
+		errs = append(errs, fmt.Errorf("%s.limits.cpu_time_limit: %s is out of range, use a value between %s and %s",
+			field, limits.CPUTimeLimit, MinTimeLimit, MaxTimeLimit))
+	}
+	if limits.MemoryMB != 0 && (limits.MemoryMB < MinMemoryMB || limits.MemoryMB > MaxMemoryMB) {
+		errs = append(errs, fmt.Errorf("%s.limits.memory_mb: %d is out of range, use a value between %d and %d",
+			field, limits.MemoryMB, MinMemoryMB, MaxMemoryMB))
+	}
+	if limits.CPUs < 0 || limits.CPUs > MaxCPUs {
+		errs = append(errs, fmt.Errorf("%s.limits.cpus: %g is out of range, use a value between 0 and %d",
+			field, limits.CPUs, MaxCPUs))
+	}
+	if limits.MaxProcesses < 0 || limits.MaxProcesses > MaxProcesses {
+		errs = append(errs, fmt.Errorf("%s.limits.max_processes: %d is out of range, use a value between 1 and %d",
+			field, limits.MaxProcesses, MaxProcesses))
+	}
+	if limits.MaxFileSizeMB < 0 || limits.MaxFileSizeMB > MaxFileSizeLimit {
+		errs = append(errs, fmt.Errorf("%s.limits.max_file_size_mb: %d is out of range, use a value between 1 and %d",
+			field, limits.MaxFileSizeMB, MaxFileSizeLimit))
+	}
+	return errs
+}
+
+// ValidateImages checks that the image of every language is present, the images are never pulled
+// at execution time so a missing image fails every submission of that language.
+func (c ImageConfig) ValidateImages(ctx context.Context, imageExists func(ctx context.Context, image string) (bool, error)) error {
+	var errs []error
+	checked := map[string]bool{}
+	check := func(field, image string) {
+		if image == "" || checked[image] {
+			return
+		}
+		checked[image] = true
+
+		ok, err := imageExists(ctx, image)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s.image: failed to check %q: %w", field, image, err))
+		} else if !ok {
+			errs = append(errs, fmt.Errorf("%s.image: %q is not present in the docker daemon, build it with scripts/build_docker.sh or pull it",
+				field, image))
+		}
+	}
+
+	for _, lang := range slices.Sorted(maps.Keys(c)) {
+		langConfig := c[lang]
+		if len(langConfig.Versions) == 0 {
+			check(string(lang), langConfig.Image)
+			continue
+		}
+
+		for _, version := range langConfig.GetVersions() {
+			check(fmt.Sprintf("%s.versions.%s", lang, version), langConfig.ResolveVersion(version).Image)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closestPlaceholder suggests the supported placeholder nearest to name, if any is close enough to be a typo.
+func closestPlaceholder(name string) string {
+	best, bestDistance := "", 3
+	for _, placeholder := range supportedPlaceholders {
+		distance := editDistance(strings.ToUpper(name), strings.Trim(placeholder, "{}"))
+		if distance < bestDistance {
+			best, bestDistance = placeholder, distance
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
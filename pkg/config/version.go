@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+	ErrUnsupportedVersion  = errors.New("unsupported version")
+)
+
+// Resolve returns the config of the requested version of the language, or of the default version if
+// no version is requested. The returned config has the settings of the version merged in and no versions.
+func (c *ImageConfig) Resolve(lang Language, version string) (LanguageConfig, string, error) {
+	langConfig, ok := (*c)[lang]
+	if !ok {
+		return LanguageConfig{}, "", fmt.Errorf("%w %q", ErrUnsupportedLanguage, lang)
+	}
+
+	if len(langConfig.Versions) == 0 {
+		if version != "" {
+			return LanguageConfig{}, "", fmt.Errorf("%w %q, %s doesn't offer multiple versions", ErrUnsupportedVersion, version, lang)
+		}
+		return langConfig, "", nil
+	}
+
+	if version == "" {
+		version = langConfig.DefaultVersion
+	}
+	if _, ok := langConfig.Versions[version]; !ok {
+		return LanguageConfig{}, "", fmt.Errorf("%w %q of %s, use one of %s", ErrUnsupportedVersion, version, lang,
+			strings.Join(langConfig.GetVersions(), ", "))
+	}
+	return langConfig.ResolveVersion(version), version, nil
+}
+
+// GetVersions returns the versions of the language in a stable order.
+func (l LanguageConfig) GetVersions() []string {
+	return slices.Sorted(maps.Keys(l.Versions))
+}
+
+// ResolveVersion returns the config with the settings of a configured version merged in and no versions.
+func (l LanguageConfig) ResolveVersion(version string) LanguageConfig {
+	versionConfig := l.Versions[version]
+	resolved := l
+	resolved.DefaultVersion = ""
+	resolved.Versions = nil
+
+	if versionConfig.Image != "" {
+		resolved.Image = versionConfig.Image
+	}
+	if versionConfig.Command != "" {
+		resolved.Command = versionConfig.Command
+	}
+	if versionConfig.ProjectCommand != "" {
+		resolved.ProjectCommand = versionConfig.ProjectCommand
+	}
+	if versionConfig.Flags != "" {
+		resolved.Flags = versionConfig.Flags
+	}
+	resolved.Limits = resolved.Limits.Merge(versionConfig.Limits)
+	return resolved
+}
+
+// Merge returns the limits overridden by the non-zero limits of other.
+func (l Limits) Merge(other Limits) Limits {
+	if other.TimeLimit != 0 {
+		l.TimeLimit = other.TimeLimit
+	}
+	if other.CPUTimeLimit != 0 {
+		l.CPUTimeLimit = other.CPUTimeLimit
+	}
+	if other.MemoryMB != 0 {
+		l.MemoryMB = other.MemoryMB
+	}
+	if other.CPUs != 0 {
+		l.CPUs = other.CPUs
+	}
+	if other.MaxProcesses != 0 {
+		l.MaxProcesses = other.MaxProcesses
+	}
+	if other.MaxFileSizeMB != 0 {
+		l.MaxFileSizeMB = other.MaxFileSizeMB
+	}
+	return l
+}
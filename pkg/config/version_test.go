@@ -0,0 +1,182 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func versionedConfig() ImageConfig {
+	return ImageConfig{
+		Golang: {
+			Extension: ".go",
+			Image:     "golang:latest",
+			Command:   "go run {{FILE}} < {{INPUT}}",
+		},
+		Cpp: {
+			Extension: ".cpp",
+			Image:     "gcc:12",
+			Command:   "run {{FLAGS}} {{FILE}} {{INPUT}}",
+			Limits: Limits{
+				TimeLimit: 5 * time.Second,
+				MemoryMB:  256,
+			},
+			DefaultVersion: "c++17",
+			Versions: map[string]VersionConfig{
+				"c++17": {Flags: "-std=c++17"},
+				"c++20": {Image: "gcc:14", Flags: "-std=c++20", Limits: Limits{MemoryMB: 512}},
+			},
+		},
+	}
+}
+
+func TestResolve(t *testing.T) {
+	config := versionedConfig()
+
+	tests := []struct {
+		name            string
+		lang            Language
+		version         string
+		expectedVersion string
+		expectedImage   string
+		expectedFlags   string
+		expectedLimits  Limits
+	}{
+		{
+			name:           "language without versions",
+			lang:           Golang,
+			expectedImage:  "golang:latest",
+			expectedLimits: Limits{},
+		},
+		{
+			name:            "default version",
+			lang:            Cpp,
+			expectedVersion: "c++17",
+			expectedImage:   "gcc:12",
+			expectedFlags:   "-std=c++17",
+			expectedLimits:  Limits{TimeLimit: 5 * time.Second, MemoryMB: 256},
+		},
+		{
+			name:            "requested version",
+			lang:            Cpp,
+			version:         "c++20",
+			expectedVersion: "c++20",
+			expectedImage:   "gcc:14",
+			expectedFlags:   "-std=c++20",
+			expectedLimits:  Limits{TimeLimit: 5 * time.Second, MemoryMB: 512},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			langConfig, version, err := config.Resolve(tt.lang, tt.version)
+			if err != nil {
+				t.Fatalf("failed to resolve: %v", err)
+			}
+
+			if version != tt.expectedVersion {
+				t.Errorf("expected version %q, got %q", tt.expectedVersion, version)
+			}
+			if langConfig.Image != tt.expectedImage || langConfig.Flags != tt.expectedFlags {
+				t.Errorf("expected image %q and flags %q, got %q and %q",
+					tt.expectedImage, tt.expectedFlags, langConfig.Image, langConfig.Flags)
+			}
+			if langConfig.Limits != tt.expectedLimits {
+				t.Errorf("expected limits %+v, got %+v", tt.expectedLimits, langConfig.Limits)
+			}
+			if langConfig.Versions != nil || langConfig.DefaultVersion != "" {
+				t.Error("expected the resolved config to have no versions")
+			}
+		})
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	config := versionedConfig()
+
+	tests := []struct {
+		name     string
+		lang     Language
+		version  string
+		expected error
+	}{
+		{"unknown language", "cobol", "", ErrUnsupportedLanguage},
+		{"unknown version", Cpp, "c++98", ErrUnsupportedVersion},
+		{"version of a language without versions", Golang, "1.21", ErrUnsupportedVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := config.Resolve(tt.lang, tt.version)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidateVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*LanguageConfig)
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			modify: func(*LanguageConfig) {},
+		},
+		{
+			name:    "missing default version",
+			modify:  func(c *LanguageConfig) { c.DefaultVersion = "" },
+			wantErr: "cpp.default_version: default_version is required when versions are configured, use one of c++17, c++20",
+		},
+		{
+			name:    "unknown default version",
+			modify:  func(c *LanguageConfig) { c.DefaultVersion = "c++23" },
+			wantErr: `cpp.default_version: "c++23" is not one of the versions`,
+		},
+		{
+			name: "version without image",
+			modify: func(c *LanguageConfig) {
+				c.Image = ""
+				c.Versions["c++17"] = VersionConfig{}
+			},
+			wantErr: "cpp.versions.c++17.image: image is required",
+		},
+		{
+			name: "version limits out of range",
+			modify: func(c *LanguageConfig) {
+				c.Versions["c++20"] = VersionConfig{Limits: Limits{MemoryMB: 1}}
+			},
+			wantErr: "cpp.versions.c++20.limits.memory_mb: 1 is out of range",
+		},
+		{
+			name: "invalid version name",
+			modify: func(c *LanguageConfig) {
+				c.Versions["c++ 23"] = VersionConfig{}
+			},
+			wantErr: "cpp.versions.c++ 23: version names",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := versionedConfig()
+			langConfig := config[Cpp]
+			tt.modify(&langConfig)
+			config[Cpp] = langConfig
+
+			err := config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
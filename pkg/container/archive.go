@@ -0,0 +1,166 @@
+package codecontainer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ErrInvalidArchive = errors.New("invalid archive")
+
+// ArchiveLimits restrict what an uploaded archive may extract to, the sizes of the entries
+// are counted while reading so a lying header can't be used to sneak in more data.
+type ArchiveLimits struct {
+	MaxSize  int64
+	MaxFiles int
+}
+
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// archiveReader collects the regular files of an archive within the limits.
+type archiveReader struct {
+	limits ArchiveLimits
+	size   int64
+	files  []File
+}
+
+// ReadArchive reads the files of a zip or tar.gz archive, the format is detected from the content.
+// Links and special files are rejected, directories are implied by the paths of the files.
+// If every file is inside the same top-level directory, e.g. because a whole project folder was
+// archived, that directory is stripped.
+func ReadArchive(r io.ReaderAt, size int64, limits ArchiveLimits) ([]File, error) {
+	header := make([]byte, len(zipMagic))
+	n, _ := r.ReadAt(header, 0)
+	header = header[:n]
+
+	reader := &archiveReader{limits: limits}
+	var err error
+	switch {
+	case bytes.HasPrefix(header, zipMagic):
+		err = reader.readZip(r, size)
+	case bytes.HasPrefix(header, gzipMagic):
+		err = reader.readTarGz(io.NewSectionReader(r, 0, size))
+	default:
+		return nil, fmt.Errorf("%w: only zip and tar.gz archives are supported", ErrInvalidArchive)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(reader.files) == 0 {
+		return nil, fmt.Errorf("%w: the archive contains no files", ErrInvalidArchive)
+	}
+	stripCommonDirectory(reader.files)
+	return reader.files, nil
+}
+
+func (a *archiveReader) readZip(r io.ReaderAt, size int64) error {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%w: failed to read the zip archive: %v", ErrInvalidArchive, err)
+	}
+
+	for _, entry := range archive.File {
+		mode := entry.Mode()
+		switch {
+		case mode.IsDir():
+			continue
+		case !mode.IsRegular():
+			return fmt.Errorf("%w: %s is not a regular file, links are not allowed", ErrInvalidArchive, entry.Name)
+		}
+
+		f, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("%w: failed to open %s: %v", ErrInvalidArchive, entry.Name, err)
+		}
+		err = a.add(entry.Name, f)
+		_ = f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *archiveReader) readTarGz(r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: failed to read the gzip stream: %v", ErrInvalidArchive, err)
+	}
+	defer func() {
+		_ = gz.Close()
+	}()
+
+	archive := tar.NewReader(gz)
+	for {
+		entry, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: failed to read the tar archive: %v", ErrInvalidArchive, err)
+		}
+
+		switch entry.Typeflag {
+		case tar.TypeDir, tar.TypeXGlobalHeader:
+			continue
+		case tar.TypeReg:
+			if err := a.add(entry.Name, archive); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: %s is not a regular file, links are not allowed", ErrInvalidArchive, entry.Name)
+		}
+	}
+}
+
+func (a *archiveReader) add(name string, content io.Reader) error {
+	// Metadata added by the macOS archive utility
+	if strings.HasPrefix(name, "__MACOSX/") {
+		return nil
+	}
+
+	filePath, err := cleanFilePath(name)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if len(a.files) >= a.limits.MaxFiles {
+		return fmt.Errorf("%w: the archive contains more than %d files", ErrInvalidArchive, a.limits.MaxFiles)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, a.limits.MaxSize-a.size+1))
+	if err != nil {
+		return fmt.Errorf("%w: failed to read %s: %v", ErrInvalidArchive, name, err)
+	}
+	a.size += int64(len(data))
+	if a.size > a.limits.MaxSize {
+		return fmt.Errorf("%w: the extracted files are larger than %d bytes", ErrInvalidArchive, a.limits.MaxSize)
+	}
+
+	a.files = append(a.files, File{Path: filePath, Content: data})
+	return nil
+}
+
+func stripCommonDirectory(files []File) {
+	dir, _, ok := strings.Cut(files[0].Path, "/")
+	if !ok {
+		return
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Path, dir+"/") {
+			return
+		}
+	}
+
+	for i := range files {
+		files[i].Path = strings.TrimPrefix(files[i].Path, dir+"/")
+	}
+}
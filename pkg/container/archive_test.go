@@ -0,0 +1,165 @@
+package codecontainer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+)
+
+type archiveEntry struct {
+	name     string
+	content  string
+	typeflag byte
+}
+
+func createZip(t *testing.T, entries []archiveEntry) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		f, err := w.Create(entry.name)
+		if err != nil {
+			t.Fatalf("failed to create the zip entry: %v", err)
+		}
+		if _, err := f.Write([]byte(entry.content)); err != nil {
+			t.Fatalf("failed to write the zip entry: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close the zip archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func createTarGz(t *testing.T, entries []archiveEntry) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := tar.NewWriter(gz)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.name,
+			Mode:     0644,
+			Size:     int64(len(entry.content)),
+			Typeflag: entry.typeflag,
+		}
+		if entry.typeflag == tar.TypeSymlink {
+			header.Linkname, header.Size = entry.content, 0
+		}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatalf("failed to write the tar header: %v", err)
+		}
+		if entry.typeflag == tar.TypeReg {
+			if _, err := w.Write([]byte(entry.content)); err != nil {
+				t.Fatalf("failed to write the tar entry: %v", err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close the tar archive: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to close the gzip stream: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadArchive(t *testing.T) {
+	limits := ArchiveLimits{MaxSize: 64, MaxFiles: 2}
+
+	tests := []struct {
+		name          string
+		archive       func(t *testing.T) []byte
+		expectedFiles map[string]string
+		expectedErr   error
+	}{
+		{
+			name: "zip archive",
+			archive: func(t *testing.T) []byte {
+				return createZip(t, []archiveEntry{
+					{name: "main.cpp", content: "int main() {}"},
+					{name: "include/util.h", content: "int f();"},
+				})
+			},
+			expectedFiles: map[string]string{"main.cpp": "int main() {}", "include/util.h": "int f();"},
+		},
+		{
+			name: "tar.gz archive of a project folder",
+			archive: func(t *testing.T) []byte {
+				return createTarGz(t, []archiveEntry{
+					{name: "project/", typeflag: tar.TypeDir},
+					{name: "project/main.go", content: "package main", typeflag: tar.TypeReg},
+					{name: "./project/util/util.go", content: "package util", typeflag: tar.TypeReg},
+				})
+			},
+			expectedFiles: map[string]string{"main.go": "package main", "util/util.go": "package util"},
+		},
+		{
+			name: "symlink",
+			archive: func(t *testing.T) []byte {
+				return createTarGz(t, []archiveEntry{
+					{name: "main.go", content: "/etc/passwd", typeflag: tar.TypeSymlink},
+				})
+			},
+			expectedErr: ErrInvalidArchive,
+		},
+		{
+			name: "path traversal",
+			archive: func(t *testing.T) []byte {
+				return createZip(t, []archiveEntry{{name: "../main.cpp", content: "int main() {}"}})
+			},
+			expectedErr: ErrInvalidArchive,
+		},
+		{
+			name: "absolute path",
+			archive: func(t *testing.T) []byte {
+				return createTarGz(t, []archiveEntry{{name: "/tmp/main.go", content: "package main", typeflag: tar.TypeReg}})
+			},
+			expectedErr: ErrInvalidArchive,
+		},
+		{
+			name: "too many files",
+			archive: func(t *testing.T) []byte {
+				return createZip(t, []archiveEntry{{name: "a.c"}, {name: "b.c"}, {name: "c.c"}})
+			},
+			expectedErr: ErrInvalidArchive,
+		},
+		{
+			name: "extracted files too large",
+			archive: func(t *testing.T) []byte {
+				return createZip(t, []archiveEntry{{name: "main.c", content: string(bytes.Repeat([]byte("a"), 65))}})
+			},
+			expectedErr: ErrInvalidArchive,
+		},
+		{
+			name: "unsupported format",
+			archive: func(t *testing.T) []byte {
+				return []byte("int main() {}")
+			},
+			expectedErr: ErrInvalidArchive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.archive(t)
+			files, err := ReadArchive(bytes.NewReader(data), int64(len(data)), limits)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if len(files) != len(tt.expectedFiles) {
+				t.Fatalf("expected %d files, got %d", len(tt.expectedFiles), len(files))
+			}
+			for _, file := range files {
+				if expected, ok := tt.expectedFiles[file.Path]; !ok || string(file.Content) != expected {
+					t.Errorf("unexpected file '%s' with content '%s'", file.Path, file.Content)
+				}
+			}
+		})
+	}
+}
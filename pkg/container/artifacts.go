@@ -0,0 +1,125 @@
+package codecontainer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+const (
+	MaxArtifactPatterns = 16
+	MaxArtifacts        = 64
+)
+
+var ErrInvalidArtifactPattern = errors.New("invalid artifact pattern")
+
+// validateArtifactPatterns checks that the patterns are valid globs relative to the submission directory.
+func validateArtifactPatterns(patterns []string) error {
+	if len(patterns) > MaxArtifactPatterns {
+		return fmt.Errorf("%w: at most %d patterns can be declared", ErrInvalidArtifactPattern, MaxArtifactPatterns)
+	}
+
+	for _, pattern := range patterns {
+		if _, err := cleanRelativePath(pattern); err != nil {
+			return fmt.Errorf("%w %q: %v", ErrInvalidArtifactPattern, pattern, err)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w %q: %v", ErrInvalidArtifactPattern, pattern, err)
+		}
+	}
+	return nil
+}
+
+// collectArtifacts reads the files of the submission directory matching the artifact patterns of the code,
+// the input and the submitted files are never returned. Only regular files are read, a link created by the
+// program must not expose files of the host. Files which would exceed maxSize or MaxArtifacts are left out
+// and reported by the returned flag.
+func collectArtifacts(submissionDir string, code *Code, maxSize int64, logger *zap.Logger) ([]Artifact, bool, error) {
+	if len(code.Artifacts) == 0 {
+		return nil, false, nil
+	}
+
+	submitted := map[string]bool{inputFileName: true, getEntrypoint(code): true}
+	for _, file := range code.Files {
+		submitted[file.Path] = true
+	}
+
+	artifacts := []Artifact{}
+	truncated := false
+	var size int64
+	err := filepath.WalkDir(submissionDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(submissionDir, filePath)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		if submitted[relativePath] || !matchesAny(code.Artifacts, relativePath) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if len(artifacts) >= MaxArtifacts || size+info.Size() > maxSize {
+			logger.Info("skipping the artifact, the artifact limits are exceeded",
+				zap.String("path", relativePath),
+				zap.Int64("size", info.Size()),
+			)
+			truncated = true
+			return nil
+		}
+
+		data, err := readArtifact(filePath, maxSize-size)
+		if err != nil {
+			return err
+		}
+		size += int64(len(data))
+		artifacts = append(artifacts, Artifact{
+			Path:           relativePath,
+			EncodedContent: base64.StdEncoding.EncodeToString(data),
+			Size:           int64(len(data)),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to collect the artifacts: %w", err)
+	}
+
+	return artifacts, truncated, nil
+}
+
+func matchesAny(patterns []string, filePath string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		matched, _ := path.Match(pattern, filePath)
+		return matched
+	})
+}
+
+// readArtifact reads at most limit bytes, the file may not grow past the size it was checked with.
+func readArtifact(filePath string, limit int64) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return io.ReadAll(io.LimitReader(f, limit))
+}
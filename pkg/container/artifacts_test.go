@@ -0,0 +1,95 @@
+package codecontainer
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestValidateArtifactPatterns(t *testing.T) {
+	tests := []struct {
+		name        string
+		patterns    []string
+		expectedErr error
+	}{
+		{
+			name:     "valid patterns",
+			patterns: []string{"*.csv", "out/*.png", "result.txt"},
+		},
+		{
+			name:        "absolute pattern",
+			patterns:    []string{"/etc/*"},
+			expectedErr: ErrInvalidArtifactPattern,
+		},
+		{
+			name:        "pattern leaving the submission directory",
+			patterns:    []string{"../*"},
+			expectedErr: ErrInvalidArtifactPattern,
+		},
+		{
+			name:        "malformed pattern",
+			patterns:    []string{"out/[.png"},
+			expectedErr: ErrInvalidArtifactPattern,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateArtifactPatterns(tt.patterns)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestCollectArtifacts(t *testing.T) {
+	submissionDir := t.TempDir()
+	files := map[string]string{
+		"main.py":        "print('hello')",
+		"input.txt":      "input",
+		"result.csv":     "a,b",
+		"out/plot.png":   "png",
+		"out/large.png":  "0123456789",
+		"out/notes.md":   "notes",
+		"other/data.csv": "c,d",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(submissionDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("failed to create the directory: %v", err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write the file: %v", err)
+		}
+	}
+	if err := os.Symlink("/etc/passwd", filepath.Join(submissionDir, "passwd.csv")); err != nil {
+		t.Fatalf("failed to create the symlink: %v", err)
+	}
+
+	code := &Code{Artifacts: []string{"*.csv", "out/*.png", "*.txt", "*.py"}}
+	code.Extension = ".py"
+
+	artifacts, truncated, err := collectArtifacts(submissionDir, code, 8, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to collect the artifacts: %v", err)
+	}
+
+	expected := map[string]string{"result.csv": "a,b", "out/plot.png": "png"}
+	if len(artifacts) != len(expected) {
+		t.Fatalf("expected %d artifacts, got %+v", len(expected), artifacts)
+	}
+	for _, artifact := range artifacts {
+		content, _ := base64.StdEncoding.DecodeString(artifact.EncodedContent)
+		if expected[artifact.Path] != string(content) || artifact.Size != int64(len(content)) {
+			t.Errorf("unexpected artifact %+v", artifact)
+		}
+	}
+	if !truncated {
+		t.Error("expected the artifacts to be truncated, out/large.png exceeds the size limit")
+	}
+}
@@ -0,0 +1,60 @@
+package codecontainer
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// newBenchmarkResult summarizes the measured runs of a benchmark.
+func newBenchmarkResult(benchmark *Benchmark, runs []runUsage) *BenchmarkResult {
+	wallTimes := make([]float64, len(runs))
+	cpuTimes := make([]float64, len(runs))
+	peakMemories := make([]float64, len(runs))
+	for i, run := range runs {
+		wallTimes[i] = float64(run.WallTime) / float64(time.Millisecond)
+		cpuTimes[i] = float64(run.CPUTime) / float64(time.Millisecond)
+		peakMemories[i] = float64(run.PeakMemoryBytes) / 1024
+	}
+
+	return &BenchmarkResult{
+		Runs:         len(runs),
+		Warmups:      benchmark.Warmups,
+		WallTimeMs:   summarize(wallTimes),
+		CPUTimeMs:    summarize(cpuTimes),
+		PeakMemoryKB: summarize(peakMemories),
+	}
+}
+
+// summarize returns the statistics of at least one value. The 95th percentile is the nearest rank and the standard
+// deviation is the one of a sample, 0 for a single value.
+func summarize(values []float64) Summary {
+	sorted := slices.Sorted(slices.Values(values))
+	n := len(sorted)
+
+	median := sorted[n/2]
+	if n%2 == 0 {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+
+	var sum float64
+	for _, value := range sorted {
+		sum += value
+	}
+	mean := sum / float64(n)
+	var squares float64
+	for _, value := range sorted {
+		squares += (value - mean) * (value - mean)
+	}
+	var stddev float64
+	if n > 1 {
+		stddev = math.Sqrt(squares / float64(n-1))
+	}
+
+	return Summary{
+		Min:    sorted[0],
+		Median: median,
+		P95:    sorted[int(math.Ceil(0.95*float64(n)))-1],
+		Stddev: stddev,
+	}
+}
@@ -0,0 +1,65 @@
+package codecontainer
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []float64
+		expected Summary
+	}{
+		{name: "single value", values: []float64{5}, expected: Summary{Min: 5, Median: 5, P95: 5}},
+		{
+			name:     "odd number of values",
+			values:   []float64{3, 1, 2},
+			expected: Summary{Min: 1, Median: 2, P95: 3, Stddev: 1},
+		},
+		{
+			name:     "even number of values",
+			values:   []float64{4, 1, 3, 2},
+			expected: Summary{Min: 1, Median: 2.5, P95: 4, Stddev: math.Sqrt(5.0 / 3)},
+		},
+		{
+			name:     "nearest rank percentile",
+			values:   []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 100},
+			expected: Summary{Min: 1, Median: 11, P95: 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := summarize(tt.values)
+			if summary.Min != tt.expected.Min || summary.Median != tt.expected.Median || summary.P95 != tt.expected.P95 {
+				t.Errorf("expected %+v, got %+v", tt.expected, summary)
+			}
+			if tt.expected.Stddev != 0 && math.Abs(summary.Stddev-tt.expected.Stddev) > 1e-9 {
+				t.Errorf("expected the standard deviation %g, got %g", tt.expected.Stddev, summary.Stddev)
+			}
+		})
+	}
+}
+
+func TestNewBenchmarkResult(t *testing.T) {
+	runs := []runUsage{
+		{WallTime: 120 * time.Millisecond, ResourceUsage: ResourceUsage{CPUTime: 100 * time.Millisecond, PeakMemoryBytes: 4096 * 1024}},
+		{WallTime: 100 * time.Millisecond, ResourceUsage: ResourceUsage{CPUTime: 90 * time.Millisecond, PeakMemoryBytes: 4096 * 1024}},
+	}
+
+	result := newBenchmarkResult(&Benchmark{Runs: 2, Warmups: 1}, runs)
+	if result.Runs != 2 || result.Warmups != 1 {
+		t.Errorf("expected 2 runs after 1 warmup, got %+v", result)
+	}
+	if result.WallTimeMs.Min != 100 || result.WallTimeMs.Median != 110 {
+		t.Errorf("expected the wall times in milliseconds, got %+v", result.WallTimeMs)
+	}
+	if result.CPUTimeMs.P95 != 100 {
+		t.Errorf("expected the CPU times in milliseconds, got %+v", result.CPUTimeMs)
+	}
+	if result.PeakMemoryKB != (Summary{Min: 4096, Median: 4096, P95: 4096}) {
+		t.Errorf("expected the peak memory in KB, got %+v", result.PeakMemoryKB)
+	}
+}
@@ -0,0 +1,229 @@
+package codecontainer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"remote-code-engine/pkg/cache"
+	"remote-code-engine/pkg/config"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Image digests are looked up again after this long, so a rebuilt image stops serving stale results
+// without inspecting the image for every submission.
+const imageDigestTTL = time.Minute
+
+type imageDigest struct {
+	digest  string
+	expires time.Time
+}
+
+// cachedResult is an entry of the result cache, the streams are replayed to stdout and stderr like the execution
+// wrote them.
+type cachedResult struct {
+	Result *Result `json:"result"`
+	Stdout string  `json:"stdout"`
+	Stderr string  `json:"stderr"`
+}
+
+// cachingClient serves identical submissions from a result cache, everything else is passed on to the wrapped client.
+type cachingClient struct {
+	ContainerClient
+	cache  cache.Cache
+	config *config.ServerConfig
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	digests map[string]imageDigest
+}
+
+// NewCachingClient wraps client with the result cache, client is returned as is if resultCache is nil.
+func NewCachingClient(client ContainerClient, resultCache cache.Cache, serverConfig *config.ServerConfig, logger *zap.Logger) ContainerClient {
+	if resultCache == nil {
+		return client
+	}
+
+	return &cachingClient{
+		ContainerClient: client,
+		cache:           resultCache,
+		config:          serverConfig,
+		logger:          logger,
+		digests:         map[string]imageDigest{},
+	}
+}
+
+func (c *cachingClient) ExecuteCode(ctx context.Context, code *Code) (*Result, error) {
+	return c.StreamCode(ctx, code, io.Discard, io.Discard)
+}
+
+// StreamCode writes each stream of a cached result to its writer at once.
+func (c *cachingClient) StreamCode(ctx context.Context, code *Code, stdout, stderr io.Writer) (*Result, error) {
+	// The timings of a benchmark are what it measures, a cached result wouldn't measure anything. A deterministic
+	// run records the image it ran with, a cached result may come from an older image.
+	if code.NoCache || code.Benchmark != nil || code.Deterministic != nil {
+		return c.ContainerClient.StreamCode(ctx, code, stdout, stderr)
+	}
+
+	digest, err := c.imageDigest(ctx, code.Image)
+	if err != nil {
+		c.logger.Error("failed to get the image digest, skipping the result cache",
+			zap.String("image", code.Image),
+			zap.Error(err),
+		)
+		return c.ContainerClient.StreamCode(ctx, code, stdout, stderr)
+	}
+
+	key, err := code.Hash(digest, c.config.EffectiveLimits(code.Limits))
+	if err != nil {
+		// The execution reports the invalid code.
+		return c.ContainerClient.StreamCode(ctx, code, stdout, stderr)
+	}
+
+	if data, ok := c.cache.Get(key); ok {
+		var cached cachedResult
+		if err := json.Unmarshal(data, &cached); err == nil && cached.Result != nil {
+			c.logger.Info("serving the result from the cache", zap.String("key", key))
+			cached.Result.Cached = true
+			// The writers may send an event for every write, empty streams aren't written.
+			if cached.Stdout != "" {
+				_, _ = io.WriteString(stdout, cached.Stdout)
+			}
+			if cached.Stderr != "" {
+				_, _ = io.WriteString(stderr, cached.Stderr)
+			}
+			return cached.Result, nil
+		}
+	}
+
+	// The streams are bounded by the output limit of the execution.
+	var stdoutCopy, stderrCopy strings.Builder
+	result, err := c.ContainerClient.StreamCode(ctx, code, io.MultiWriter(stdout, &stdoutCopy), io.MultiWriter(stderr, &stderrCopy))
+	if err != nil || result.TimedOut || result.OutputTruncated || result.Verdict == VerdictCancelled {
+		// Time limits depend on the load of the host, a timeout is not a property of the code. Where the output
+		// is cut off depends on when the container was killed.
+		return result, err
+	}
+
+	data, err := json.Marshal(cachedResult{Result: result, Stdout: stdoutCopy.String(), Stderr: stderrCopy.String()})
+	if err == nil {
+		err = c.cache.Set(key, data)
+	}
+	if err != nil {
+		c.logger.Error("failed to cache the result", zap.String("key", key), zap.Error(err))
+	}
+	return result, nil
+}
+
+func (c *cachingClient) imageDigest(ctx context.Context, image string) (string, error) {
+	c.mu.Lock()
+	cached, ok := c.digests[image]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.digest, nil
+	}
+
+	digest, err := c.ContainerClient.ImageDigest(ctx, image)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.digests[image] = imageDigest{digest: digest, expires: time.Now().Add(imageDigestTTL)}
+	c.mu.Unlock()
+	return digest, nil
+}
+
+type hashedFile struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+}
+
+// Hash returns a key identifying everything the result of an execution depends on: the code and the input,
+// the image and how it is run. Equivalent submissions, e.g. listing the same files in a different order, get the same key.
+func (c *Code) Hash(imageDigest string, limits config.Limits) (string, error) {
+	hashContent := func(encoded string) (string, error) {
+		hash := sha256.New()
+		if _, err := io.Copy(hash, base64.NewDecoder(base64.StdEncoding, strings.NewReader(encoded))); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	var files []hashedFile
+	if c.IsProject() {
+		for _, file := range c.Files {
+			if file.Content != nil {
+				hash := sha256.Sum256(file.Content)
+				files = append(files, hashedFile{Path: file.Path, Hash: hex.EncodeToString(hash[:])})
+				continue
+			}
+			hash, err := hashContent(file.EncodedContent)
+			if err != nil {
+				return "", fmt.Errorf("failed to decode %s: %w", file.Path, err)
+			}
+			files = append(files, hashedFile{Path: file.Path, Hash: hash})
+		}
+		slices.SortFunc(files, func(a, b hashedFile) int {
+			return strings.Compare(a.Path, b.Path)
+		})
+	} else {
+		hash, err := hashContent(c.EncodedCode)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode the code: %w", err)
+		}
+		files = append(files, hashedFile{Path: getEntrypoint(c), Hash: hash})
+	}
+
+	// Uploaded inputs are identified by the hash of their content already.
+	input := c.InputID
+	if c.InputPath == "" {
+		var err error
+		input, err = hashContent(c.EncodedInput)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode the input: %w", err)
+		}
+	}
+
+	key, err := json.Marshal(struct {
+		Language    config.Language   `json:"language"`
+		Version     string            `json:"version"`
+		ImageDigest string            `json:"image_digest"`
+		Command     string            `json:"command"`
+		Flags       string            `json:"flags"`
+		Env         map[string]string `json:"env"`
+		Caches      []config.Cache    `json:"caches"`
+		Entrypoint  string            `json:"entrypoint"`
+		Files       []hashedFile      `json:"files"`
+		Input       string            `json:"input"`
+		Artifacts   []string          `json:"artifacts"`
+		Limits      config.Limits     `json:"limits"`
+	}{
+		Language:    c.Language,
+		Version:     c.Version,
+		ImageDigest: imageDigest,
+		Command:     c.GetCommand(c.IsProject()),
+		Flags:       c.Flags,
+		Env:         c.Env,
+		Caches:      c.Caches,
+		Entrypoint:  getEntrypoint(c),
+		Files:       files,
+		Input:       input,
+		Artifacts:   slices.Sorted(slices.Values(c.Artifacts)),
+		Limits:      limits,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:]), nil
+}
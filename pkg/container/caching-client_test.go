@@ -0,0 +1,239 @@
+package codecontainer
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"remote-code-engine/pkg/cache"
+	"remote-code-engine/pkg/config"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeClient counts the executions instead of running containers.
+type fakeClient struct {
+	ContainerClient
+	executions int
+	result     Result
+	stderr     string
+}
+
+func (f *fakeClient) StreamCode(ctx context.Context, code *Code, stdout, stderr io.Writer) (*Result, error) {
+	f.executions++
+	result := f.result
+	_, _ = io.WriteString(stdout, result.Output)
+	_, _ = io.WriteString(stderr, f.stderr)
+	return &result, nil
+}
+
+func (f *fakeClient) ImageDigest(ctx context.Context, image string) (string, error) {
+	return "sha256:" + image, nil
+}
+
+func TestCachingClient(t *testing.T) {
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	newCode := func() *Code {
+		return &Code{
+			EncodedCode:  encode("print(input())"),
+			EncodedInput: encode("hello"),
+			Language:     "python",
+			LanguageConfig: config.LanguageConfig{
+				Extension: ".py",
+				Image:     "python",
+				Command:   "run.sh {{FILE}}",
+			},
+		}
+	}
+
+	fake := &fakeClient{result: Result{Output: "hello"}}
+	serverConfig := config.DefaultServerConfig()
+	client := NewCachingClient(fake, cache.NewMemory(time.Minute, 1024), &serverConfig, zap.NewNop())
+
+	tests := []struct {
+		name               string
+		code               func() *Code
+		expectedCached     bool
+		expectedExecutions int
+	}{
+		{
+			name:               "first submission",
+			code:               newCode,
+			expectedExecutions: 1,
+		},
+		{
+			name:               "identical submission",
+			code:               newCode,
+			expectedCached:     true,
+			expectedExecutions: 1,
+		},
+		{
+			name: "different input",
+			code: func() *Code {
+				code := newCode()
+				code.EncodedInput = encode("world")
+				return code
+			},
+			expectedExecutions: 2,
+		},
+		{
+			name: "different limits",
+			code: func() *Code {
+				code := newCode()
+				code.Limits.TimeLimit = time.Second
+				return code
+			},
+			expectedExecutions: 3,
+		},
+		{
+			name: "cache bypassed",
+			code: func() *Code {
+				code := newCode()
+				code.NoCache = true
+				return code
+			},
+			expectedExecutions: 4,
+		},
+		{
+			name: "benchmark",
+			code: func() *Code {
+				code := newCode()
+				code.Benchmark = &Benchmark{Runs: 5}
+				return code
+			},
+			expectedExecutions: 5,
+		},
+		{
+			name: "deterministic",
+			code: func() *Code {
+				code := newCode()
+				code.Deterministic = &Deterministic{Seed: 42}
+				return code
+			},
+			expectedExecutions: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := client.ExecuteCode(context.Background(), tt.code())
+			if err != nil {
+				t.Fatalf("failed to execute the code: %v", err)
+			}
+			if result.Cached != tt.expectedCached || result.Output != "hello" {
+				t.Errorf("expected cached %v with output 'hello', got %+v", tt.expectedCached, result)
+			}
+			if fake.executions != tt.expectedExecutions {
+				t.Errorf("expected %d executions, got %d", tt.expectedExecutions, fake.executions)
+			}
+		})
+	}
+}
+
+func TestCachingClientSkipsKilledExecutions(t *testing.T) {
+	for _, result := range []Result{
+		{Output: "Time limit exceeded", Verdict: VerdictTimeLimitExceeded, TimedOut: true},
+		{Output: "xxxx", Verdict: VerdictOutputLimitExceeded, OutputTruncated: true},
+	} {
+		t.Run(string(result.Verdict), func(t *testing.T) {
+			fake := &fakeClient{result: result}
+			serverConfig := config.DefaultServerConfig()
+			client := NewCachingClient(fake, cache.NewMemory(time.Minute, 1024), &serverConfig, zap.NewNop())
+
+			code := &Code{
+				EncodedCode:    base64.StdEncoding.EncodeToString([]byte("while True: print('x')")),
+				Language:       "python",
+				LanguageConfig: config.LanguageConfig{Extension: ".py", Image: "python"},
+			}
+			for range 2 {
+				if _, err := client.ExecuteCode(context.Background(), code); err != nil {
+					t.Fatalf("failed to execute the code: %v", err)
+				}
+			}
+			if fake.executions != 2 {
+				t.Errorf("expected the killed execution not to be cached, got %d executions", fake.executions)
+			}
+		})
+	}
+}
+
+func TestCachingClientStreamCode(t *testing.T) {
+	code := &Code{
+		EncodedCode: base64.StdEncoding.EncodeToString([]byte("print('hello')")),
+		Language:    "python",
+		LanguageConfig: config.LanguageConfig{
+			Extension: ".py",
+			Image:     "python",
+			Command:   "run.sh {{FILE}}",
+		},
+	}
+	serverConfig := config.DefaultServerConfig()
+
+	tests := []struct {
+		name               string
+		verdict            Verdict
+		expectedExecutions int
+	}{
+		{
+			name:               "finished execution is cached",
+			verdict:            VerdictOK,
+			expectedExecutions: 1,
+		},
+		{
+			name:               "cancelled execution is not cached",
+			verdict:            VerdictCancelled,
+			expectedExecutions: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeClient{result: Result{Output: "hello", Verdict: tt.verdict}, stderr: "warning"}
+			client := NewCachingClient(fake, cache.NewMemory(time.Minute, 1024), &serverConfig, zap.NewNop())
+
+			for range 2 {
+				var stdout, stderr strings.Builder
+				if _, err := client.StreamCode(context.Background(), code, &stdout, &stderr); err != nil {
+					t.Fatalf("failed to execute the code: %v", err)
+				}
+				if stdout.String() != "hello" || stderr.String() != "warning" {
+					t.Errorf("expected 'hello' to be streamed to stdout and 'warning' to stderr, got %q and %q", stdout.String(), stderr.String())
+				}
+			}
+			if fake.executions != tt.expectedExecutions {
+				t.Errorf("expected %d executions, got %d", tt.expectedExecutions, fake.executions)
+			}
+		})
+	}
+}
+
+func TestCodeHashIgnoresFileOrder(t *testing.T) {
+	code := &Code{Files: []File{{Path: "a.go", EncodedContent: "YQ=="}, {Path: "b.go", EncodedContent: "Yg=="}}}
+	reordered := &Code{Files: []File{code.Files[1], code.Files[0]}}
+
+	hash, err := code.Hash("digest", config.Limits{})
+	if err != nil {
+		t.Fatalf("failed to hash the code: %v", err)
+	}
+	reorderedHash, err := reordered.Hash("digest", config.Limits{})
+	if err != nil {
+		t.Fatalf("failed to hash the code: %v", err)
+	}
+	if hash != reorderedHash {
+		t.Error("expected the same hash regardless of the order of the files")
+	}
+
+	raw := &Code{Files: []File{{Path: "a.go", Content: []byte("a")}, {Path: "b.go", Content: []byte("b")}}}
+	if rawHash, _ := raw.Hash("digest", config.Limits{}); hash != rawHash {
+		t.Error("expected the same hash for files extracted from an archive")
+	}
+
+	otherDigest, _ := code.Hash("other digest", config.Limits{})
+	if hash == otherDigest {
+		t.Error("expected a different hash for a different image")
+	}
+}
@@ -0,0 +1,118 @@
+package codecontainer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"remote-code-engine/pkg/config"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+var ErrImageChanged = errors.New("the image with the recorded digest is gone")
+
+// Hostname of the containers of deterministic runs, the other containers get a random one.
+const deterministicHostname = "rce-sandbox"
+
+// Label of the images built by scripts/build_docker.sh naming the repository of the image, e.g. python_x86_64. It
+// stays with an image which lost its tag to a rebuilt image.
+const imageRepositoryLabel = "rce.repository"
+
+// imageIDPattern matches the IDs of local images, which are recorded as the image digest of deterministic runs.
+var imageIDPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// ValidImageDigest reports whether the digest is the ID of a local image, like the recorded image digests.
+func ValidImageDigest(digest string) bool {
+	return imageIDPattern.MatchString(digest)
+}
+
+// pinImage resolves the image of a deterministic run to its digest, the container is created from the digest so a
+// tag which is moved to a rebuilt image meanwhile doesn't change the run. A recorded digest is used as long as the
+// image still exists, even if the tag of the language was moved to a rebuilt image since.
+func (d *dockerClient) pinImage(ctx context.Context, code *Code) (string, error) {
+	expected := code.Deterministic.ImageDigest
+	if expected == "" {
+		digest, err := d.ImageDigest(ctx, code.Image)
+		if err != nil {
+			return "", fmt.Errorf("failed to pin the image: %w", classifyError(err))
+		}
+		return digest, nil
+	}
+
+	if !ValidImageDigest(expected) {
+		return "", fmt.Errorf("%w: %s is not the digest of an image", ErrImageChanged, expected)
+	}
+	inspect, _, err := d.client.ImageInspectWithRaw(ctx, expected)
+	if client.IsErrNotFound(err) {
+		return "", fmt.Errorf("%w: %s was removed", ErrImageChanged, expected)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to pin the image: %w", classifyError(err))
+	}
+	// Only images of the language may be used.
+	if !isImageOf(inspect, imageRepository(code.Image)) {
+		return "", fmt.Errorf("%w: %s is not an image of %s", ErrImageChanged, expected, code.Image)
+	}
+	return inspect.ID, nil
+}
+
+// isImageOf reports whether the image belongs to the repository. An image which lost its tag to a rebuilt image has
+// no tags left, it is still known by the digests it was pulled by or the label it was built with. Untagged images
+// with neither, e.g. of another repository, are rejected.
+func isImageOf(inspect types.ImageInspect, repository string) bool {
+	matches := func(reference string) bool {
+		return imageRepository(reference) == repository
+	}
+	if slices.ContainsFunc(inspect.RepoTags, matches) || slices.ContainsFunc(inspect.RepoDigests, matches) {
+		return true
+	}
+	return inspect.Config != nil && inspect.Config.Labels[imageRepositoryLabel] == repository
+}
+
+// imageRepository returns the repository of an image reference, e.g. python for python:3.12 or python@sha256:...
+func imageRepository(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// getDeterministicEnv returns the env of a deterministic run. The clock is faked by preloading libfaketime if the
+// time offset is set.
+func getDeterministicEnv(deterministic *Deterministic, timeOffset time.Duration, faketimeLibrary string) map[string]string {
+	seed := strconv.FormatInt(deterministic.Seed, 10)
+	env := map[string]string{
+		"LANG":   "C.UTF-8",
+		"LC_ALL": "C.UTF-8",
+		"TZ":     "UTC",
+		// Assigning RANDOM seeds it in sh and bash.
+		"RANDOM":         seed,
+		"RCE_SEED":       seed,
+		"PYTHONHASHSEED": seed,
+	}
+	if timeOffset != 0 {
+		env["LD_PRELOAD"] = faketimeLibrary
+		env["FAKETIME"] = fmt.Sprintf("%+d", int64(timeOffset/time.Second))
+	}
+	return env
+}
+
+// newEnvironment records the conditions of a deterministic run.
+func newEnvironment(code *Code, serverConfig *config.ServerConfig, imageDigest string, command, env []string, limits config.Limits) *Environment {
+	return &Environment{
+		ImageDigest: imageDigest,
+		Command:     command,
+		Env:         env,
+		Hostname:    deterministicHostname,
+		Seed:        code.Deterministic.Seed,
+		TimeOffset:  serverConfig.DeterministicTimeOffset,
+		Limits:      limits,
+	}
+}
@@ -0,0 +1,138 @@
+package codecontainer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"remote-code-engine/pkg/config"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+	"go.uber.org/zap"
+)
+
+func TestGetContainerEnvDeterministic(t *testing.T) {
+	serverConfig := config.DefaultServerConfig()
+	code := &Code{
+		Deterministic:  &Deterministic{Seed: 42},
+		LanguageConfig: config.LanguageConfig{Env: map[string]string{"TZ": "Europe/Berlin"}},
+	}
+
+	expected := []string{"LANG=C.UTF-8", "LC_ALL=C.UTF-8", "PYTHONHASHSEED=42", "RANDOM=42", "RCE_SEED=42", "TZ=Europe/Berlin"}
+	if env := getContainerEnv(code, &serverConfig, config.Limits{}); !slices.Equal(env, expected) {
+		t.Errorf("expected the fixed env with the env of the language, got %v", env)
+	}
+
+	serverConfig.DeterministicTimeOffset = -24 * time.Hour
+	env := getContainerEnv(code, &serverConfig, config.Limits{})
+	if !slices.Contains(env, "FAKETIME=-86400") || !slices.Contains(env, "LD_PRELOAD="+serverConfig.FaketimeLibrary) {
+		t.Errorf("expected the clock to be faked, got %v", env)
+	}
+
+	code.Deterministic = nil
+	if env := getContainerEnv(code, &serverConfig, config.Limits{}); !slices.Equal(env, []string{"TZ=Europe/Berlin"}) {
+		t.Errorf("expected the env of the language only, got %v", env)
+	}
+}
+
+func TestPinImage(t *testing.T) {
+	const (
+		digest    = "sha256:4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1"
+		oldDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		gccDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+		// Untagged images, a pulled one of python, one of gcc and one nobody knows the repository of.
+		pulledDigest    = "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+		untaggedDigest  = "sha256:4444444444444444444444444444444444444444444444444444444444444444"
+		anonymousDigest = "sha256:5555555555555555555555555555555555555555555555555555555555555555"
+	)
+	images := map[string]string{
+		"python:latest": fmt.Sprintf(`{"Id": %q, "RepoTags": ["python:latest"]}`, digest),
+		digest:          fmt.Sprintf(`{"Id": %q, "RepoTags": ["python:latest"]}`, digest),
+		// The image python:latest pointed to before it was rebuilt.
+		oldDigest:       fmt.Sprintf(`{"Id": %q, "RepoTags": [], "Config": {"Labels": {"rce.repository": "python"}}}`, oldDigest),
+		gccDigest:       fmt.Sprintf(`{"Id": %q, "RepoTags": ["gcc:latest"]}`, gccDigest),
+		pulledDigest:    fmt.Sprintf(`{"Id": %q, "RepoTags": [], "RepoDigests": ["python@sha256:6666"]}`, pulledDigest),
+		untaggedDigest:  fmt.Sprintf(`{"Id": %q, "RepoTags": [], "RepoDigests": ["gcc@sha256:7777"], "Config": {"Labels": {"rce.repository": "gcc"}}}`, untaggedDigest),
+		anonymousDigest: fmt.Sprintf(`{"Id": %q, "RepoTags": []}`, anonymousDigest),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := strings.CutSuffix(r.URL.Path[strings.Index(r.URL.Path, "/images/")+len("/images/"):], "/json")
+		if !ok || images[name] == "" {
+			http.Error(w, `{"message": "No such image"}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, images[name])
+	}))
+	defer server.Close()
+
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+server.Listener.Addr().String()), client.WithVersion("1.47"))
+	if err != nil {
+		t.Fatalf("failed to create the docker client: %v", err)
+	}
+	serverConfig := config.DefaultServerConfig()
+	d := &dockerClient{client: cli, config: &serverConfig, logger: zap.NewNop()}
+
+	tests := []struct {
+		name        string
+		image       string
+		recorded    string
+		expected    string
+		expectedErr error
+	}{
+		{name: "current image", image: "python:latest", expected: digest},
+		{name: "recorded digest", image: "python:latest", recorded: digest, expected: digest},
+		{name: "rebuilt image", image: "python:latest", recorded: oldDigest, expected: oldDigest},
+		{
+			name:        "removed image",
+			image:       "python:latest",
+			recorded:    "sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			expectedErr: ErrImageChanged,
+		},
+		{name: "image of another language", image: "python:latest", recorded: gccDigest, expectedErr: ErrImageChanged},
+		{name: "untagged pulled image", image: "python:latest", recorded: pulledDigest, expected: pulledDigest},
+		{name: "untagged image of another language", image: "python:latest", recorded: untaggedDigest, expectedErr: ErrImageChanged},
+		{name: "untagged image of an unknown repository", image: "python:latest", recorded: anonymousDigest, expectedErr: ErrImageChanged},
+		{name: "not a digest", image: "python:latest", recorded: "gcc:latest", expectedErr: ErrImageChanged},
+		{name: "missing image", image: "cobol:latest", expectedErr: ErrImageNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := &Code{
+				Deterministic:  &Deterministic{ImageDigest: tt.recorded},
+				LanguageConfig: config.LanguageConfig{Image: tt.image},
+			}
+			pinned, err := d.pinImage(context.Background(), code)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("expected %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil || pinned != tt.expected {
+				t.Errorf("expected the image to be pinned to %s, got %q and %v", tt.expected, pinned, err)
+			}
+		})
+	}
+}
+
+func TestImageRepository(t *testing.T) {
+	tests := map[string]string{
+		"python":                     "python",
+		"python:3.12":                "python",
+		"python@sha256:4bcff639":     "python",
+		"localhost:5000/rce/python":  "localhost:5000/rce/python",
+		"localhost:5000/rce/gcc:14":  "localhost:5000/rce/gcc",
+		"ghcr.io/rce/python:3.12-rc": "ghcr.io/rce/python",
+	}
+	for image, expected := range tests {
+		if repository := imageRepository(image); repository != expected {
+			t.Errorf("expected the repository of %s to be %s, got %s", image, expected, repository)
+		}
+	}
+}
@@ -3,10 +3,14 @@ package codecontainer
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
-	"path/filepath"
 	"remote-code-engine/pkg/config"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,13 +24,19 @@ import (
 	"go.uber.org/zap"
 )
 
+var (
+	ErrImageNotFound     = errors.New("the image of the language is missing")
+	ErrDaemonUnavailable = errors.New("the docker daemon is unavailable")
+)
+
 type dockerClient struct {
 	ContainerClient
 	client *client.Client
+	config *config.ServerConfig
 	logger *zap.Logger
 }
 
-func NewDockerClient(opts *client.Opt, logger *zap.Logger) (ContainerClient, error) {
+func NewDockerClient(opts *client.Opt, serverConfig *config.ServerConfig, logger *zap.Logger) (ContainerClient, error) {
 	var cli *client.Client
 	var err error
 
@@ -42,6 +52,7 @@ func NewDockerClient(opts *client.Opt, logger *zap.Logger) (ContainerClient, err
 
 	return &dockerClient{
 		client: cli,
+		config: serverConfig,
 		logger: logger,
 	}, nil
 }
@@ -66,62 +77,123 @@ func (d *dockerClient) GetContainers(ctx context.Context, opts *container.ListOp
 	return containersList, nil
 }
 
-func (d *dockerClient) getResourceConstraints() container.Resources {
-	if config.IsResourceConstraintsEnabled() {
-		return container.Resources{
-			Memory:   500 * 1024 * 1024, // 500 MB
-			NanoCPUs: 1000000000,        // 1 CPU
-			Ulimits: []*units.Ulimit{
-				{
-					Name: "nproc",
-					Soft: 64,
-					Hard: 128,
-				},
-				{
-					Name: "nofile",
-					Soft: 64,
-					Hard: 128,
-				},
-				{
-					Name: "core",
-					Soft: 0,
-					Hard: 0,
-				},
-				{
-					// Maximum file size that can be created by the process (output file in our case)
-					Name: "fsize",
-					Soft: 20 * 1024 * 1024,
-					Hard: 20 * 1024 * 1024,
-				},
-			},
+func (d *dockerClient) ImageExists(ctx context.Context, image string) (bool, error) {
+	_, _, err := d.client.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return false, nil
 		}
+		return false, fmt.Errorf("failed to inspect the image: %w", err)
 	}
-	return container.Resources{}
+	return true, nil
 }
 
-func (d *dockerClient) ExecuteCode(ctx context.Context, code *Code) (string, error) {
-	codeFileName, inputFileName, err := createCodeAndInputFilesHost(code, d.logger)
+func (d *dockerClient) ImageDigest(ctx context.Context, image string) (string, error) {
+	inspect, _, err := d.client.ImageInspectWithRaw(ctx, image)
 	if err != nil {
-		return "", fmt.Errorf("failed to create code and input files: %w", err)
+		return "", fmt.Errorf("failed to inspect the image: %w", err)
+	}
+	return inspect.ID, nil
+}
+
+// getResourceConstraints returns the constraints of the effective limits, see config.ServerConfig.EffectiveLimits.
+func (d *dockerClient) getResourceConstraints(limits config.Limits) container.Resources {
+	resources := container.Resources{}
+	if d.config.ResourceConstraints {
+		resources = getLimitConstraints(limits)
+	}
+	return resources
+}
+
+// getLimitConstraints returns the constraints applied if resource constraints are enabled.
+func getLimitConstraints(limits config.Limits) container.Resources {
+	return container.Resources{
+		Memory:   limits.MemoryMB * 1024 * 1024,
+		NanoCPUs: int64(limits.CPUs * 1e9),
+		Ulimits: []*units.Ulimit{
+			{
+				Name: "nproc",
+				Soft: min(64, limits.MaxProcesses),
+				Hard: limits.MaxProcesses,
+			},
+			{
+				Name: "nofile",
+				Soft: 64,
+				Hard: 128,
+			},
+			{
+				Name: "core",
+				Soft: 0,
+				Hard: 0,
+			},
+			{
+				// Maximum file size that can be created by the process (output file in our case)
+				Name: "fsize",
+				Soft: limits.MaxFileSizeMB * 1024 * 1024,
+				Hard: limits.MaxFileSizeMB * 1024 * 1024,
+			},
+		},
+	}
+}
+
+func (d *dockerClient) ExecuteCode(ctx context.Context, code *Code) (*Result, error) {
+	return d.StreamCode(ctx, code, io.Discard, io.Discard)
+}
+
+func (d *dockerClient) StreamCode(ctx context.Context, code *Code, stdout, stderr io.Writer) (*Result, error) {
+	submissionDir, codeFileName, inputFileName, err := createCodeAndInputFilesHost(d.config.GetHostLanguageCodePath(code.Language), code, d.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create code and input files: %w", err)
 	}
 	d.logger.Info("created code and input files",
+		zap.String("submission directory", submissionDir),
 		zap.String("code file name", codeFileName),
 		zap.String("input file name", inputFileName),
 	)
+	reportDir := submissionDir + reportDirSuffix
+	defer func() {
+		for _, dir := range []string{submissionDir, reportDir} {
+			if err := os.RemoveAll(dir); err != nil {
+				d.logger.Error("failed to remove the submission directory",
+					zap.String("submission directory", dir),
+					zap.Error(err),
+				)
+			}
+		}
+	}()
+	if err := os.Mkdir(reportDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create the report directory: %w", err)
+	}
 
-	resourceConstraints := d.getResourceConstraints()
+	limits := d.config.EffectiveLimits(code.Limits)
+	resourceConstraints := d.getResourceConstraints(limits)
+	maxOutputSize := d.config.MaxOutputSizeKB * 1024
+
+	command := getContainerCommand(code, d.config.TargetMountPath, codeFileName, inputFileName)
+	d.logger.Debug("command to be executed", zap.Strings("command", command))
+	env := getContainerEnv(code, d.config, limits)
+	image, hostname := code.Image, ""
+	var environment *Environment
+	if code.Deterministic != nil {
+		if image, err = d.pinImage(ctx, code); err != nil {
+			return nil, err
+		}
+		hostname = deterministicHostname
+		environment = newEnvironment(code, d.config, image, command, env, limits)
+	}
 
 	res, err := d.client.ContainerCreate(ctx, &container.Config{
-		Cmd:   getContainerCommand(code, codeFileName, inputFileName),
-		Image: code.Image,
+		Cmd:      command,
+		Image:    image,
+		Env:      env,
+		Hostname: hostname,
+		// Multi-file submissions refer to their files relative to the submission directory.
+		WorkingDir: d.config.TargetMountPath,
 	}, &container.HostConfig{
-		Mounts: []mount.Mount{
-			{
-				Type:   mount.TypeBind,
-				Source: config.GetHostLanguageCodePath(code.Language),
-				Target: TargetMountPath,
-			},
-		},
+		Mounts: getContainerMounts(code, submissionDir, reportDir, d.config.TargetMountPath),
+		// The command is the init process of the container, run-code.sh relies on it to kill every process the
+		// program left behind.
+		Init: new(bool),
 		// don't let the containers use any network
 		NetworkMode: "none",
 		RestartPolicy: container.RestartPolicy{
@@ -134,98 +206,215 @@ func (d *dockerClient) ExecuteCode(ctx context.Context, code *Code) (string, err
 		CapDrop:    []string{"ALL"},
 		Privileged: false,
 		Resources:  resourceConstraints,
+		LogConfig:  getLogConfig(maxOutputSize),
 	}, nil, nil, getContainerName())
 
 	if err != nil {
-		return "", fmt.Errorf("failed to create a container: %w", err)
+		return nil, fmt.Errorf("failed to create a container: %w", classifyError(err))
 	}
 
 	d.logger.Info("created the container, waiting for the container to start")
 	if err = d.client.ContainerStart(ctx, res.ID, container.StartOptions{}); err != nil {
-		return res.ID, fmt.Errorf("failed to start the container after creating: %w", err)
+		return nil, fmt.Errorf("failed to start the container after creating: %w", classifyError(err))
 	}
 
+	// Follow the logs from the start so the output is streamed, the stream ends once the container stops.
+	logs, err := d.client.ContainerLogs(ctx, res.ID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read container logs: %w", err)
+	}
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	var copyErr error
+	copied := make(chan struct{})
+	outputLimiter := newOutputLimiter(maxOutputSize)
+	go func() {
+		defer close(copied)
+		_, copyErr = stdcopy.StdCopy(outputLimiter.writer(&stdoutBuf, stdout), outputLimiter.writer(&stderrBuf, stderr), logs)
+	}()
+	// Nothing may be written to stdout and stderr after returning, closing the logs ends the copy
+	// if the container is still running.
+	defer func() {
+		_ = logs.Close()
+		<-copied
+	}()
+
+	stats := collectStats(ctx, d.client, res.ID)
+	defer stats.stop()
+
 	d.logger.Info("container started, waiting for the container to exit")
+	started := time.Now()
+	exitCode := int64(0)
 	statusCh, errCh := d.client.ContainerWait(ctx, res.ID, container.WaitConditionNotRunning)
-	ticker := time.NewTicker(MAX_EXECUTION_TIME)
+	timeLimit := limits.TimeLimit
+	ticker := time.NewTicker(timeLimit)
+	defer ticker.Stop()
 
 	select {
 	case <-ticker.C:
-		d.logger.Info("container has been running for more than 60 seconds, killing the container",
+		d.logger.Info("container exceeded the time limit, killing the container",
 			zap.String("container ID", res.ID),
+			zap.Duration("time limit", timeLimit),
 		)
-		if err := d.client.ContainerKill(ctx, res.ID, "KILL"); err != nil {
-			return "", fmt.Errorf("failed to kill the container: %w", err)
+		if err := d.killContainer(ctx, res.ID); err != nil {
+			return nil, err
+		}
+		return &Result{
+			Output:         "Time limit exceeded",
+			Verdict:        VerdictTimeLimitExceeded,
+			TimedOut:       true,
+			Duration:       time.Since(started),
+			ContainerUsage: stats.stop(),
+			Environment:    environment,
+		}, nil
+	case <-ctx.Done():
+		return d.cancelExecution(ctx, res.ID, started)
+	case <-outputLimiter.exceeded:
+		d.logger.Info("container exceeded the output limit, killing the container",
+			zap.String("container ID", res.ID),
+			zap.Int64("output limit", maxOutputSize),
+		)
+		if err := d.killContainer(ctx, res.ID); err != nil {
+			return nil, err
 		}
-		d.logger.Info("killed the container")
-		return "Time limit exceeded", nil
 	case err := <-errCh:
+		if ctx.Err() != nil {
+			return d.cancelExecution(ctx, res.ID, started)
+		}
 		if err != nil {
-			return "", fmt.Errorf("failed to get the container logs: %w", err)
+			return nil, fmt.Errorf("failed to get the container logs: %w", err)
 		}
 	case status := <-statusCh:
 		d.logger.Info("container exited", zap.Any("status", status))
+		exitCode = status.StatusCode
 	}
-
-	logs, err := d.client.ContainerLogs(ctx, res.ID, container.LogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-		Follow:     false,
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to read container logs: %w", err)
+	duration := time.Since(started)
+	containerUsage := stats.stop()
+	usage, benchmark := d.readRunFiles(reportDir, code, exitCode)
+	compilationFailed := readCompilationFailed(reportDir)
+
+	// The logs of a killed container end as well, closing them makes sure the copy ends.
+	if outputLimiter.Exceeded() {
+		_ = logs.Close()
 	}
-	var stdoutBuf, stderrBuf bytes.Buffer
-	_, err = stdcopy.StdCopy(&stdoutBuf, &stderrBuf, logs)
-	if err != nil {
-		return "", fmt.Errorf("error processing the logs: %w", err)
+	<-copied
+	if copyErr != nil && !outputLimiter.Exceeded() {
+		return nil, fmt.Errorf("error processing the logs: %w", copyErr)
 	}
 
 	output := stdoutBuf.String()
 	if stderrBuf.Len() > 0 {
 		output = output + "\nReceived error while executing the code: " + stderrBuf.String()
 	}
+	if outputLimiter.Exceeded() {
+		return &Result{
+			Output:          output,
+			Verdict:         VerdictOutputLimitExceeded,
+			Duration:        duration,
+			OutputTruncated: true,
+			Usage:           usage,
+			ContainerUsage:  containerUsage,
+			Environment:     environment,
+		}, nil
+	}
 
-	return output, nil
-}
+	maxArtifactSize := d.config.MaxArtifactSizeMB * 1024 * 1024
+	artifacts, truncated, err := collectArtifacts(submissionDir, code, maxArtifactSize, d.logger)
+	if err != nil {
+		return nil, err
+	}
 
-func deleteStaleFiles(dir string, logger *zap.Logger) error {
-	now := time.Now()
-	threshold := now.Add(-5 * time.Minute)
+	cpuTimeExceeded := limits.CPUTimeLimit > 0 && exitCode == cpuTimeExceededExitCode
+	return &Result{
+		Output:             output,
+		Verdict:            getVerdict(exitCode, compilationFailed, d.isOOMKilled(ctx, res.ID, exitCode), cpuTimeExceeded),
+		ExitCode:           int(exitCode),
+		Duration:           duration,
+		Artifacts:          artifacts,
+		ArtifactsTruncated: truncated,
+		TimedOut:           cpuTimeExceeded,
+		Usage:              usage,
+		ContainerUsage:     containerUsage,
+		Benchmark:          benchmark,
+		Environment:        environment,
+	}, nil
+}
 
-	files, err := os.ReadDir(dir)
+// isOOMKilled reports whether the kernel killed a process of the container because it ran out of memory.
+// The processes of a container which exited successfully are never checked.
+func (d *dockerClient) isOOMKilled(ctx context.Context, id string, exitCode int64) bool {
+	if exitCode == 0 {
+		return false
+	}
+	inspect, err := d.client.ContainerInspect(ctx, id)
 	if err != nil {
-		logger.Error("failed to read the directory", zap.String("directory name", dir))
-		return err
+		d.logger.Warn("failed to inspect the container",
+			zap.String("container ID", id),
+			zap.Error(err),
+		)
+		return false
 	}
+	return inspect.State != nil && inspect.State.OOMKilled
+}
 
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
+// readRunFiles reads the usage and the benchmark written by run-code.sh. A file which can't be read is logged, the
+// result just lacks the usage then.
+func (d *dockerClient) readRunFiles(reportDir string, code *Code, exitCode int64) (*ResourceUsage, *BenchmarkResult) {
+	usage, err := readUsage(reportDir)
+	if err != nil {
+		d.logger.Warn("failed to read the resource usage of the program",
+			zap.String("report directory", reportDir),
+			zap.Error(err),
+		)
+	}
+	if code.Benchmark == nil {
+		return usage, nil
+	}
 
-		fileInfo, err := file.Info()
-		if err != nil {
-			logger.Debug("failed to get file info of the file", zap.String("file name", file.Name()))
-			continue
-		}
-		modTime := fileInfo.ModTime()
+	runs, err := readBenchmark(reportDir, code.Benchmark.Runs)
+	if err != nil {
+		d.logger.Warn("failed to read the benchmark of the program",
+			zap.String("report directory", reportDir),
+			zap.Error(err),
+		)
+	}
+	// A benchmark stops at the first run which fails.
+	if exitCode != 0 || len(runs) != code.Benchmark.Runs {
+		return usage, nil
+	}
+	return usage, newBenchmarkResult(code.Benchmark, runs)
+}
 
-		if modTime.Before(threshold) {
-			filePath := filepath.Join(dir, file.Name())
-			err := os.Remove(filePath)
-			if err != nil {
-				logger.Error("error deleting file", zap.String("file name", filePath))
-			} else {
-				logger.Debug("deleted the file", zap.String("file name", filePath))
-			}
-		}
+// killContainer kills the container even if ctx is cancelled already.
+func (d *dockerClient) killContainer(ctx context.Context, id string) error {
+	if err := d.client.ContainerKill(context.WithoutCancel(ctx), id, "KILL"); err != nil {
+		return fmt.Errorf("failed to kill the container: %w", err)
 	}
+	d.logger.Info("killed the container")
 	return nil
 }
 
+// cancelExecution kills the container of a cancelled execution.
+func (d *dockerClient) cancelExecution(ctx context.Context, id string, started time.Time) (*Result, error) {
+	d.logger.Info("execution cancelled, killing the container",
+		zap.String("container ID", id),
+	)
+	if err := d.killContainer(ctx, id); err != nil {
+		return nil, err
+	}
+	return &Result{
+		Output:   "Execution cancelled",
+		Verdict:  VerdictCancelled,
+		Duration: time.Since(started),
+	}, nil
+}
+
 func (d *dockerClient) FreeUpZombieContainers(ctx context.Context) error {
-	ticker := time.NewTicker(GarbageCollectionTimeWindow)
+	ticker := time.NewTicker(d.config.GCInterval)
 	for {
 		select {
 		case <-ctx.Done():
@@ -243,35 +432,122 @@ func (d *dockerClient) FreeUpZombieContainers(ctx context.Context) error {
 				zap.Int("#Pruned containers", len(pruneResults.ContainersDeleted)),
 			)
 
-			if err = deleteStaleFiles(config.GetHostLanguageCodePath(config.Cpp), d.logger); err != nil {
-				d.logger.Error("failed to delete stale files",
-					zap.Error(err),
-				)
-			}
-
-			if err = deleteStaleFiles(config.GetHostLanguageCodePath(config.Golang), d.logger); err != nil {
-				d.logger.Error("failed to delete stale files",
-					zap.Error(err),
-				)
-			}
+			sweepCodeDirectories(d.config, d.logger)
 		}
 	}
 }
 
+// classifyError marks the docker errors the callers handle differently from other failures.
+func classifyError(err error) error {
+	switch {
+	case client.IsErrConnectionFailed(err):
+		return fmt.Errorf("%w: %w", ErrDaemonUnavailable, err)
+	case client.IsErrNotFound(err):
+		// Creating a container only fails with not found if the image is missing.
+		return fmt.Errorf("%w: %w", ErrImageNotFound, err)
+	default:
+		return err
+	}
+}
+
+// getVerdict classifies an execution by the exit code of the container, the bundled run-code.sh exits with the
+// status of the program and marks compilation failures in the report directory, the output of the program could
+// claim anything. Exceeding the CPU time limit fails even a successful program, running out of memory fails the
+// compilation as well.
+func getVerdict(exitCode int64, compilationFailed, oomKilled, cpuTimeExceeded bool) Verdict {
+	switch {
+	case cpuTimeExceeded:
+		return VerdictTimeLimitExceeded
+	case exitCode == 0:
+		return VerdictOK
+	case oomKilled:
+		return VerdictMemoryLimitExceeded
+	case compilationFailed:
+		return VerdictCompilationError
+	default:
+		return VerdictRuntimeError
+	}
+}
+
+// getLogConfig caps the logs the daemon keeps on disk, the output beyond the limit of each stream is dropped anyway.
+func getLogConfig(maxOutputSize int64) container.LogConfig {
+	return container.LogConfig{
+		Type: "json-file",
+		Config: map[string]string{
+			// Both streams go to the same file, JSON framing adds to the size of the output.
+			"max-size": strconv.FormatInt(4*maxOutputSize, 10),
+			"max-file": "1",
+		},
+	}
+}
+
 func getContainerName() string {
 	return fmt.Sprintf("code-execution-%s", uuid.New().String())
 }
 
-func getContainerCommand(code *Code, codeFileName, inputFileName string) []string {
-	codeFilePath := getFilePathContainer(TargetMountPath, codeFileName)
-	inputFilePath := getFilePathContainer(TargetMountPath, inputFileName)
+// getContainerMounts mounts the submission directory, the report directory and the dependency caches of the
+// language, the caches are shared by all the executions so they are mounted read-only.
+func getContainerMounts(code *Code, submissionDir, reportDir, mountPath string) []mount.Mount {
+	mounts := []mount.Mount{
+		{
+			Type:   mount.TypeBind,
+			Source: submissionDir,
+			Target: mountPath,
+		},
+		{
+			Type:   mount.TypeBind,
+			Source: reportDir,
+			Target: reportMountPath,
+		},
+	}
+	for _, cache := range code.Caches {
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   cache.Source,
+			Target:   cache.Target,
+			ReadOnly: true,
+		})
+	}
+	return mounts
+}
+
+// getContainerEnv returns the env of the language and tells run-code.sh how to run a benchmark and the CPU time limit
+// of the program. The env of a deterministic run is fixed, the env of the language takes precedence over it.
+func getContainerEnv(code *Code, serverConfig *config.ServerConfig, limits config.Limits) []string {
+	vars := map[string]string{}
+	if code.Deterministic != nil {
+		vars = getDeterministicEnv(code.Deterministic, serverConfig.DeterministicTimeOffset, serverConfig.FaketimeLibrary)
+	}
+	maps.Copy(vars, code.Env)
 
-	command := code.Command
-	command = strings.Replace(command, "{{LANGUAGE}}", string(code.Language), -1)
-	command = strings.Replace(command, "{{FILE}}", codeFilePath, -1)
-	command = strings.Replace(command, "{{INPUT}}", inputFilePath, -1)
+	env := []string{}
+	for _, name := range slices.Sorted(maps.Keys(vars)) {
+		env = append(env, name+"="+vars[name])
+	}
+	if code.Benchmark != nil {
+		env = append(env,
+			"RCE_BENCHMARK_RUNS="+strconv.Itoa(code.Benchmark.Runs),
+			"RCE_BENCHMARK_WARMUPS="+strconv.Itoa(code.Benchmark.Warmups),
+		)
+	}
+	if limits.CPUTimeLimit > 0 {
+		env = append(env, "RCE_CPU_TIME_LIMIT_MS="+strconv.FormatInt(limits.CPUTimeLimit.Milliseconds(), 10))
+	}
+	return env
+}
 
-	fmt.Println("Command to be executed:", command)
+func getContainerCommand(code *Code, mountPath, codeFileName, inputFileName string) []string {
+	codeFilePath := getFilePathContainer(mountPath, codeFileName)
+	inputFilePath := getFilePathContainer(mountPath, inputFileName)
+
+	command := code.GetCommand(code.IsProject())
+	command = strings.Replace(command, config.PlaceholderLanguage, string(code.Language), -1)
+	command = strings.Replace(command, config.PlaceholderFile, codeFilePath, -1)
+	command = strings.Replace(command, config.PlaceholderInput, inputFilePath, -1)
+	command = strings.Replace(command, config.PlaceholderVersion, code.Version, -1)
+	command = strings.Replace(command, config.PlaceholderFlags, code.Flags, -1)
+	command = strings.Replace(command, config.PlaceholderEntrypoint, codeFileName, -1)
+	command = strings.Replace(command, config.PlaceholderDir, mountPath, -1)
 
 	return []string{
 		"sh", "-c",
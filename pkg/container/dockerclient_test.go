@@ -1,8 +1,14 @@
 package codecontainer
 
 import (
+	"errors"
 	"remote-code-engine/pkg/config"
+	"slices"
 	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 )
 
 func TestGetContainerCommand(t *testing.T) {
@@ -45,11 +51,48 @@ func TestGetContainerCommand(t *testing.T) {
 				"g++ /container/code/main.cpp -o a.out && a.out < /container/code/input.txt",
 			},
 		},
+		{
+			name: "versioned cpp command",
+			code: &Code{
+				Language: "cpp",
+				Version:  "c++20",
+				LanguageConfig: config.LanguageConfig{
+					Extension: ".cpp",
+					Command:   "run.sh {{LANGUAGE}}:{{VERSION}} {{FILE}} {{INPUT}} '{{FLAGS}}'",
+					Flags:     "-std=c++20 -O2",
+				},
+			},
+			codeFileName:  "main.cpp",
+			inputFileName: "input.txt",
+			expectedCommand: []string{
+				"sh", "-c",
+				"run.sh cpp:c++20 /container/code/main.cpp /container/code/input.txt '-std=c++20 -O2'",
+			},
+		},
+		{
+			name: "project command",
+			code: &Code{
+				Language:   "cpp",
+				Files:      []File{{Path: "src/main.cpp"}, {Path: "src/util.h"}},
+				Entrypoint: "src/main.cpp",
+				LanguageConfig: config.LanguageConfig{
+					Extension:      ".cpp",
+					Command:        "run.sh {{LANGUAGE}} {{FILE}} {{INPUT}}",
+					ProjectCommand: "run.sh {{LANGUAGE}} {{DIR}}/{{ENTRYPOINT}} {{INPUT}}",
+				},
+			},
+			codeFileName:  "src/main.cpp",
+			inputFileName: "input.txt",
+			expectedCommand: []string{
+				"sh", "-c",
+				"run.sh cpp /container/code/src/main.cpp /container/code/input.txt",
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			command := getContainerCommand(tt.code, tt.codeFileName, tt.inputFileName)
+			command := getContainerCommand(tt.code, "/container/code", tt.codeFileName, tt.inputFileName)
 			if len(command) != len(tt.expectedCommand) {
 				t.Errorf("expected command length %d, got %d", len(tt.expectedCommand), len(command))
 			}
@@ -61,3 +104,147 @@ func TestGetContainerCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestGetResourceConstraints(t *testing.T) {
+	serverConfig := config.DefaultServerConfig()
+	d := &dockerClient{config: &serverConfig}
+
+	limits := config.Limits{MemoryMB: 100, CPUs: 0.5, MaxProcesses: 32, MaxFileSizeMB: 1}
+	if resources := d.getResourceConstraints(limits); resources.Memory != 0 || len(resources.Ulimits) != 0 {
+		t.Errorf("expected no constraints without resource constraints, got %+v", resources)
+	}
+
+	serverConfig.ResourceConstraints = true
+	resources := d.getResourceConstraints(limits)
+	if resources.Memory != 100*1024*1024 || resources.NanoCPUs != 5e8 {
+		t.Errorf("expected the limits, got memory %d and nano CPUs %d", resources.Memory, resources.NanoCPUs)
+	}
+	for _, ulimit := range resources.Ulimits {
+		if ulimit.Name == "nproc" && (ulimit.Soft != 32 || ulimit.Hard != 32) {
+			t.Errorf("expected the process limit to be 32, got soft %d and hard %d", ulimit.Soft, ulimit.Hard)
+		}
+		if ulimit.Name == "fsize" && ulimit.Hard != 1024*1024 {
+			t.Errorf("expected the file size limit to be 1 MB, got %d", ulimit.Hard)
+		}
+	}
+}
+
+func TestGetResourceConstraintsCPUTimeLimit(t *testing.T) {
+	serverConfig := config.DefaultServerConfig()
+	d := &dockerClient{config: &serverConfig}
+
+	// The CPU time limit is set by run-code.sh for the program only, the container isn't limited.
+	resources := d.getResourceConstraints(config.Limits{CPUTimeLimit: 1500 * time.Millisecond})
+	if len(resources.Ulimits) != 0 {
+		t.Errorf("expected no ulimits, got %+v", resources.Ulimits)
+	}
+
+	env := getContainerEnv(&Code{}, &serverConfig, config.Limits{CPUTimeLimit: 1500 * time.Millisecond})
+	if !slices.Equal(env, []string{"RCE_CPU_TIME_LIMIT_MS=1500"}) {
+		t.Errorf("expected the CPU time limit to be passed to the command, got %v", env)
+	}
+}
+
+func TestGetContainerMountsAndEnv(t *testing.T) {
+	code := &Code{
+		LanguageConfig: config.LanguageConfig{
+			Caches: []config.Cache{{Source: "/var/lib/rce/go", Target: "/go/pkg/mod"}},
+			Env:    map[string]string{"GOPROXY": "off", "GOFLAGS": "-mod=mod"},
+		},
+	}
+
+	mounts := getContainerMounts(code, "/tmp/golang/submission", "/tmp/golang/submission.report", "/container/code")
+	if len(mounts) != 3 {
+		t.Fatalf("expected 3 mounts, got %d", len(mounts))
+	}
+	if mounts[0].Source != "/tmp/golang/submission" || mounts[0].Target != "/container/code" || mounts[0].ReadOnly {
+		t.Errorf("expected a writable mount of the submission directory, got %+v", mounts[0])
+	}
+	if mounts[1].Source != "/tmp/golang/submission.report" || mounts[1].Target != reportMountPath || mounts[1].ReadOnly {
+		t.Errorf("expected a writable mount of the report directory, got %+v", mounts[1])
+	}
+	if mounts[2].Source != "/var/lib/rce/go" || mounts[2].Target != "/go/pkg/mod" || !mounts[2].ReadOnly {
+		t.Errorf("expected a read-only mount of the cache, got %+v", mounts[2])
+	}
+
+	serverConfig := config.DefaultServerConfig()
+	env := getContainerEnv(code, &serverConfig, config.Limits{})
+	expectedEnv := []string{"GOFLAGS=-mod=mod", "GOPROXY=off"}
+	if len(env) != len(expectedEnv) {
+		t.Fatalf("expected env %v, got %v", expectedEnv, env)
+	}
+	for i := range env {
+		if env[i] != expectedEnv[i] {
+			t.Errorf("expected env %v, got %v", expectedEnv, env)
+		}
+	}
+
+	code.Benchmark = &Benchmark{Runs: 10, Warmups: 2}
+	env = getContainerEnv(code, &serverConfig, config.Limits{})
+	if !slices.Equal(env[len(expectedEnv):], []string{"RCE_BENCHMARK_RUNS=10", "RCE_BENCHMARK_WARMUPS=2"}) {
+		t.Errorf("expected the benchmark to be passed to the command, got %v", env)
+	}
+}
+
+func TestGetVerdict(t *testing.T) {
+	tests := []struct {
+		name              string
+		exitCode          int64
+		compilationFailed bool
+		oomKilled         bool
+		cpuTimeExceeded   bool
+		expected          Verdict
+	}{
+		{name: "success", exitCode: 0, expected: VerdictOK},
+		{name: "compilation error", exitCode: 1, compilationFailed: true, expected: VerdictCompilationError},
+		{name: "runtime error", exitCode: 139, expected: VerdictRuntimeError},
+		{name: "out of memory", exitCode: 137, oomKilled: true, expected: VerdictMemoryLimitExceeded},
+		{name: "compiler out of memory", exitCode: 1, compilationFailed: true, oomKilled: true, expected: VerdictMemoryLimitExceeded},
+		{name: "cpu time limit exceeded", exitCode: 152, cpuTimeExceeded: true, expected: VerdictTimeLimitExceeded},
+		{name: "successful program over the cpu time limit", exitCode: 0, cpuTimeExceeded: true, expected: VerdictTimeLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if verdict := getVerdict(tt.exitCode, tt.compilationFailed, tt.oomKilled, tt.cpuTimeExceeded); verdict != tt.expected {
+				t.Errorf("expected verdict %s, got %s", tt.expected, verdict)
+			}
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		expectedErr error
+	}{
+		{name: "daemon down", err: client.ErrorConnectionFailed("unix:///var/run/docker.sock"), expectedErr: ErrDaemonUnavailable},
+		{name: "image missing", err: errdefs.NotFound(errors.New("No such image: gcc:latest")), expectedErr: ErrImageNotFound},
+		{name: "other failure", err: errors.New("conflict")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyError(tt.err)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected the error to wrap %v, got %v", tt.err, err)
+			}
+			for _, sentinel := range []error{ErrDaemonUnavailable, ErrImageNotFound} {
+				if errors.Is(err, sentinel) != (sentinel == tt.expectedErr) {
+					t.Errorf("unexpected classification of %v as %v", err, sentinel)
+				}
+			}
+		})
+	}
+}
+
+func TestGetLogConfig(t *testing.T) {
+	logConfig := getLogConfig(1024 * 1024)
+	if logConfig.Type != "json-file" {
+		t.Errorf("expected the json-file driver, got %q", logConfig.Type)
+	}
+	if logConfig.Config["max-size"] != "4194304" || logConfig.Config["max-file"] != "1" {
+		t.Errorf("expected the logs to be capped at 4 MB in a single file, got %v", logConfig.Config)
+	}
+}
@@ -0,0 +1,88 @@
+package codecontainer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Encoding of the code, the input and the file contents of a submission.
+type Encoding string
+
+const (
+	EncodingBase64 Encoding = "base64"
+	// The content as is, e.g. a raw JSON string.
+	EncodingUTF8 Encoding = "utf8"
+	// URL-safe base64, the padding is optional.
+	EncodingBase64URL Encoding = "base64url"
+)
+
+var ErrInvalidEncoding = errors.New("invalid encoding")
+
+// normalizeEncoding converts the code, the input and the file contents to standard base64, which is what
+// the staging and the result cache work with. Content which doesn't decode is rejected before staging.
+func (c *Code) normalizeEncoding() error {
+	switch c.Encoding {
+	case "", EncodingBase64, EncodingUTF8, EncodingBase64URL:
+	default:
+		return fmt.Errorf("%w %q: use %s, %s or %s", ErrInvalidEncoding, c.Encoding, EncodingBase64, EncodingUTF8, EncodingBase64URL)
+	}
+
+	normalize := func(name string, content *string) error {
+		normalized, err := normalizeContent(*content, c.Encoding)
+		if err != nil {
+			return fmt.Errorf("%w: the %s is not valid %s: %v", ErrInvalidEncoding, name, c.getEncoding(), err)
+		}
+		*content = normalized
+		return nil
+	}
+
+	if c.IsProject() {
+		for i := range c.Files {
+			if c.Files[i].Content != nil {
+				continue
+			}
+			if err := normalize("content of "+c.Files[i].Path, &c.Files[i].EncodedContent); err != nil {
+				return err
+			}
+		}
+	} else if err := normalize("code", &c.EncodedCode); err != nil {
+		return err
+	}
+	if c.InputPath == "" {
+		if err := normalize("input", &c.EncodedInput); err != nil {
+			return err
+		}
+	}
+
+	c.Encoding = EncodingBase64
+	return nil
+}
+
+func (c *Code) getEncoding() Encoding {
+	if c.Encoding == "" {
+		return EncodingBase64
+	}
+	return c.Encoding
+}
+
+func normalizeContent(content string, encoding Encoding) (string, error) {
+	switch encoding {
+	case EncodingUTF8:
+		return base64.StdEncoding.EncodeToString([]byte(content)), nil
+	case EncodingBase64URL:
+		data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(content, "="))
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(data), nil
+	default:
+		// Check the content without holding the decoded copy in memory.
+		if _, err := io.Copy(io.Discard, base64.NewDecoder(base64.StdEncoding, strings.NewReader(content))); err != nil {
+			return "", err
+		}
+		return content, nil
+	}
+}
@@ -0,0 +1,114 @@
+package codecontainer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeEncoding(t *testing.T) {
+	tests := []struct {
+		name          string
+		encoding      Encoding
+		code          string
+		input         string
+		files         []File
+		expectedCode  string
+		expectedInput string
+		expectedFiles []File
+		expectedErr   error
+	}{
+		{
+			name:          "base64 by default",
+			code:          "cHJpbnQoMSk=",
+			input:         "aGk=",
+			expectedCode:  "cHJpbnQoMSk=",
+			expectedInput: "aGk=",
+		},
+		{
+			name:          "utf8",
+			encoding:      EncodingUTF8,
+			code:          "print(1)",
+			input:         "hi",
+			expectedCode:  "cHJpbnQoMSk=",
+			expectedInput: "aGk=",
+		},
+		{
+			name:          "base64url without padding",
+			encoding:      EncodingBase64URL,
+			code:          "Pz8_",
+			input:         "aGk",
+			expectedCode:  "Pz8/",
+			expectedInput: "aGk=",
+		},
+		{
+			name:          "utf8 files",
+			encoding:      EncodingUTF8,
+			files:         []File{{Path: "main.py", EncodedContent: "print(1)"}},
+			expectedFiles: []File{{Path: "main.py", EncodedContent: "cHJpbnQoMSk="}},
+		},
+		{
+			name:          "raw files are kept",
+			encoding:      EncodingUTF8,
+			files:         []File{{Path: "main.py", Content: []byte("print(1)")}},
+			expectedFiles: []File{{Path: "main.py", Content: []byte("print(1)")}},
+		},
+		{
+			name:        "invalid base64 code",
+			code:        "print(1)",
+			expectedErr: ErrInvalidEncoding,
+		},
+		{
+			name:        "invalid base64 input",
+			code:        "cHJpbnQoMSk=",
+			input:       "hi!",
+			expectedErr: ErrInvalidEncoding,
+		},
+		{
+			name:        "url-safe alphabet is not standard base64",
+			code:        "Pz8_",
+			expectedErr: ErrInvalidEncoding,
+		},
+		{
+			name:        "invalid file content",
+			encoding:    EncodingBase64URL,
+			files:       []File{{Path: "main.py", EncodedContent: "a+b/"}},
+			expectedErr: ErrInvalidEncoding,
+		},
+		{
+			name:        "unknown encoding",
+			encoding:    "hex",
+			expectedErr: ErrInvalidEncoding,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := &Code{
+				EncodedCode:  tt.code,
+				EncodedInput: tt.input,
+				Files:        tt.files,
+				Encoding:     tt.encoding,
+			}
+
+			err := code.normalizeEncoding()
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if code.EncodedCode != tt.expectedCode || code.EncodedInput != tt.expectedInput {
+				t.Errorf("expected code %q and input %q, got %q and %q", tt.expectedCode, tt.expectedInput, code.EncodedCode, code.EncodedInput)
+			}
+			for i, file := range tt.expectedFiles {
+				if !reflect.DeepEqual(code.Files[i], file) {
+					t.Errorf("expected file %+v, got %+v", file, code.Files[i])
+				}
+			}
+			if code.Encoding != EncodingBase64 {
+				t.Errorf("expected the encoding to be base64 after normalizing, got %q", code.Encoding)
+			}
+		})
+	}
+}
@@ -2,15 +2,35 @@ package codecontainer
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
-	"remote-code-engine/pkg/config"
+	"regexp"
+	"strings"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+const (
+	// The input is staged next to the code, so no file of a submission can use its name.
+	inputFileName = "input.txt"
+
+	MaxSubmissionFiles = 256
+	maxPathLength      = 255
+)
+
+var (
+	ErrInvalidFilePath   = errors.New("invalid file path")
+	ErrInvalidEntrypoint = errors.New("invalid entrypoint")
+)
+
+// The paths are substituted into the command unquoted, so they are limited to characters the shell takes literally.
+var filePathPattern = regexp.MustCompile(`^[A-Za-z0-9._/-]+$`)
+
 func getFilePathContainer(mountPath, fileName string) string {
 	return filepath.Join(mountPath, fileName)
 }
@@ -19,14 +39,132 @@ func getFilePathHost(hostCodeDirectoryPath, fileName string) string {
 	return filepath.Join(hostCodeDirectoryPath, fileName)
 }
 
-func getCodeAndInputFilePathsHost(code *Code) (string, string) {
-	filename := uuid.New().String()
-	codeDirectoryPathHost := config.GetHostLanguageCodePath(code.Language)
-	codeFileName := filename + code.Extension
-	inputFileName := filename + ".txt"
+// cleanRelativePath returns the cleaned path if it stays inside the submission directory.
+func cleanRelativePath(filePath string) (string, error) {
+	switch {
+	case filePath == "":
+		return "", fmt.Errorf("%w: the path is empty", ErrInvalidFilePath)
+	case len(filePath) > maxPathLength:
+		return "", fmt.Errorf("%w %q: the path is longer than %d characters", ErrInvalidFilePath, filePath, maxPathLength)
+	case strings.ContainsAny(filePath, "\\\x00"):
+		return "", fmt.Errorf("%w %q: use forward slashes only", ErrInvalidFilePath, filePath)
+	case path.IsAbs(filePath):
+		return "", fmt.Errorf("%w %q: the path has to be relative", ErrInvalidFilePath, filePath)
+	}
+
+	cleaned := path.Clean(filePath)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("%w %q: the path has to stay inside the submission directory", ErrInvalidFilePath, filePath)
+	}
+	if cleaned == inputFileName {
+		return "", fmt.Errorf("%w %q: %s is reserved for the input", ErrInvalidFilePath, filePath, inputFileName)
+	}
+	return cleaned, nil
+}
+
+// cleanFilePath cleans the path of a submitted file, which can end up in the command of the language.
+func cleanFilePath(filePath string) (string, error) {
+	cleaned, err := cleanRelativePath(filePath)
+	if err != nil {
+		return "", err
+	}
+	if !filePathPattern.MatchString(cleaned) {
+		return "", fmt.Errorf("%w %q: use letters, digits, '.', '_', '-' and '/' only", ErrInvalidFilePath, filePath)
+	}
+	// The entrypoint is passed to the commands relative to the submission directory, it can't look like an option.
+	if strings.HasPrefix(cleaned, "-") || strings.Contains(cleaned, "/-") {
+		return "", fmt.Errorf("%w %q: a file or directory name can't start with '-'", ErrInvalidFilePath, filePath)
+	}
+	return cleaned, nil
+}
+
+// Validate checks the artifact patterns as well as the files and the entry point of a multi-file
+// submission and fills in the default entry point.
+func (c *Code) Validate() error {
+	if err := validateArtifactPatterns(c.Artifacts); err != nil {
+		return err
+	}
+	if err := c.normalizeEncoding(); err != nil {
+		return err
+	}
+	if !c.IsProject() {
+		return nil
+	}
+	if len(c.Files) > MaxSubmissionFiles {
+		return fmt.Errorf("too many files: %d, at most %d files can be submitted", len(c.Files), MaxSubmissionFiles)
+	}
+
+	paths := map[string]bool{}
+	for i := range c.Files {
+		cleaned, err := cleanFilePath(c.Files[i].Path)
+		if err != nil {
+			return err
+		}
+		if paths[cleaned] {
+			return fmt.Errorf("%w %q: the file is submitted more than once", ErrInvalidFilePath, c.Files[i].Path)
+		}
+		paths[cleaned] = true
+		c.Files[i].Path = cleaned
+	}
+	// A file can't be used as the directory of another one.
+	for filePath := range paths {
+		for dir := path.Dir(filePath); dir != "."; dir = path.Dir(dir) {
+			if paths[dir] {
+				return fmt.Errorf("%w %q: %s is a file", ErrInvalidFilePath, filePath, dir)
+			}
+		}
+	}
+
+	if c.Entrypoint == "" {
+		entrypoint, err := defaultEntrypoint(paths, c.Extension)
+		if err != nil {
+			return err
+		}
+		c.Entrypoint = entrypoint
+		return nil
+	}
+
+	entrypoint, err := cleanFilePath(c.Entrypoint)
+	if err != nil || !paths[entrypoint] {
+		return fmt.Errorf("%w %q: the entrypoint has to be one of the submitted files", ErrInvalidEntrypoint, c.Entrypoint)
+	}
+	c.Entrypoint = entrypoint
+	return nil
+}
+
+// defaultEntrypoint picks main<extension>, or the only file with the extension of the language.
+func defaultEntrypoint(paths map[string]bool, extension string) (string, error) {
+	if paths["main"+extension] {
+		return "main" + extension, nil
+	}
+
+	var candidates []string
+	for filePath := range paths {
+		if path.Ext(filePath) == extension {
+			candidates = append(candidates, filePath)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	return "", fmt.Errorf("%w: the entrypoint is required when there is no main%s and not exactly one %s file",
+		ErrInvalidEntrypoint, extension, extension)
+}
+
+func getEntrypoint(code *Code) string {
+	if code.IsProject() {
+		return code.Entrypoint
+	}
+	return "main" + code.Extension
+}
+
+// Every submission is staged in its own directory so it can be removed as a whole once the execution is done.
+func getSubmissionPathsHost(hostCodeDirectoryPath string, code *Code) (string, string, string) {
+	submissionDirectoryPathHost := getFilePathHost(hostCodeDirectoryPath, uuid.New().String())
 
-	return getFilePathHost(codeDirectoryPathHost, codeFileName),
-		getFilePathHost(codeDirectoryPathHost, inputFileName)
+	return submissionDirectoryPathHost,
+		getFilePathHost(submissionDirectoryPathHost, filepath.FromSlash(getEntrypoint(code))),
+		getFilePathHost(submissionDirectoryPathHost, inputFileName)
 }
 
 func createFile(filePath, base64FileContent string, logger *zap.Logger) (string, error) {
@@ -34,35 +172,110 @@ func createFile(filePath, base64FileContent string, logger *zap.Logger) (string,
 	if err != nil {
 		return "", fmt.Errorf("failed to create the file: %w", err)
 	}
+	defer func() {
+		_ = f.Close()
+	}()
 
-	data, err := base64.StdEncoding.DecodeString(base64FileContent)
+	// Decode while writing, so large contents aren't held in memory twice.
+	n, err := io.Copy(f, base64.NewDecoder(base64.StdEncoding, strings.NewReader(base64FileContent)))
 	if err != nil {
-		return filepath.Base(filePath), fmt.Errorf("failed to decode the file content: %w", err)
+		return filepath.Base(filePath), fmt.Errorf("failed to write the content to the file: %w", err)
 	}
+	logger.Info("wrote the file content to the file",
+		zap.String("file path", filePath),
+		zap.Int64("bytes", n),
+	)
 
-	n, err := f.Write(data)
-	if err != nil {
-		return filepath.Base(filePath), fmt.Errorf("failed to write the content to the file: %w", err)
+	return filepath.Base(filePath), nil
+}
+
+// writeFile stages a file which wasn't sent encoded, e.g. a file extracted from an archive.
+func writeFile(filePath string, content []byte, logger *zap.Logger) error {
+	if err := os.WriteFile(filePath, content, 0666); err != nil {
+		return fmt.Errorf("failed to write the content to the file: %w", err)
 	}
 	logger.Info("wrote the file content to the file",
 		zap.String("file path", filePath),
-		zap.Int("bytes", n),
+		zap.Int("bytes", len(content)),
 	)
+	return nil
+}
 
+// copyFile stages a pre-uploaded file. It is copied rather than linked, so a program writing to its input
+// can't change the upload other submissions use.
+func copyFile(sourcePath, filePath string) (string, error) {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the file: %w", err)
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+
+	f, err := os.Create(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	if _, err := io.Copy(f, source); err != nil {
+		return filepath.Base(filePath), fmt.Errorf("failed to copy the file: %w", err)
+	}
 	return filepath.Base(filePath), nil
 }
 
-func createCodeAndInputFilesHost(code *Code, logger *zap.Logger) (string, string, error) {
-	codeFilePath, inputFilePath := getCodeAndInputFilePathsHost(code)
-	codeFileName, err := createFile(codeFilePath, code.EncodedCode, logger)
+// createProjectFiles stages the files of a multi-file submission, the paths have to be validated before.
+func createProjectFiles(submissionDir string, files []File, logger *zap.Logger) error {
+	for _, file := range files {
+		filePath := getFilePathHost(submissionDir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return fmt.Errorf("failed to create the directory of %s: %w", file.Path, err)
+		}
+		var err error
+		if file.Content != nil {
+			err = writeFile(filePath, file.Content, logger)
+		} else {
+			_, err = createFile(filePath, file.EncodedContent, logger)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", file.Path, err)
+		}
+	}
+	return nil
+}
+
+// createCodeAndInputFilesHost stages the code and the input in a new submission directory inside the
+// code directory of the language and returns the directory along with the entry point and the input
+// file name inside it.
+func createCodeAndInputFilesHost(hostCodeDirectoryPath string, code *Code, logger *zap.Logger) (string, string, string, error) {
+	submissionDir, codeFilePath, inputFilePath := getSubmissionPathsHost(hostCodeDirectoryPath, code)
+	if err := os.MkdirAll(submissionDir, 0755); err != nil {
+		return "", "", "", fmt.Errorf("failed to create the submission directory: %w", err)
+	}
+
+	var err error
+	if code.IsProject() {
+		err = createProjectFiles(submissionDir, code.Files, logger)
+	} else {
+		_, err = createFile(codeFilePath, code.EncodedCode, logger)
+	}
 	if err != nil {
-		return "", "", fmt.Errorf("failed to create the code file: %w", err)
+		_ = os.RemoveAll(submissionDir)
+		return "", "", "", fmt.Errorf("failed to create the code file: %w", err)
 	}
 
-	inputFileName, err := createFile(inputFilePath, code.EncodedInput, logger)
+	var inputFileName string
+	if code.InputPath != "" {
+		inputFileName, err = copyFile(code.InputPath, inputFilePath)
+	} else {
+		inputFileName, err = createFile(inputFilePath, code.EncodedInput, logger)
+	}
 	if err != nil {
-		return "", "", fmt.Errorf("failed to create the input file: %w", err)
+		_ = os.RemoveAll(submissionDir)
+		return "", "", "", fmt.Errorf("failed to create the input file: %w", err)
 	}
 
-	return codeFileName, inputFileName, nil
+	return submissionDir, getEntrypoint(code), inputFileName, nil
 }
@@ -2,6 +2,7 @@ package codecontainer
 
 import (
 	"encoding/base64"
+	"errors"
 	"os"
 	"path/filepath"
 	"remote-code-engine/pkg/config"
@@ -58,7 +59,7 @@ func TestCreateFileInvalidBase64(t *testing.T) {
 		t.Fatal("expected an error due to invalid base64 content, but got none")
 	}
 }
-func TestGetCodeAndInputFilePathsHost(t *testing.T) {
+func TestGetSubmissionPathsHost(t *testing.T) {
 	code := &Code{
 		Language: "golang",
 		LanguageConfig: config.LanguageConfig{
@@ -66,11 +67,15 @@ func TestGetCodeAndInputFilePathsHost(t *testing.T) {
 		},
 	}
 
-	codeFilePath, inputFilePath := getCodeAndInputFilePathsHost(code)
+	expectedCodeDir := "/tmp/golang"
+	submissionDir, codeFilePath, inputFilePath := getSubmissionPathsHost(expectedCodeDir, code)
 
-	expectedCodeDir := config.GetHostLanguageCodePath(code.Language)
-	expectedCodeFilePath := filepath.Join(expectedCodeDir, filepath.Base(codeFilePath))
-	expectedInputFilePath := filepath.Join(expectedCodeDir, filepath.Base(inputFilePath))
+	if filepath.Dir(submissionDir) != expectedCodeDir {
+		t.Errorf("expected submission directory inside '%s', got '%s'", expectedCodeDir, submissionDir)
+	}
+
+	expectedCodeFilePath := filepath.Join(submissionDir, "main.go")
+	expectedInputFilePath := filepath.Join(submissionDir, "input.txt")
 
 	if codeFilePath != expectedCodeFilePath {
 		t.Errorf("expected code file path '%s', got '%s'", expectedCodeFilePath, codeFilePath)
@@ -79,6 +84,76 @@ func TestGetCodeAndInputFilePathsHost(t *testing.T) {
 	if inputFilePath != expectedInputFilePath {
 		t.Errorf("expected input file path '%s', got '%s'", expectedInputFilePath, inputFilePath)
 	}
+
+	otherSubmissionDir, _, _ := getSubmissionPathsHost(expectedCodeDir, code)
+	if otherSubmissionDir == submissionDir {
+		t.Errorf("expected a new submission directory for every submission, got '%s' twice", submissionDir)
+	}
+}
+
+func TestCreateCodeAndInputFilesHost(t *testing.T) {
+	logger := zap.NewNop()
+	codeDir := t.TempDir()
+
+	code := &Code{
+		EncodedCode:  base64.StdEncoding.EncodeToString([]byte("package main")),
+		EncodedInput: base64.StdEncoding.EncodeToString([]byte("input")),
+		Language:     "golang",
+		LanguageConfig: config.LanguageConfig{
+			Extension: ".go",
+		},
+	}
+
+	submissionDir, codeFileName, inputFileName, err := createCodeAndInputFilesHost(codeDir, code, logger)
+	if err != nil {
+		t.Fatalf("failed to create the code and input files: %v", err)
+	}
+
+	for _, fileName := range []string{codeFileName, inputFileName} {
+		if _, err := os.Stat(filepath.Join(submissionDir, fileName)); err != nil {
+			t.Errorf("expected '%s' in the submission directory: %v", fileName, err)
+		}
+	}
+
+	code.EncodedInput = "invalid_base64_content"
+	submissionDir, _, _, err = createCodeAndInputFilesHost(codeDir, code, logger)
+	if err == nil {
+		t.Fatal("expected an error due to invalid base64 input, but got none")
+	}
+
+	entries, err := os.ReadDir(codeDir)
+	if err != nil {
+		t.Fatalf("failed to read the code directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected the failed submission directory '%s' to be removed, got %d entries", submissionDir, len(entries))
+	}
+}
+
+func TestCreateCodeAndInputFilesHostUploadedInput(t *testing.T) {
+	uploaded := filepath.Join(t.TempDir(), "input")
+	if err := os.WriteFile(uploaded, []byte("1 2 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code := &Code{
+		EncodedCode: base64.StdEncoding.EncodeToString([]byte("package main")),
+		InputID:     "input",
+		InputPath:   uploaded,
+		Language:    "golang",
+		LanguageConfig: config.LanguageConfig{
+			Extension: ".go",
+		},
+	}
+
+	submissionDir, _, inputFileName, err := createCodeAndInputFilesHost(t.TempDir(), code, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to create the code and input files: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(submissionDir, inputFileName))
+	if err != nil || string(data) != "1 2 3\n" {
+		t.Errorf("expected the uploaded input to be staged, got %q: %v", data, err)
+	}
 }
 
 func TestGetFilePathHost(t *testing.T) {
@@ -104,3 +179,160 @@ func TestGetFilePathContainer(t *testing.T) {
 		t.Errorf("expected file path '%s', got '%s'", expectedFilePath, result)
 	}
 }
+
+func TestCodeValidate(t *testing.T) {
+	tests := []struct {
+		name               string
+		files              []string
+		entrypoint         string
+		expectedEntrypoint string
+		expectedErr        error
+	}{
+		{
+			name:               "single file submission",
+			expectedEntrypoint: "",
+		},
+		{
+			name:               "default entrypoint",
+			files:              []string{"main.go", "util/util.go"},
+			expectedEntrypoint: "main.go",
+		},
+		{
+			name:               "only file with the extension",
+			files:              []string{"cmd/app.go", "go.mod"},
+			expectedEntrypoint: "cmd/app.go",
+		},
+		{
+			name:               "explicit entrypoint is cleaned",
+			files:              []string{"./cmd/app.go", "util.go"},
+			entrypoint:         "cmd//app.go",
+			expectedEntrypoint: "cmd/app.go",
+		},
+		{
+			name:        "ambiguous entrypoint",
+			files:       []string{"a.go", "b.go"},
+			expectedErr: ErrInvalidEntrypoint,
+		},
+		{
+			name:        "entrypoint not submitted",
+			files:       []string{"main.go"},
+			entrypoint:  "other.go",
+			expectedErr: ErrInvalidEntrypoint,
+		},
+		{
+			name:        "absolute path",
+			files:       []string{"/etc/passwd"},
+			expectedErr: ErrInvalidFilePath,
+		},
+		{
+			name:        "path escaping the submission directory",
+			files:       []string{"main.go", "util/../../escape.go"},
+			expectedErr: ErrInvalidFilePath,
+		},
+		{
+			name:        "duplicate path",
+			files:       []string{"main.go", "./main.go"},
+			expectedErr: ErrInvalidFilePath,
+		},
+		{
+			name:        "reserved input file",
+			files:       []string{"main.go", "input.txt"},
+			expectedErr: ErrInvalidFilePath,
+		},
+		{
+			name:        "file used as a directory",
+			files:       []string{"main.go", "main.go/util.go"},
+			expectedErr: ErrInvalidFilePath,
+		},
+		{
+			name:        "shell metacharacters",
+			files:       []string{"main.go", "$(reboot).go"},
+			expectedErr: ErrInvalidFilePath,
+		},
+		{
+			name:        "space in the path",
+			files:       []string{"main.go", "my util.go"},
+			expectedErr: ErrInvalidFilePath,
+		},
+		{
+			name:        "entrypoint with shell metacharacters",
+			files:       []string{"main.go"},
+			entrypoint:  "main.go;reboot",
+			expectedErr: ErrInvalidEntrypoint,
+		},
+		{
+			name:        "name looking like an option",
+			files:       []string{"main.go", "util/-o.go"},
+			expectedErr: ErrInvalidFilePath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := &Code{
+				Entrypoint: tt.entrypoint,
+				LanguageConfig: config.LanguageConfig{
+					Extension: ".go",
+				},
+			}
+			for _, path := range tt.files {
+				code.Files = append(code.Files, File{Path: path})
+			}
+
+			err := code.Validate()
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if err == nil && code.Entrypoint != tt.expectedEntrypoint {
+				t.Errorf("expected entrypoint '%s', got '%s'", tt.expectedEntrypoint, code.Entrypoint)
+			}
+		})
+	}
+}
+
+func TestCreateProjectFilesHost(t *testing.T) {
+	codeDir := t.TempDir()
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	code := &Code{
+		EncodedInput: encode("input"),
+		Files: []File{
+			{Path: "main.go", EncodedContent: encode("package main")},
+			{Path: "util/util.go", EncodedContent: encode("package util")},
+			{Path: "lib/lib.go", Content: []byte("package lib")},
+		},
+		Language: "golang",
+		LanguageConfig: config.LanguageConfig{
+			Extension: ".go",
+		},
+	}
+	if err := code.Validate(); err != nil {
+		t.Fatalf("failed to validate the code: %v", err)
+	}
+
+	submissionDir, entrypoint, _, err := createCodeAndInputFilesHost(codeDir, code, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to create the project files: %v", err)
+	}
+	if entrypoint != "main.go" {
+		t.Errorf("expected entrypoint 'main.go', got '%s'", entrypoint)
+	}
+
+	data, err := os.ReadFile(filepath.Join(submissionDir, "util", "util.go"))
+	if err != nil {
+		t.Fatalf("failed to read the nested file: %v", err)
+	}
+	if string(data) != "package util" {
+		t.Errorf("expected nested file content 'package util', got '%s'", string(data))
+	}
+
+	data, err = os.ReadFile(filepath.Join(submissionDir, "lib", "lib.go"))
+	if err != nil {
+		t.Fatalf("failed to read the raw file: %v", err)
+	}
+	if string(data) != "package lib" {
+		t.Errorf("expected raw file content 'package lib', got '%s'", string(data))
+	}
+}
@@ -0,0 +1,147 @@
+package codecontainer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"remote-code-engine/pkg/config"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// latestModTime returns the most recent modification time of path and, if it is a directory, everything below it.
+func latestModTime(path string) (time.Time, error) {
+	var latest time.Time
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest, err
+}
+
+// diskUsage returns the number of bytes used by the regular files below dir.
+func diskUsage(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+// deleteStaleFiles removes every file and submission directory in dir which hasn't been modified since threshold.
+func deleteStaleFiles(dir string, threshold time.Time, logger *zap.Logger) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		logger.Error("failed to read the directory", zap.String("directory name", dir))
+		return err
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		modTime, err := latestModTime(path)
+		if err != nil {
+			logger.Debug("failed to get the modification time", zap.String("path", path), zap.Error(err))
+			continue
+		}
+
+		if modTime.Before(threshold) {
+			if err := os.RemoveAll(path); err != nil {
+				logger.Error("error deleting file", zap.String("file name", path), zap.Error(err))
+			} else {
+				logger.Debug("deleted the file", zap.String("file name", path))
+			}
+		}
+	}
+	return nil
+}
+
+// languageDirectories returns every directory in the code directory, the languages removed from the config by a
+// reload still have their directories until they are swept.
+func languageDirectories(codeDir string) ([]string, error) {
+	entries, err := os.ReadDir(codeDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the code directory: %w", err)
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, filepath.Join(codeDir, entry.Name()))
+		}
+	}
+	return dirs, nil
+}
+
+// sweepCodeDirectories deletes the stale files of every directory in the code directory. Once the code directories
+// use more than the disk high-water mark, everything older than the maximum execution time is deleted regardless
+// of the retention period since it can no longer belong to a running execution.
+func sweepCodeDirectories(serverConfig *config.ServerConfig, logger *zap.Logger) {
+	threshold := time.Now().Add(-serverConfig.FileRetention)
+
+	dirs, err := languageDirectories(serverConfig.CodeDir)
+	if err != nil {
+		logger.Error("failed to list the code directories",
+			zap.String("code directory", serverConfig.CodeDir),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if highWaterMark := serverConfig.DiskHighWaterMarkMB * 1024 * 1024; highWaterMark > 0 {
+		var usage int64
+		for _, dir := range dirs {
+			size, err := diskUsage(dir)
+			if err != nil {
+				logger.Error("failed to compute the disk usage",
+					zap.String("directory", dir),
+					zap.Error(err),
+				)
+				continue
+			}
+			usage += size
+		}
+
+		if usage > highWaterMark {
+			logger.Warn("code directories exceeded the disk high-water mark, cleaning up aggressively",
+				zap.Int64("usage bytes", usage),
+				zap.Int64("high-water mark bytes", highWaterMark),
+			)
+			threshold = time.Now().Add(-serverConfig.MaxExecutionTime)
+		}
+	}
+
+	for _, dir := range dirs {
+		if err := deleteStaleFiles(dir, threshold, logger); err != nil {
+			logger.Error("failed to delete stale files",
+				zap.String("directory", dir),
+				zap.Error(err),
+			)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package codecontainer
+
+import (
+	"os"
+	"path/filepath"
+	"remote-code-engine/pkg/config"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func writeFileWithModTime(t *testing.T, path string, size int, modTime time.Time) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create the directory: %v", err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0600); err != nil {
+		t.Fatalf("failed to write the file: %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to change the modification time: %v", err)
+	}
+}
+
+func setDirModTime(t *testing.T, path string, modTime time.Time) {
+	t.Helper()
+
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to change the modification time: %v", err)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestDeleteStaleFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := now.Add(-time.Hour)
+
+	writeFileWithModTime(t, filepath.Join(dir, "stale", "main.go"), 10, old)
+	setDirModTime(t, filepath.Join(dir, "stale"), old)
+
+	writeFileWithModTime(t, filepath.Join(dir, "active", "main.go"), 10, old)
+	writeFileWithModTime(t, filepath.Join(dir, "active", "input.txt"), 10, now)
+	setDirModTime(t, filepath.Join(dir, "active"), old)
+
+	writeFileWithModTime(t, filepath.Join(dir, "legacy.cpp"), 10, old)
+
+	if err := deleteStaleFiles(dir, now.Add(-time.Minute), zap.NewNop()); err != nil {
+		t.Fatalf("failed to delete the stale files: %v", err)
+	}
+
+	if exists(filepath.Join(dir, "stale")) {
+		t.Error("expected the stale submission directory to be deleted")
+	}
+	if exists(filepath.Join(dir, "legacy.cpp")) {
+		t.Error("expected the stale file to be deleted")
+	}
+	if !exists(filepath.Join(dir, "active", "main.go")) {
+		t.Error("expected the recently modified submission directory to be kept")
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	dir := t.TempDir()
+	writeFileWithModTime(t, filepath.Join(dir, "a", "main.go"), 100, time.Now())
+	writeFileWithModTime(t, filepath.Join(dir, "b", "c", "input.txt"), 50, time.Now())
+
+	usage, err := diskUsage(dir)
+	if err != nil {
+		t.Fatalf("failed to compute the disk usage: %v", err)
+	}
+	if usage != 150 {
+		t.Errorf("expected 150 bytes, got %d", usage)
+	}
+}
+
+func TestSweepCodeDirectories(t *testing.T) {
+	serverConfig := config.DefaultServerConfig()
+	serverConfig.CodeDir = t.TempDir()
+	serverConfig.FileRetention = time.Hour
+
+	// A language which isn't one of the built-in constants, or was removed from the config by a reload, still has
+	// to be cleaned up.
+	languages := []config.Language{config.Golang, "python", "cobol"}
+	recent := time.Now().Add(-10 * time.Minute)
+	for _, lang := range languages {
+		dir := filepath.Join(serverConfig.GetHostLanguageCodePath(lang), "submission")
+		writeFileWithModTime(t, filepath.Join(dir, "main"), 1024*1024, recent)
+		setDirModTime(t, dir, recent)
+	}
+
+	sweepCodeDirectories(&serverConfig, zap.NewNop())
+	for _, lang := range languages {
+		if !exists(filepath.Join(serverConfig.GetHostLanguageCodePath(lang), "submission")) {
+			t.Errorf("expected the files of %s within the retention period to be kept", lang)
+		}
+	}
+
+	serverConfig.DiskHighWaterMarkMB = 1
+	sweepCodeDirectories(&serverConfig, zap.NewNop())
+	for _, lang := range languages {
+		if exists(filepath.Join(serverConfig.GetHostLanguageCodePath(lang), "submission")) {
+			t.Errorf("expected the files of %s to be deleted above the high-water mark", lang)
+		}
+	}
+}
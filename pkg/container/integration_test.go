@@ -0,0 +1,276 @@
+//go:build integration
+
+package codecontainer
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"remote-code-engine/pkg/config"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// The integration tests execute code in the images of the config file, build them with scripts/build_docker.sh
+// and run the tests with
+//
+//	go test -tags integration ./pkg/container/
+//
+// RCE_INTEGRATION_CONFIG points the tests at another config file, e.g. one using the x86_64 images.
+
+type integrationProgram struct {
+	name     string
+	code     string
+	expected string
+}
+
+var integrationPrograms = map[config.Language][]integrationProgram{
+	config.Cpp: {
+		{"stdin", "#include <iostream>\n#include <string>\nint main() { std::string s; std::cin >> s; std::cout << \"Hello, \" << s << std::endl; }\n", "Hello, srujan"},
+		{"compile error", "int main() { return undefined; }\n", "Compilation failed"},
+		{"runtime error", "#include <cstdlib>\nint main() { std::abort(); }\n", "Runtime error occurred"},
+	},
+	config.C: {
+		{"stdin", "#include <stdio.h>\nint main(void) { char s[64]; scanf(\"%63s\", s); printf(\"Hello, %s\\n\", s); return 0; }\n", "Hello, srujan"},
+		{"compile error", "int main(void) { return undefined; }\n", "Compilation failed"},
+		{"runtime error", "int main(void) { return 3; }\n", "the program exited with status 3"},
+	},
+	config.Golang: {
+		{"stdin", "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tvar s string\n\tfmt.Scanln(&s)\n\tfmt.Println(\"Hello, \" + s)\n}\n", "Hello, srujan"},
+		{"compile error", "package main\n\nfunc main() {\n\tundefined()\n}\n", "Compilation failed"},
+		{"runtime error", "package main\n\nfunc main() {\n\tpanic(\"boom\")\n}\n", "Runtime error occurred"},
+	},
+	config.Python: {
+		{"stdin", "print(\"Hello, \" + input())\n", "Hello, srujan"},
+		{"compile error", "def main(:\n    pass\n", "Compilation failed"},
+		{"runtime error", "print(1 / 0)\n", "ZeroDivisionError"},
+	},
+	config.Java: {
+		{"stdin", "import java.util.Scanner;\n\nclass Greeter {\n    static String greet(String s) { return \"Hello, \" + s; }\n}\n\npublic class Solution {\n    public static void main(String[] args) {\n        System.out.println(Greeter.greet(new Scanner(System.in).next()));\n    }\n}\n", "Hello, srujan"},
+		{"compile error", "public class Main {\n    public static void main(String[] args) {\n        undefined();\n    }\n}\n", "Compilation failed"},
+		{"runtime error", "public class Main {\n    public static void main(String[] args) {\n        throw new IllegalStateException(\"boom\");\n    }\n}\n", "IllegalStateException"},
+	},
+	config.Rust: {
+		{"stdin", "use std::io;\n\nfn main() {\n    let mut s = String::new();\n    io::stdin().read_line(&mut s).unwrap();\n    println!(\"Hello, {}\", s.trim());\n}\n", "Hello, srujan"},
+		{"compile error", "fn main() {\n    undefined();\n}\n", "Compilation failed"},
+		{"runtime error", "fn main() {\n    panic!(\"boom\");\n}\n", "Runtime error occurred"},
+	},
+	config.JavaScript: {
+		{"stdin", "const s = require(\"fs\").readFileSync(0, \"utf8\").trim();\nconsole.log(\"Hello, \" + s);\n", "Hello, srujan"},
+		{"compile error", "const = ;\n", "Compilation failed"},
+		{"runtime error", "throw new Error(\"boom\");\n", "Runtime error occurred"},
+	},
+}
+
+// newIntegrationClient returns a client of the docker daemon configured by the config file, the test is skipped
+// if the daemon is not available.
+func newIntegrationClient(t *testing.T, modify func(*config.ServerConfig)) (ContainerClient, *config.File) {
+	t.Helper()
+	configPath := "../../config.yml"
+	if path, ok := os.LookupEnv("RCE_INTEGRATION_CONFIG"); ok {
+		configPath = path
+	}
+
+	configFile, err := config.LoadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to load the config file: %v", err)
+	}
+
+	serverConfig := configFile.Server
+	serverConfig.CodeDir = t.TempDir()
+	if modify != nil {
+		modify(&serverConfig)
+	}
+
+	cli, err := NewDockerClient(nil, &serverConfig, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to create the docker client: %v", err)
+	}
+	if _, err := cli.(*dockerClient).client.Ping(context.Background()); err != nil {
+		t.Skipf("docker daemon is not available: %v", err)
+	}
+	return cli, configFile
+}
+
+// resolveIntegrationLanguage resolves the default version of the language and prepares its code directory, the
+// test is skipped if the image is not present.
+func resolveIntegrationLanguage(t *testing.T, cli ContainerClient, configFile *config.File, lang config.Language) (config.LanguageConfig, string) {
+	t.Helper()
+	langConfig, version, err := configFile.Languages.Resolve(lang, "")
+	if err != nil {
+		t.Fatalf("failed to resolve the language: %v", err)
+	}
+
+	exists, err := cli.ImageExists(context.Background(), langConfig.Image)
+	if err != nil {
+		t.Fatalf("failed to check the image: %v", err)
+	}
+	if !exists {
+		t.Skipf("image %s is not present, build it with scripts/build_docker.sh", langConfig.Image)
+	}
+
+	serverConfig := cli.(*dockerClient).config
+	if err := os.MkdirAll(serverConfig.GetHostLanguageCodePath(lang), 0755); err != nil {
+		t.Fatalf("failed to create the code directory: %v", err)
+	}
+	return langConfig, version
+}
+
+func TestIntegrationLanguages(t *testing.T) {
+	cli, configFile := newIntegrationClient(t, nil)
+
+	encodedInput := base64.StdEncoding.EncodeToString([]byte("srujan\n"))
+	for lang, programs := range integrationPrograms {
+		t.Run(string(lang), func(t *testing.T) {
+			langConfig, version := resolveIntegrationLanguage(t, cli, configFile, lang)
+
+			for _, program := range programs {
+				t.Run(program.name, func(t *testing.T) {
+					result, err := cli.ExecuteCode(context.Background(), &Code{
+						EncodedCode:    base64.StdEncoding.EncodeToString([]byte(program.code)),
+						EncodedInput:   encodedInput,
+						Language:       lang,
+						Version:        version,
+						LanguageConfig: langConfig,
+					})
+					if err != nil {
+						t.Fatalf("failed to execute the code: %v", err)
+					}
+
+					if !strings.Contains(result.Output, program.expected) {
+						t.Errorf("expected the output to contain %q, got:\n%s", program.expected, result.Output)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestIntegrationOutputLimit(t *testing.T) {
+	cli, configFile := newIntegrationClient(t, func(s *config.ServerConfig) {
+		s.MaxOutputSizeKB = 64
+	})
+	langConfig, version := resolveIntegrationLanguage(t, cli, configFile, config.Python)
+
+	result, err := cli.ExecuteCode(context.Background(), &Code{
+		EncodedCode:    base64.StdEncoding.EncodeToString([]byte("while True:\n    print('x' * 1000)\n")),
+		Language:       config.Python,
+		Version:        version,
+		LanguageConfig: langConfig,
+	})
+	if err != nil {
+		t.Fatalf("failed to execute the code: %v", err)
+	}
+
+	if result.Verdict != VerdictOutputLimitExceeded || !result.OutputTruncated {
+		t.Errorf("expected the output limit to be exceeded, got %s", result.Verdict)
+	}
+	if len(result.Output) > 64*1024 {
+		t.Errorf("expected at most 64 KB of output, got %d bytes", len(result.Output))
+	}
+}
+
+func TestIntegrationResourceUsage(t *testing.T) {
+	cli, configFile := newIntegrationClient(t, func(s *config.ServerConfig) {
+		s.ResourceConstraints = true
+	})
+	langConfig, version := resolveIntegrationLanguage(t, cli, configFile, config.Python)
+
+	tests := []struct {
+		name     string
+		code     string
+		limits   config.Limits
+		expected Verdict
+	}{
+		{name: "usage", code: "print(sum(range(10**6)))\n", expected: VerdictOK},
+		{
+			name:     "cpu time limit",
+			code:     "while True:\n    pass\n",
+			limits:   config.Limits{CPUTimeLimit: time.Second},
+			expected: VerdictTimeLimitExceeded,
+		},
+		{
+			name:     "memory limit",
+			code:     "data = []\nwhile True:\n    data.append(' ' * 10**6)\n",
+			limits:   config.Limits{MemoryMB: 64},
+			expected: VerdictMemoryLimitExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := &Code{
+				EncodedCode:    base64.StdEncoding.EncodeToString([]byte(tt.code)),
+				Language:       config.Python,
+				Version:        version,
+				LanguageConfig: langConfig,
+			}
+			code.Limits = code.Limits.Merge(tt.limits)
+			result, err := cli.ExecuteCode(context.Background(), code)
+			if err != nil {
+				t.Fatalf("failed to execute the code: %v", err)
+			}
+
+			if result.Verdict != tt.expected {
+				t.Errorf("expected the verdict %s, got %s: %s", tt.expected, result.Verdict, result.Output)
+			}
+			if result.Usage == nil || result.Usage.PeakMemoryBytes == 0 {
+				t.Errorf("expected the usage of the program, got %+v", result.Usage)
+			}
+		})
+	}
+}
+
+func TestIntegrationBenchmark(t *testing.T) {
+	cli, configFile := newIntegrationClient(t, nil)
+	langConfig, version := resolveIntegrationLanguage(t, cli, configFile, config.Python)
+
+	result, err := cli.ExecuteCode(context.Background(), &Code{
+		EncodedCode:    base64.StdEncoding.EncodeToString([]byte("print(sum(range(10**6)))\n")),
+		Language:       config.Python,
+		Version:        version,
+		LanguageConfig: langConfig,
+		Benchmark:      &Benchmark{Runs: 5, Warmups: 1},
+	})
+	if err != nil {
+		t.Fatalf("failed to execute the code: %v", err)
+	}
+
+	if result.Verdict != VerdictOK || strings.TrimSpace(result.Output) != "499999500000" {
+		t.Fatalf("expected the output of a single run, got %s: %q", result.Verdict, result.Output)
+	}
+	if result.Benchmark == nil || result.Benchmark.Runs != 5 || result.Benchmark.PeakMemoryKB.Min == 0 {
+		t.Errorf("expected the statistics of 5 runs, got %+v", result.Benchmark)
+	}
+}
+
+func TestIntegrationDeterministic(t *testing.T) {
+	cli, configFile := newIntegrationClient(t, nil)
+	langConfig, version := resolveIntegrationLanguage(t, cli, configFile, config.Python)
+
+	program := "import os, socket\nprint(os.environ['RCE_SEED'], socket.gethostname(), hash('rce'))\n"
+	var outputs []string
+	for range 2 {
+		result, err := cli.ExecuteCode(context.Background(), &Code{
+			EncodedCode:    base64.StdEncoding.EncodeToString([]byte(program)),
+			Language:       config.Python,
+			Version:        version,
+			LanguageConfig: langConfig,
+			Deterministic:  &Deterministic{Seed: 42},
+		})
+		if err != nil {
+			t.Fatalf("failed to execute the code: %v", err)
+		}
+		if result.Environment == nil || !strings.HasPrefix(result.Environment.ImageDigest, "sha256:") {
+			t.Fatalf("expected the environment with the image digest, got %+v", result.Environment)
+		}
+		outputs = append(outputs, result.Output)
+	}
+
+	if !strings.HasPrefix(outputs[0], "42 "+deterministicHostname+" ") || outputs[0] != outputs[1] {
+		t.Errorf("expected the same output of both runs, got %q and %q", outputs[0], outputs[1])
+	}
+}
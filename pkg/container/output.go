@@ -0,0 +1,61 @@
+package codecontainer
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// outputLimiter bounds the output kept and forwarded for each stream of an execution. Once a stream exceeds the
+// limit, the rest of its output is discarded and exceeded is closed so the container can be killed.
+type outputLimiter struct {
+	limit    int64
+	exceeded chan struct{}
+	once     sync.Once
+}
+
+func newOutputLimiter(limit int64) *outputLimiter {
+	return &outputLimiter{limit: limit, exceeded: make(chan struct{})}
+}
+
+// writer keeps the head of a stream in buf and forwards it to w.
+func (l *outputLimiter) writer(buf *bytes.Buffer, w io.Writer) io.Writer {
+	return &limitedWriter{limiter: l, buf: buf, w: w}
+}
+
+// Exceeded reports whether a stream exceeded the limit.
+func (l *outputLimiter) Exceeded() bool {
+	select {
+	case <-l.exceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+type limitedWriter struct {
+	limiter *outputLimiter
+	buf     *bytes.Buffer
+	w       io.Writer
+}
+
+// Write never fails because of the limit, the output beyond it is read and dropped so the log stream keeps
+// flowing until the container is killed.
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if remaining := lw.limiter.limit - int64(lw.buf.Len()); int64(len(p)) > remaining {
+		p = p[:max(remaining, 0)]
+		lw.limiter.once.Do(func() {
+			close(lw.limiter.exceeded)
+		})
+	}
+	if len(p) == 0 {
+		return n, nil
+	}
+
+	lw.buf.Write(p)
+	if _, err := lw.w.Write(p); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,43 @@
+package codecontainer
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestOutputLimiter(t *testing.T) {
+	limiter := newOutputLimiter(8)
+	var stdout, stderr, forwarded bytes.Buffer
+	stdoutWriter := limiter.writer(&stdout, &forwarded)
+	stderrWriter := limiter.writer(&stderr, io.Discard)
+
+	for _, chunk := range []string{"12345", "678"} {
+		if n, err := io.WriteString(stdoutWriter, chunk); err != nil || n != len(chunk) {
+			t.Fatalf("failed to write %q: %d, %v", chunk, n, err)
+		}
+	}
+	if limiter.Exceeded() {
+		t.Fatal("expected output up to the limit to be accepted")
+	}
+
+	// The limit applies to every stream on its own.
+	if _, err := io.WriteString(stderrWriter, "error"); err != nil || limiter.Exceeded() {
+		t.Fatalf("expected stderr to have its own limit, got %v", err)
+	}
+
+	n, err := io.WriteString(stdoutWriter, strings.Repeat("x", 100))
+	if err != nil || n != 100 {
+		t.Fatalf("expected the output beyond the limit to be dropped silently, got %d, %v", n, err)
+	}
+	if !limiter.Exceeded() {
+		t.Error("expected the limit to be exceeded")
+	}
+	if stdout.String() != "12345678" || forwarded.String() != "12345678" {
+		t.Errorf("expected the head of the output, got %q and %q", stdout.String(), forwarded.String())
+	}
+	if stderr.String() != "error" {
+		t.Errorf("expected stderr to be kept, got %q", stderr.String())
+	}
+}
@@ -2,7 +2,9 @@ package codecontainer
 
 import (
 	"context"
+	"io"
 	"remote-code-engine/pkg/config"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 )
@@ -16,15 +18,180 @@ type Container struct {
 type Code struct {
 	EncodedCode  string
 	EncodedInput string
-	Language     config.Language
+	// ID and path of a pre-uploaded input, used instead of EncodedInput if the path is set.
+	InputID   string
+	InputPath string
+	// Files of a multi-file submission, EncodedCode is ignored if there are any.
+	Files []File
+	// Path of the file the command runs, relative to the submission directory. Defaults to main<extension>.
+	Entrypoint string
+	Language   config.Language
+	// Version of the language, empty if the language doesn't offer multiple versions.
+	Version string
+	// Globs of the files written by the program which are returned as artifacts, e.g. "out/*.csv".
+	Artifacts []string
+	// Execute the code even if the result is cached, e.g. because the program uses randomness.
+	NoCache bool
+	// Runs the program repeatedly if set, benchmarks are never cached.
+	Benchmark *Benchmark
+	// Runs the code in a fixed environment if set, deterministic runs are never cached.
+	Deterministic *Deterministic
+	// Encoding of the code, the input and the file contents, base64 if empty. Validate converts them to base64.
+	Encoding Encoding
+	// Config of the language with the settings of the version merged in.
 	config.LanguageConfig
 }
 
+// Verdict classifies the outcome of an execution.
+type Verdict string
+
+const (
+	VerdictOK                Verdict = "ok"
+	VerdictCompilationError  Verdict = "compilation_error"
+	VerdictRuntimeError      Verdict = "runtime_error"
+	VerdictTimeLimitExceeded Verdict = "time_limit_exceeded"
+	// The program was killed by the kernel because the container ran out of memory.
+	VerdictMemoryLimitExceeded Verdict = "memory_limit_exceeded"
+	// The program was killed because it wrote more than the output limit to stdout or stderr.
+	VerdictOutputLimitExceeded Verdict = "output_limit_exceeded"
+	// The server failed to execute the code, the code itself may be fine.
+	VerdictInternalError Verdict = "internal_error"
+	// The execution was cancelled before the program finished.
+	VerdictCancelled Verdict = "cancelled"
+)
+
+// Exit code of run-code.sh when the program used more CPU time than the limit, the exit code of a program killed by
+// SIGXCPU.
+const cpuTimeExceededExitCode = 128 + 24
+
+// Result of an execution.
+type Result struct {
+	Output  string
+	Verdict Verdict
+	// Exit code of the container, 0 if it was killed because of the time limit.
+	ExitCode int
+	// Time the container ran for.
+	Duration  time.Duration
+	Artifacts []Artifact
+	// Set if some of the matching files were left out because of the artifact limits.
+	ArtifactsTruncated bool
+	// Set if the program was killed because it exceeded the time limit or it used more than the CPU time limit.
+	TimedOut bool
+	// Usage of the program reported by the command, nil if the command doesn't report it. The usage of the last run
+	// for a benchmark.
+	Usage *ResourceUsage
+	// Usage of the whole container including the compilation, sampled from the cgroup of the container.
+	// Nil if the container exited before it was sampled.
+	ContainerUsage *ResourceUsage
+	// Set if the output is the head of a stream which exceeded the output limit.
+	OutputTruncated bool
+	// Statistics of the runs of a benchmark, nil unless every run succeeded.
+	Benchmark *BenchmarkResult
+	// Conditions of a deterministic run, nil for other runs.
+	Environment *Environment
+	// Set if the result was served from the result cache instead of executing the code.
+	Cached bool
+}
+
+// Benchmark runs the compiled program repeatedly against the same input in the same container. All the runs
+// share the time limit of the execution.
+type Benchmark struct {
+	Runs int
+	// Runs before the measured ones whose usage is discarded, e.g. to warm up a JIT compiler.
+	Warmups int
+}
+
+// Deterministic runs the code with the image pinned to its digest, a fixed env and hostname and seeded random
+// number env vars, and with a faked clock if the server configures a time offset. Together with the code and the
+// input the recorded Environment reproduces a run.
+type Deterministic struct {
+	// Seed of RANDOM, RCE_SEED and PYTHONHASHSEED.
+	Seed int64
+	// Digest of the image to run, e.g. the one recorded for an earlier run, as long as the image exists. Empty runs
+	// the current image of the language.
+	ImageDigest string
+}
+
+// Environment records the exact conditions of a deterministic run.
+type Environment struct {
+	// Content digest of the image the container was created from.
+	ImageDigest string
+	Command     []string
+	// Env passed to the container, on top of the env of the image.
+	Env      []string
+	Hostname string
+	Seed     int64
+	// Offset of the faked clock, 0 if the time isn't faked.
+	TimeOffset time.Duration
+	// Effective limits the code ran with.
+	Limits config.Limits
+}
+
+// BenchmarkResult summarizes the measured runs of a benchmark.
+type BenchmarkResult struct {
+	Runs         int
+	Warmups      int
+	WallTimeMs   Summary
+	CPUTimeMs    Summary
+	PeakMemoryKB Summary
+}
+
+// Summary holds the statistics of a metric over the runs of a benchmark.
+type Summary struct {
+	Min    float64
+	Median float64
+	P95    float64
+	Stddev float64
+}
+
+// ResourceUsage is the CPU time and the memory used by an execution.
+type ResourceUsage struct {
+	// User and system CPU time.
+	CPUTime time.Duration
+	// Peak resident memory.
+	PeakMemoryBytes int64
+}
+
+// Artifact is a file written by the program.
+type Artifact struct {
+	// Path relative to the submission directory.
+	Path           string
+	EncodedContent string
+	Size           int64
+}
+
+// File of a multi-file submission.
+type File struct {
+	// Path relative to the submission directory, e.g. "include/util.h".
+	Path           string
+	EncodedContent string
+	// Content of files which weren't sent encoded, e.g. the files of an archive, it is used instead of
+	// EncodedContent if set.
+	Content []byte
+}
+
+// IsProject reports whether the code is a multi-file submission.
+func (c *Code) IsProject() bool {
+	return len(c.Files) > 0
+}
+
 type ContainerClient interface {
+	// Periodically prunes the stopped containers and deletes the stale files in the code directory, including the
+	// files of languages which were removed from the config.
 	FreeUpZombieContainers(ctx context.Context) error
 
-	// Executes and returns the output in the string, error in case of server errors not code errors.
-	ExecuteCode(ctx context.Context, code *Code) (string, error)
+	// Executes and returns the output and the artifacts, error in case of server errors not code errors.
+	ExecuteCode(ctx context.Context, code *Code) (*Result, error)
+
+	// Executes like ExecuteCode and copies the output to stdout and stderr while the program runs.
+	// Cancelling the context kills the program, the result has the cancelled verdict then.
+	StreamCode(ctx context.Context, code *Code, stdout, stderr io.Writer) (*Result, error)
+
+	// Reports whether the image is present, images are never pulled at execution time.
+	ImageExists(ctx context.Context, image string) (bool, error)
+
+	// Returns the content digest of the image, it changes whenever the image is rebuilt.
+	ImageDigest(ctx context.Context, image string) (string, error)
 
 	// TODO: Is this even needed?
 	// Remove list options if you want some other container type other than docker
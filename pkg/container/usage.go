@@ -0,0 +1,221 @@
+package codecontainer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// The report directory of an execution is mounted here, run-code.sh writes the usage and the benchmark to it. It is
+// a mount of its own so the reports never mix with the files of the submission directory.
+const reportMountPath = "/rce-report"
+
+// Suffix of the report directory on the host, it is created next to the submission directory.
+const reportDirSuffix = ".report"
+
+// Written to the report directory by run-code.sh, it holds the wall seconds, the user and system CPU seconds and
+// the peak resident memory in KB of the program, e.g. "0.61 0.52 0.03 10240".
+const usageFileName = "usage"
+
+// Written to the report directory by run-code.sh for a benchmark, it holds a line of the usage file for every
+// measured run. Both files are written once the program and the processes it left behind are gone.
+const benchmarkFileName = "benchmark"
+
+// Written empty to the report directory by run-code.sh when the code doesn't compile. The program never ran then,
+// and whatever a program puts in the report directory is removed before the reports are written.
+const compilationFailedFileName = "compilation-failed"
+
+// The usage file holds a single line, anything larger wasn't written by run-code.sh.
+const maxUsageFileSize = 4096
+
+// runUsage is the usage of a single run of the program.
+type runUsage struct {
+	WallTime time.Duration
+	ResourceUsage
+}
+
+// readUsage reads and removes the usage file of the execution, nil if the command didn't write one.
+func readUsage(reportDir string) (*ResourceUsage, error) {
+	content, err := readRunFile(filepath.Join(reportDir, usageFileName), maxUsageFileSize)
+	if err != nil || content == "" {
+		return nil, err
+	}
+	return parseUsage(content)
+}
+
+// readBenchmark reads and removes the benchmark file of the execution, nil if the command didn't write one.
+func readBenchmark(reportDir string, runs int) ([]runUsage, error) {
+	content, err := readRunFile(filepath.Join(reportDir, benchmarkFileName), int64(runs)*maxUsageFileSize)
+	if err != nil || content == "" {
+		return nil, err
+	}
+
+	var usages []runUsage
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		usage, err := parseUsageLine(line)
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+// readCompilationFailed reports whether run-code.sh marked the compilation as failed and removes the marker.
+func readCompilationFailed(reportDir string) bool {
+	markerPath := filepath.Join(reportDir, compilationFailedFileName)
+	info, err := os.Lstat(markerPath)
+	if err != nil {
+		return false
+	}
+	_ = os.Remove(markerPath)
+	return info.Mode().IsRegular()
+}
+
+// readRunFile reads and removes a file written by run-code.sh, empty if it doesn't exist. The file is written by a
+// process of the container, it is only read if it is a regular file.
+func readRunFile(filePath string, maxSize int64) (string, error) {
+	info, err := os.Lstat(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", filepath.Base(filePath), err)
+	}
+	// The file isn't an artifact, it is removed whether it can be read or not.
+	defer os.Remove(filePath)
+	if !info.Mode().IsRegular() || info.Size() > maxSize {
+		return "", nil
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", filepath.Base(filePath), err)
+	}
+	return string(content), nil
+}
+
+// parseUsage parses the last line of the usage file, time writes a line about the exit status of a failed program
+// before the usage.
+func parseUsage(content string) (*ResourceUsage, error) {
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	usage, err := parseUsageLine(lines[len(lines)-1])
+	if err != nil {
+		return nil, err
+	}
+	return &usage.ResourceUsage, nil
+}
+
+func parseUsageLine(line string) (runUsage, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 4 {
+		return runUsage{}, fmt.Errorf("failed to parse the usage %q: expected 4 fields, got %d", line, len(fields))
+	}
+
+	var seconds [3]float64
+	for i, name := range []string{"wall time", "user CPU time", "system CPU time"} {
+		var err error
+		if seconds[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return runUsage{}, fmt.Errorf("failed to parse the %s: %w", name, err)
+		}
+	}
+	peakMemoryKB, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return runUsage{}, fmt.Errorf("failed to parse the peak memory: %w", err)
+	}
+
+	return runUsage{
+		WallTime: secondsToDuration(seconds[0]),
+		ResourceUsage: ResourceUsage{
+			CPUTime:         secondsToDuration(seconds[1] + seconds[2]),
+			PeakMemoryBytes: peakMemoryKB * 1024,
+		},
+	}, nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond)
+}
+
+// statsCollector follows the stats of a container, the cgroup of the container accounts for every process of the
+// execution including the compiler.
+type statsCollector struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	usage  ResourceUsage
+	sample bool
+}
+
+// collectStats follows the stats of the container until stop is called.
+func collectStats(ctx context.Context, cli client.APIClient, id string) *statsCollector {
+	ctx, cancel := context.WithCancel(ctx)
+	collector := &statsCollector{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(collector.done)
+		stats, err := cli.ContainerStats(ctx, id, true)
+		if err != nil {
+			return
+		}
+		defer stats.Body.Close()
+		collector.read(stats.Body)
+	}()
+	return collector
+}
+
+// read decodes the stream of stats until it ends.
+func (c *statsCollector) read(r io.Reader) {
+	decoder := json.NewDecoder(r)
+	for {
+		var stats container.StatsResponse
+		if err := decoder.Decode(&stats); err != nil {
+			return
+		}
+		c.add(stats.Stats)
+	}
+}
+
+// add records a sample, the CPU usage is cumulative while the memory usage is the peak of the samples unless the
+// cgroup reports its peak itself.
+func (c *statsCollector) add(stats container.Stats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The stats of a stopped container are empty.
+	if stats.CPUStats.CPUUsage.TotalUsage == 0 && stats.MemoryStats.Usage == 0 {
+		return
+	}
+	c.sample = true
+	c.usage.CPUTime = max(c.usage.CPUTime, time.Duration(stats.CPUStats.CPUUsage.TotalUsage))
+	c.usage.PeakMemoryBytes = max(c.usage.PeakMemoryBytes, int64(stats.MemoryStats.Usage), int64(stats.MemoryStats.MaxUsage))
+}
+
+// stop ends the collection and returns the usage, nil if the container ran too shortly to be sampled.
+func (c *statsCollector) stop() *ResourceUsage {
+	c.cancel()
+	<-c.done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.sample {
+		return nil
+	}
+	usage := c.usage
+	return &usage
+}
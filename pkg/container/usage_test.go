@@ -0,0 +1,160 @@
+package codecontainer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestParseUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected ResourceUsage
+		wantErr  bool
+	}{
+		{
+			name:     "usage",
+			content:  "0.61 0.52 0.03 10240\n",
+			expected: ResourceUsage{CPUTime: 550 * time.Millisecond, PeakMemoryBytes: 10240 * 1024},
+		},
+		{
+			name:     "failed program",
+			content:  "Command exited with non-zero status 1\n0.02 0.00 0.01 2048\n",
+			expected: ResourceUsage{CPUTime: 10 * time.Millisecond, PeakMemoryBytes: 2048 * 1024},
+		},
+		{name: "missing fields", content: "0.52 0.03 10240\n", wantErr: true},
+		{name: "invalid number", content: "0.61 0.52 x 10240\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage, err := parseUsage(tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", usage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *usage != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, *usage)
+			}
+		})
+	}
+}
+
+func TestReadUsage(t *testing.T) {
+	dir := t.TempDir()
+	if usage, err := readUsage(dir); usage != nil || err != nil {
+		t.Fatalf("expected no usage without the file, got %+v, %v", usage, err)
+	}
+
+	usagePath := filepath.Join(dir, usageFileName)
+	if err := os.WriteFile(usagePath, []byte("1.60 1.00 0.50 4096\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	usage, err := readUsage(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if usage == nil || usage.CPUTime != 1500*time.Millisecond || usage.PeakMemoryBytes != 4096*1024 {
+		t.Errorf("expected 1.5s and 4 MB, got %+v", usage)
+	}
+	if _, err := os.Stat(usagePath); !os.IsNotExist(err) {
+		t.Errorf("expected the usage file to be removed, got %v", err)
+	}
+
+	// A program may replace the file with a link to a file of the host.
+	target := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(target, []byte("1.60 1.00 0.50 4096\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, usagePath); err != nil {
+		t.Fatal(err)
+	}
+	if usage, err := readUsage(dir); usage != nil || err != nil {
+		t.Errorf("expected a link to be ignored, got %+v, %v", usage, err)
+	}
+	if _, err := os.Stat(target); err != nil {
+		t.Errorf("expected the target of the link to be kept, got %v", err)
+	}
+}
+
+func TestReadCompilationFailed(t *testing.T) {
+	dir := t.TempDir()
+	if readCompilationFailed(dir) {
+		t.Fatal("expected no compilation failure without the marker")
+	}
+
+	markerPath := filepath.Join(dir, compilationFailedFileName)
+	if err := os.WriteFile(markerPath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !readCompilationFailed(dir) {
+		t.Error("expected the marker to report the compilation failure")
+	}
+	if _, err := os.Stat(markerPath); !os.IsNotExist(err) {
+		t.Errorf("expected the marker to be removed, got %v", err)
+	}
+
+	if err := os.Mkdir(markerPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if readCompilationFailed(dir) {
+		t.Error("expected a directory to be ignored")
+	}
+}
+
+func TestReadBenchmark(t *testing.T) {
+	dir := t.TempDir()
+	content := "0.20 0.15 0.01 1024\n0.30 0.25 0.02 2048\n"
+	if err := os.WriteFile(filepath.Join(dir, benchmarkFileName), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := readBenchmark(dir, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []runUsage{
+		{WallTime: 200 * time.Millisecond, ResourceUsage: ResourceUsage{CPUTime: 160 * time.Millisecond, PeakMemoryBytes: 1024 * 1024}},
+		{WallTime: 300 * time.Millisecond, ResourceUsage: ResourceUsage{CPUTime: 270 * time.Millisecond, PeakMemoryBytes: 2048 * 1024}},
+	}
+	if !slices.Equal(runs, expected) {
+		t.Errorf("expected %+v, got %+v", expected, runs)
+	}
+}
+
+func TestStatsCollector(t *testing.T) {
+	stream := strings.Join([]string{
+		`{"cpu_stats": {"cpu_usage": {"total_usage": 100000000}}, "memory_stats": {"usage": 2097152}}`,
+		`{"cpu_stats": {"cpu_usage": {"total_usage": 300000000}}, "memory_stats": {"usage": 1048576}}`,
+		// The container stopped.
+		`{"cpu_stats": {"cpu_usage": {}}, "memory_stats": {}}`,
+	}, "\n")
+
+	collector := &statsCollector{cancel: func() {}, done: make(chan struct{})}
+	collector.read(strings.NewReader(stream))
+	close(collector.done)
+
+	usage := collector.stop()
+	expected := ResourceUsage{CPUTime: 300 * time.Millisecond, PeakMemoryBytes: 2097152}
+	if usage == nil || *usage != expected {
+		t.Errorf("expected %+v, got %+v", expected, usage)
+	}
+
+	empty := &statsCollector{cancel: func() {}, done: make(chan struct{})}
+	empty.add(container.Stats{})
+	close(empty.done)
+	if usage := empty.stop(); usage != nil {
+		t.Errorf("expected no usage without samples, got %+v", usage)
+	}
+}
@@ -0,0 +1,143 @@
+// Package inputs stores inputs uploaded ahead of the submissions which reference them by ID.
+package inputs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotFound = errors.New("input not found")
+	ErrTooLarge = errors.New("input too large")
+)
+
+// IDs are the hex encoded SHA-256 of the content.
+var idPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Input is an uploaded input.
+type Input struct {
+	ID   string `json:"input_id"`
+	Size int64  `json:"size"`
+}
+
+// Store keeps the inputs in a directory, named by the SHA-256 of their content so an input uploaded twice is
+// stored once. The modification time of an input is its last use, unused inputs are deleted by RunRetention.
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create the input directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Save streams the input to the store, it fails with ErrTooLarge if the input is larger than maxSize.
+func (s *Store) Save(r io.Reader, maxSize int64) (*Input, error) {
+	f, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the input file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+
+	hash := sha256.New()
+	// Read one byte more than allowed to detect inputs which are too large.
+	size, err := io.Copy(io.MultiWriter(f, hash), io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to write the input: %w", err)
+	}
+	if size > maxSize {
+		return nil, fmt.Errorf("%w: the input is larger than %d bytes", ErrTooLarge, maxSize)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write the input: %w", err)
+	}
+
+	id := hex.EncodeToString(hash.Sum(nil))
+	if err := os.Rename(f.Name(), s.path(id)); err != nil {
+		return nil, fmt.Errorf("failed to store the input: %w", err)
+	}
+	return &Input{ID: id, Size: size}, nil
+}
+
+// Path returns the path of the input and marks it as used, so it isn't deleted while it is used.
+func (s *Store) Path(id string) (string, error) {
+	if !idPattern.MatchString(id) {
+		return "", fmt.Errorf("%w: %q is not an input ID", ErrNotFound, id)
+	}
+
+	path := s.path(id)
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: %s, it may have expired, upload it again", ErrNotFound, id)
+		}
+		return "", fmt.Errorf("failed to mark the input as used: %w", err)
+	}
+	return path, nil
+}
+
+// DeleteUnused deletes the inputs which haven't been used since before, along with uploads which were interrupted.
+func (s *Store) DeleteUnused(before time.Time) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list the inputs: %w", err)
+	}
+
+	deleted := 0
+	var errs []error
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(before) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, errors.Join(errs...)
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// RunRetention deletes the inputs unused for longer than retention every interval until the context is cancelled.
+func RunRetention(ctx context.Context, store *Store, retention, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("stopping the input retention routine")
+			return
+		case <-ticker.C:
+			deleted, err := store.DeleteUnused(time.Now().Add(-retention))
+			if err != nil {
+				logger.Error("failed to delete the unused inputs",
+					zap.Error(err),
+				)
+			}
+			logger.Info("deleted the unused inputs",
+				zap.Int("#Deleted inputs", deleted),
+			)
+		}
+	}
+}
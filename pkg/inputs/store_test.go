@@ -0,0 +1,88 @@
+package inputs
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create the store: %v", err)
+	}
+
+	input, err := store.Save(strings.NewReader("1 2 3\n"), 16)
+	if err != nil {
+		t.Fatalf("failed to save the input: %v", err)
+	}
+	// sha256sum of "1 2 3\n"
+	if input.ID != "1def07dbe06eeb097aafec8a40329937cd20c93a83634b8221ea2b41a894310c" {
+		t.Errorf("unexpected ID %q", input.ID)
+	}
+	if input.Size != 6 {
+		t.Errorf("expected the size 6, got %d", input.Size)
+	}
+
+	again, err := store.Save(strings.NewReader("1 2 3\n"), 16)
+	if err != nil || again.ID != input.ID {
+		t.Errorf("expected the same input to get the same ID, got %v, %v", again, err)
+	}
+
+	path, err := store.Path(input.ID)
+	if err != nil {
+		t.Fatalf("failed to get the input: %v", err)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "1 2 3\n" {
+		t.Errorf("unexpected content %q: %v", content, err)
+	}
+
+	if _, err := store.Save(strings.NewReader(strings.Repeat("x", 17)), 16); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected an input larger than the maximum to fail, got %v", err)
+	}
+	for _, id := range []string{"../etc/passwd", strings.Repeat("0", 64)} {
+		if _, err := store.Path(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected %q not to be found, got %v", id, err)
+		}
+	}
+}
+
+func TestDeleteUnused(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create the store: %v", err)
+	}
+
+	unused, err := store.Save(strings.NewReader("unused"), 16)
+	if err != nil {
+		t.Fatalf("failed to save the input: %v", err)
+	}
+	used, err := store.Save(strings.NewReader("used"), 16)
+	if err != nil {
+		t.Fatalf("failed to save the input: %v", err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	for _, id := range []string{unused.ID, used.ID} {
+		if err := os.Chtimes(store.path(id), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Using an input keeps it.
+	if _, err := store.Path(used.ID); err != nil {
+		t.Fatalf("failed to get the input: %v", err)
+	}
+
+	deleted, err := store.DeleteUnused(time.Now().Add(-time.Hour))
+	if err != nil || deleted != 1 {
+		t.Fatalf("expected 1 input to be deleted, got %d: %v", deleted, err)
+	}
+	if _, err := store.Path(unused.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the unused input to be deleted, got %v", err)
+	}
+	if _, err := store.Path(used.ID); err != nil {
+		t.Errorf("expected the used input to be kept, got %v", err)
+	}
+}
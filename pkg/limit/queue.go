@@ -0,0 +1,84 @@
+// Package limit bounds the load the clients put on the server.
+package limit
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrQueueFull = errors.New("the execution queue is full")
+
+// Queue bounds the executions running at once and the executions waiting for a slot.
+type Queue struct {
+	// nil if the executions are not limited.
+	slots       chan struct{}
+	maxAdmitted int
+
+	mu       sync.Mutex
+	admitted int
+}
+
+// NewQueue returns a queue running at most maxRunning executions with at most maxWaiting more waiting,
+// maxRunning 0 doesn't limit the executions.
+func NewQueue(maxRunning, maxWaiting int) *Queue {
+	if maxRunning <= 0 {
+		return &Queue{}
+	}
+	return &Queue{
+		slots:       make(chan struct{}, maxRunning),
+		maxAdmitted: maxRunning + maxWaiting,
+	}
+}
+
+// Ticket is a place in the queue.
+type Ticket struct {
+	queue   *Queue
+	running bool
+}
+
+// Reserve takes a place in the queue, it fails with ErrQueueFull if every slot is taken and
+// the maximum number of executions is waiting already.
+func (q *Queue) Reserve() (*Ticket, error) {
+	if q.slots == nil {
+		return &Ticket{queue: q}, nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.admitted >= q.maxAdmitted {
+		return nil, ErrQueueFull
+	}
+	q.admitted++
+	return &Ticket{queue: q}, nil
+}
+
+// Wait blocks until the execution may run or ctx is done.
+func (t *Ticket) Wait(ctx context.Context) error {
+	if t.queue.slots == nil {
+		return nil
+	}
+
+	select {
+	case t.queue.slots <- struct{}{}:
+		t.running = true
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees the place in the queue, also if Wait failed. It must be called exactly once.
+func (t *Ticket) Release() {
+	q := t.queue
+	if q.slots == nil {
+		return
+	}
+
+	if t.running {
+		<-q.slots
+	}
+	q.mu.Lock()
+	q.admitted--
+	q.mu.Unlock()
+}
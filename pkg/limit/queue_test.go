@@ -0,0 +1,61 @@
+package limit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	queue := NewQueue(1, 1)
+
+	running, err := queue.Reserve()
+	if err != nil {
+		t.Fatalf("failed to reserve the first place: %v", err)
+	}
+	if err := running.Wait(context.Background()); err != nil {
+		t.Fatalf("expected the first execution to run right away, got %v", err)
+	}
+
+	waiting, err := queue.Reserve()
+	if err != nil {
+		t.Fatalf("failed to reserve the waiting place: %v", err)
+	}
+	if _, err := queue.Reserve(); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected the queue to be full, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := waiting.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the waiting execution to time out while the slot is taken, got %v", err)
+	}
+
+	running.Release()
+	if err := waiting.Wait(context.Background()); err != nil {
+		t.Fatalf("expected the waiting execution to run once the slot is free, got %v", err)
+	}
+	waiting.Release()
+
+	for range 2 {
+		ticket, err := queue.Reserve()
+		if err != nil {
+			t.Fatalf("expected the released places to be free again, got %v", err)
+		}
+		defer ticket.Release()
+	}
+}
+
+func TestUnlimitedQueue(t *testing.T) {
+	queue := NewQueue(0, 0)
+	for range 100 {
+		ticket, err := queue.Reserve()
+		if err != nil {
+			t.Fatalf("expected an unlimited queue, got %v", err)
+		}
+		if err := ticket.Wait(context.Background()); err != nil {
+			t.Fatalf("expected an unlimited queue, got %v", err)
+		}
+	}
+}
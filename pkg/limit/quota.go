@@ -0,0 +1,67 @@
+package limit
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrQuotaExceeded = errors.New("the submission quota is exceeded")
+
+// Quota allows every key, e.g. a tenant, a number of submissions per window. The windows are fixed,
+// they start with the first submission of the key.
+type Quota struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]*quotaWindow
+}
+
+type quotaWindow struct {
+	start time.Time
+	count int
+}
+
+// NewQuota returns a quota of limit submissions per window, limit 0 allows everything.
+func NewQuota(limit int, window time.Duration) *Quota {
+	return &Quota{
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+		windows: map[string]*quotaWindow{},
+	}
+}
+
+// Allow counts a submission of the key. If the quota of the key is exceeded it returns ErrQuotaExceeded
+// and the time until the next window starts.
+func (q *Quota) Allow(key string) (time.Duration, error) {
+	if q.limit <= 0 {
+		return 0, nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	w, ok := q.windows[key]
+	if !ok || now.Sub(w.start) >= q.window {
+		// Drop the expired windows now and then so keys which aren't used anymore don't pile up.
+		if !ok && len(q.windows) >= 1024 {
+			for k, other := range q.windows {
+				if now.Sub(other.start) >= q.window {
+					delete(q.windows, k)
+				}
+			}
+		}
+		w = &quotaWindow{start: now}
+		q.windows[key] = w
+	}
+
+	if w.count >= q.limit {
+		return w.start.Add(q.window).Sub(now), ErrQuotaExceeded
+	}
+	w.count++
+	return 0, nil
+}
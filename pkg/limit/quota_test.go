@@ -0,0 +1,49 @@
+package limit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQuota(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	quota := NewQuota(2, time.Minute)
+	quota.now = func() time.Time { return now }
+
+	tests := []struct {
+		name               string
+		key                string
+		elapsed            time.Duration
+		expectedErr        error
+		expectedRetryAfter time.Duration
+	}{
+		{name: "first submission", key: "cs101"},
+		{name: "second submission", key: "cs101", elapsed: 10 * time.Second},
+		{name: "quota exceeded", key: "cs101", elapsed: 10 * time.Second, expectedErr: ErrQuotaExceeded, expectedRetryAfter: 40 * time.Second},
+		{name: "other tenant", key: "cs102"},
+		{name: "next window", key: "cs101", elapsed: 40 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.elapsed)
+			retryAfter, err := quota.Allow(tt.key)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if retryAfter != tt.expectedRetryAfter {
+				t.Errorf("expected to retry after %s, got %s", tt.expectedRetryAfter, retryAfter)
+			}
+		})
+	}
+}
+
+func TestQuotaDisabled(t *testing.T) {
+	quota := NewQuota(0, time.Minute)
+	for range 100 {
+		if _, err := quota.Allow("cs101"); err != nil {
+			t.Fatalf("expected no quota, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+// Package rcepb contains the generated code of the gRPC API defined in proto/rce/v1/rce.proto.
+package rcepb
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=remote-code-engine --go-grpc_out=../.. --go-grpc_opt=module=remote-code-engine rce/v1/rce.proto
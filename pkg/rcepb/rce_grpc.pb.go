@@ -0,0 +1,297 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: rce/v1/rce.proto
+
+package rcepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CodeExecution_Submit_FullMethodName        = "/rce.v1.CodeExecution/Submit"
+	CodeExecution_Execute_FullMethodName       = "/rce.v1.CodeExecution/Execute"
+	CodeExecution_GetResult_FullMethodName     = "/rce.v1.CodeExecution/GetResult"
+	CodeExecution_Cancel_FullMethodName        = "/rce.v1.CodeExecution/Cancel"
+	CodeExecution_ListLanguages_FullMethodName = "/rce.v1.CodeExecution/ListLanguages"
+)
+
+// CodeExecutionClient is the client API for CodeExecution service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CodeExecution mirrors the REST API for service-to-service calls. Code, input and files are raw bytes
+// instead of base64. Submissions are recorded for the tenant of the token in the authorization metadata, or of the
+// x-tenant-id metadata behind a trusted gateway.
+type CodeExecutionClient interface {
+	// Submit executes a submission like POST /api/v1/submit. Asynchronous submissions return right away
+	// with the running status.
+	Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (*Result, error)
+	// Execute executes a submission synchronously and streams its output while the program runs. The first
+	// message carries the ID of the submission, the last one the result.
+	Execute(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecuteResponse], error)
+	// GetResult returns a recorded submission, requires the submission history.
+	GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*Submission, error)
+	// Cancel kills a running submission, it finishes with the cancelled verdict.
+	Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error)
+	ListLanguages(ctx context.Context, in *ListLanguagesRequest, opts ...grpc.CallOption) (*ListLanguagesResponse, error)
+}
+
+type codeExecutionClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCodeExecutionClient(cc grpc.ClientConnInterface) CodeExecutionClient {
+	return &codeExecutionClient{cc}
+}
+
+func (c *codeExecutionClient) Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (*Result, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Result)
+	err := c.cc.Invoke(ctx, CodeExecution_Submit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *codeExecutionClient) Execute(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecuteResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CodeExecution_ServiceDesc.Streams[0], CodeExecution_Execute_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubmitRequest, ExecuteResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CodeExecution_ExecuteClient = grpc.ServerStreamingClient[ExecuteResponse]
+
+func (c *codeExecutionClient) GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*Submission, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Submission)
+	err := c.cc.Invoke(ctx, CodeExecution_GetResult_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *codeExecutionClient) Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelResponse)
+	err := c.cc.Invoke(ctx, CodeExecution_Cancel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *codeExecutionClient) ListLanguages(ctx context.Context, in *ListLanguagesRequest, opts ...grpc.CallOption) (*ListLanguagesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListLanguagesResponse)
+	err := c.cc.Invoke(ctx, CodeExecution_ListLanguages_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CodeExecutionServer is the server API for CodeExecution service.
+// All implementations must embed UnimplementedCodeExecutionServer
+// for forward compatibility.
+//
+// CodeExecution mirrors the REST API for service-to-service calls. Code, input and files are raw bytes
+// instead of base64. Submissions are recorded for the tenant of the token in the authorization metadata, or of the
+// x-tenant-id metadata behind a trusted gateway.
+type CodeExecutionServer interface {
+	// Submit executes a submission like POST /api/v1/submit. Asynchronous submissions return right away
+	// with the running status.
+	Submit(context.Context, *SubmitRequest) (*Result, error)
+	// Execute executes a submission synchronously and streams its output while the program runs. The first
+	// message carries the ID of the submission, the last one the result.
+	Execute(*SubmitRequest, grpc.ServerStreamingServer[ExecuteResponse]) error
+	// GetResult returns a recorded submission, requires the submission history.
+	GetResult(context.Context, *GetResultRequest) (*Submission, error)
+	// Cancel kills a running submission, it finishes with the cancelled verdict.
+	Cancel(context.Context, *CancelRequest) (*CancelResponse, error)
+	ListLanguages(context.Context, *ListLanguagesRequest) (*ListLanguagesResponse, error)
+	mustEmbedUnimplementedCodeExecutionServer()
+}
+
+// UnimplementedCodeExecutionServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCodeExecutionServer struct{}
+
+func (UnimplementedCodeExecutionServer) Submit(context.Context, *SubmitRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Submit not implemented")
+}
+func (UnimplementedCodeExecutionServer) Execute(*SubmitRequest, grpc.ServerStreamingServer[ExecuteResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Execute not implemented")
+}
+func (UnimplementedCodeExecutionServer) GetResult(context.Context, *GetResultRequest) (*Submission, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetResult not implemented")
+}
+func (UnimplementedCodeExecutionServer) Cancel(context.Context, *CancelRequest) (*CancelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cancel not implemented")
+}
+func (UnimplementedCodeExecutionServer) ListLanguages(context.Context, *ListLanguagesRequest) (*ListLanguagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLanguages not implemented")
+}
+func (UnimplementedCodeExecutionServer) mustEmbedUnimplementedCodeExecutionServer() {}
+func (UnimplementedCodeExecutionServer) testEmbeddedByValue()                       {}
+
+// UnsafeCodeExecutionServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CodeExecutionServer will
+// result in compilation errors.
+type UnsafeCodeExecutionServer interface {
+	mustEmbedUnimplementedCodeExecutionServer()
+}
+
+func RegisterCodeExecutionServer(s grpc.ServiceRegistrar, srv CodeExecutionServer) {
+	// If the following call pancis, it indicates UnimplementedCodeExecutionServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CodeExecution_ServiceDesc, srv)
+}
+
+func _CodeExecution_Submit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CodeExecutionServer).Submit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CodeExecution_Submit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CodeExecutionServer).Submit(ctx, req.(*SubmitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CodeExecution_Execute_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubmitRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CodeExecutionServer).Execute(m, &grpc.GenericServerStream[SubmitRequest, ExecuteResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CodeExecution_ExecuteServer = grpc.ServerStreamingServer[ExecuteResponse]
+
+func _CodeExecution_GetResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CodeExecutionServer).GetResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CodeExecution_GetResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CodeExecutionServer).GetResult(ctx, req.(*GetResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CodeExecution_Cancel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CodeExecutionServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CodeExecution_Cancel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CodeExecutionServer).Cancel(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CodeExecution_ListLanguages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLanguagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CodeExecutionServer).ListLanguages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CodeExecution_ListLanguages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CodeExecutionServer).ListLanguages(ctx, req.(*ListLanguagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CodeExecution_ServiceDesc is the grpc.ServiceDesc for CodeExecution service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CodeExecution_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rce.v1.CodeExecution",
+	HandlerType: (*CodeExecutionServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Submit",
+			Handler:    _CodeExecution_Submit_Handler,
+		},
+		{
+			MethodName: "GetResult",
+			Handler:    _CodeExecution_GetResult_Handler,
+		},
+		{
+			MethodName: "Cancel",
+			Handler:    _CodeExecution_Cancel_Handler,
+		},
+		{
+			MethodName: "ListLanguages",
+			Handler:    _CodeExecution_ListLanguages_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Execute",
+			Handler:       _CodeExecution_Execute_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "rce/v1/rce.proto",
+}
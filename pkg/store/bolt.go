@@ -0,0 +1,243 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// Submissions by creation time followed by the ID, so they are iterated in chronological order.
+	submissionsBucket = []byte("submissions")
+	// Keys of the submissions bucket by ID.
+	idsBucket = []byte("ids")
+	// Dead letters by the time of the failure followed by the submission ID.
+	deadLettersBucket = []byte("dead_letters")
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Bolt stores the submissions in an embedded BoltDB file.
+type Bolt struct {
+	db *bolt.DB
+}
+
+func OpenBolt(path string) (*Bolt, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create the directory of the database: %w", err)
+	}
+
+	// Fail instead of blocking forever if another server holds the lock of the file.
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{submissionsBucket, idsBucket, deadLettersBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create the buckets: %w", err)
+	}
+
+	return &Bolt{db: db}, nil
+}
+
+func submissionKey(createdAt time.Time, id string) []byte {
+	key := make([]byte, 8, 8+len(id))
+	binary.BigEndian.PutUint64(key, uint64(createdAt.UnixNano()))
+	return append(key, id...)
+}
+
+func (b *Bolt) Save(ctx context.Context, submission *Submission) error {
+	data, err := json.Marshal(submission)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the submission: %w", err)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		ids := tx.Bucket(idsBucket)
+		submissions := tx.Bucket(submissionsBucket)
+		if previous := ids.Get([]byte(submission.ID)); previous != nil {
+			if err := submissions.Delete(previous); err != nil {
+				return err
+			}
+		}
+
+		key := submissionKey(submission.CreatedAt, submission.ID)
+		if err := submissions.Put(key, data); err != nil {
+			return fmt.Errorf("failed to save the submission: %w", err)
+		}
+		return ids.Put([]byte(submission.ID), key)
+	})
+}
+
+func (b *Bolt) Get(ctx context.Context, id string) (*Submission, error) {
+	var submission Submission
+	err := b.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(idsBucket).Get([]byte(id))
+		if key == nil {
+			return ErrNotFound
+		}
+		data := tx.Bucket(submissionsBucket).Get(key)
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, &submission)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+func (b *Bolt) List(ctx context.Context, filter Filter) ([]Submission, string, error) {
+	var start []byte
+	if filter.Cursor != "" {
+		cursor, err := hex.DecodeString(filter.Cursor)
+		if err != nil || len(cursor) < 8 {
+			return nil, "", ErrInvalidCursor
+		}
+		start = cursor
+	} else if !filter.To.IsZero() {
+		start = submissionKey(filter.To, "")
+	}
+
+	pageSize := filter.PageSize()
+	submissions := []Submission{}
+	next := ""
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(submissionsBucket).Cursor()
+
+		// Walk backwards from the newest submission, or from the one before the start key.
+		var key, data []byte
+		if start == nil {
+			key, data = c.Last()
+		} else {
+			key, data = c.Seek(start)
+			if key == nil {
+				key, data = c.Last()
+			}
+			for key != nil && bytes.Compare(key, start) >= 0 {
+				key, data = c.Prev()
+			}
+		}
+
+		for ; key != nil; key, data = c.Prev() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			var submission Submission
+			if err := json.Unmarshal(data, &submission); err != nil {
+				return fmt.Errorf("failed to unmarshal the submission: %w", err)
+			}
+			if !filter.From.IsZero() && submission.CreatedAt.Before(filter.From) {
+				return nil
+			}
+			if !filter.Matches(&submission) {
+				continue
+			}
+
+			if len(submissions) == pageSize {
+				next = hex.EncodeToString(submissionKey(submissions[len(submissions)-1].CreatedAt, submissions[len(submissions)-1].ID))
+				return nil
+			}
+			submissions = append(submissions, submission)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return submissions, next, nil
+}
+
+func (b *Bolt) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	deleted := 0
+	end := submissionKey(before, "")
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		submissions := tx.Bucket(submissionsBucket)
+		ids := tx.Bucket(idsBucket)
+
+		keys := keysBefore(submissions, end)
+		for _, key := range keys {
+			if err := ids.Delete(key[8:]); err != nil {
+				return err
+			}
+			if err := submissions.Delete(key); err != nil {
+				return err
+			}
+		}
+		deleted = len(keys)
+
+		deadLetters := tx.Bucket(deadLettersBucket)
+		for _, key := range keysBefore(deadLetters, end) {
+			if err := deadLetters.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// keysBefore returns the keys of the bucket sorting before end. Deleting while iterating makes the cursor
+// skip keys, so they are collected first.
+func keysBefore(bucket *bolt.Bucket, end []byte) [][]byte {
+	var keys [][]byte
+	c := bucket.Cursor()
+	for key, _ := c.First(); key != nil && bytes.Compare(key, end) < 0; key, _ = c.Next() {
+		keys = append(keys, bytes.Clone(key))
+	}
+	return keys
+}
+
+func (b *Bolt) SaveDeadLetter(ctx context.Context, deadLetter *DeadLetter) error {
+	data, err := json.Marshal(deadLetter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the dead letter: %w", err)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(deadLettersBucket).Put(submissionKey(deadLetter.FailedAt, deadLetter.SubmissionID), data)
+	})
+}
+
+func (b *Bolt) ListDeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	deadLetters := []DeadLetter{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(deadLettersBucket).Cursor()
+		for key, data := c.Last(); key != nil; key, data = c.Prev() {
+			var deadLetter DeadLetter
+			if err := json.Unmarshal(data, &deadLetter); err != nil {
+				return fmt.Errorf("failed to unmarshal the dead letter: %w", err)
+			}
+			deadLetters = append(deadLetters, deadLetter)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deadLetters, nil
+}
+
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
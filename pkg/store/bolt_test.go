@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) *Bolt {
+	b, err := OpenBolt(filepath.Join(t.TempDir(), "submissions.db"))
+	if err != nil {
+		t.Fatalf("failed to open the store: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = b.Close()
+	})
+	return b
+}
+
+func TestBoltSaveAndGet(t *testing.T) {
+	b := openTestStore(t)
+	ctx := context.Background()
+
+	submission := &Submission{
+		ID:        "a",
+		Tenant:    "course-1",
+		Language:  "python",
+		Verdict:   "ok",
+		Output:    "hello",
+		CreatedAt: time.Now().UTC(),
+		Duration:  time.Second,
+	}
+	if err := b.Save(ctx, submission); err != nil {
+		t.Fatalf("failed to save the submission: %v", err)
+	}
+
+	saved, err := b.Get(ctx, "a")
+	if err != nil {
+		t.Fatalf("failed to get the submission: %v", err)
+	}
+	if saved.Output != "hello" || saved.Tenant != "course-1" || !saved.CreatedAt.Equal(submission.CreatedAt) {
+		t.Errorf("expected %+v, got %+v", submission, saved)
+	}
+
+	if _, err := b.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestBoltList(t *testing.T) {
+	b := openTestStore(t)
+	ctx := context.Background()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 10 {
+		verdict := "ok"
+		if i%2 == 1 {
+			verdict = "runtime_error"
+		}
+		err := b.Save(ctx, &Submission{
+			ID:        fmt.Sprintf("s%d", i),
+			Tenant:    "course-1",
+			Language:  "python",
+			Verdict:   verdict,
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		})
+		if err != nil {
+			t.Fatalf("failed to save the submission: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		filter      Filter
+		expectedIDs [][]string
+	}{
+		{
+			name:        "newest first in pages",
+			filter:      Filter{Limit: 4},
+			expectedIDs: [][]string{{"s9", "s8", "s7", "s6"}, {"s5", "s4", "s3", "s2"}, {"s1", "s0"}},
+		},
+		{
+			name:        "filtered by verdict",
+			filter:      Filter{Verdict: "runtime_error", Limit: 3},
+			expectedIDs: [][]string{{"s9", "s7", "s5"}, {"s3", "s1"}},
+		},
+		{
+			name:        "time range",
+			filter:      Filter{From: start.Add(2 * time.Minute), To: start.Add(5 * time.Minute)},
+			expectedIDs: [][]string{{"s4", "s3", "s2"}},
+		},
+		{
+			name:        "other tenant",
+			filter:      Filter{Tenant: "course-2"},
+			expectedIDs: [][]string{{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			for page, expectedIDs := range tt.expectedIDs {
+				submissions, next, err := b.List(ctx, filter)
+				if err != nil {
+					t.Fatalf("failed to list the submissions: %v", err)
+				}
+
+				ids := []string{}
+				for _, submission := range submissions {
+					ids = append(ids, submission.ID)
+				}
+				if fmt.Sprint(ids) != fmt.Sprint(expectedIDs) {
+					t.Errorf("page %d: expected %v, got %v", page, expectedIDs, ids)
+				}
+
+				lastPage := page == len(tt.expectedIDs)-1
+				if lastPage != (next == "") {
+					t.Errorf("page %d: unexpected next cursor %q", page, next)
+				}
+				filter.Cursor = next
+			}
+		})
+	}
+
+	if _, _, err := b.List(ctx, Filter{Cursor: "not hex"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestBoltDeleteBefore(t *testing.T) {
+	b := openTestStore(t)
+	ctx := context.Background()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		if err := b.Save(ctx, &Submission{ID: fmt.Sprintf("s%d", i), CreatedAt: start.Add(time.Duration(i) * time.Hour)}); err != nil {
+			t.Fatalf("failed to save the submission: %v", err)
+		}
+	}
+
+	deleted, err := b.DeleteBefore(ctx, start.Add(3*time.Hour))
+	if err != nil {
+		t.Fatalf("failed to delete the submissions: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("expected 3 deleted submissions, got %d", deleted)
+	}
+	if _, err := b.Get(ctx, "s2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected s2 to be deleted, got %v", err)
+	}
+	if _, err := b.Get(ctx, "s3"); err != nil {
+		t.Errorf("expected s3 to be kept, got %v", err)
+	}
+}
+
+func TestBoltDeadLetters(t *testing.T) {
+	b := openTestStore(t)
+	ctx := context.Background()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		err := b.SaveDeadLetter(ctx, &DeadLetter{
+			SubmissionID: fmt.Sprintf("s%d", i),
+			CallbackURL:  "https://lms.example.com/rce",
+			FailedAt:     start.Add(time.Duration(i) * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("failed to save the dead letter: %v", err)
+		}
+	}
+
+	if _, err := b.DeleteBefore(ctx, start.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to delete the expired records: %v", err)
+	}
+
+	deadLetters, err := b.ListDeadLetters(ctx)
+	if err != nil {
+		t.Fatalf("failed to list the dead letters: %v", err)
+	}
+	if len(deadLetters) != 2 || deadLetters[0].SubmissionID != "s2" || deadLetters[1].SubmissionID != "s1" {
+		t.Errorf("expected the dead letters s2 and s1, got %+v", deadLetters)
+	}
+}
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RunRetention deletes the submissions older than retention every interval until the context is cancelled.
+func RunRetention(ctx context.Context, store Store, retention, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("stopping the submission retention routine")
+			return
+		case <-ticker.C:
+			deleted, err := store.DeleteBefore(ctx, time.Now().Add(-retention))
+			if err != nil {
+				logger.Error("failed to delete the expired submissions",
+					zap.Error(err),
+				)
+				continue
+			}
+			logger.Info("deleted the expired submissions",
+				zap.Int("#Deleted submissions", deleted),
+			)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package store keeps the history of the submissions.
+package store
+
+import (
+	"context"
+	"errors"
+	"remote-code-engine/pkg/config"
+	"time"
+)
+
+var ErrNotFound = errors.New("submission not found")
+
+// Statuses of a submission, asynchronous submissions are recorded while they are running.
+const (
+	StatusRunning  = "running"
+	StatusFinished = "finished"
+)
+
+// Page sizes of List.
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+type File struct {
+	Path           string `json:"path"`
+	EncodedContent string `json:"content"`
+}
+
+// Submission is a recorded execution with everything that was submitted and its result.
+type Submission struct {
+	ID     string `json:"id"`
+	Tenant string `json:"tenant,omitempty"`
+
+	Language     config.Language `json:"language"`
+	Version      string          `json:"version,omitempty"`
+	EncodedCode  string          `json:"code,omitempty"`
+	Files        []File          `json:"files,omitempty"`
+	Entrypoint   string          `json:"entrypoint,omitempty"`
+	EncodedInput string          `json:"input"`
+	// ID of the uploaded input the submission used instead of the input.
+	InputID string `json:"input_id,omitempty"`
+	// Effective limits the code ran with.
+	Limits config.Limits `json:"limits"`
+	// Conditions of a deterministic run, nil for other runs.
+	Environment *Environment `json:"environment,omitempty"`
+	// The result is posted to this URL once the submission is finished.
+	CallbackURL string `json:"callback_url,omitempty"`
+
+	Status string `json:"status"`
+	// Empty while the submission is running.
+	Verdict  string `json:"verdict,omitempty"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output"`
+	Cached   bool   `json:"cached"`
+
+	CreatedAt  time.Time `json:"created_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// Time the container ran for.
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Environment records the conditions of a deterministic run, submitting the code and the input with the seed and
+// the image digest reproduces the run.
+type Environment struct {
+	ImageDigest string   `json:"image_digest"`
+	Command     []string `json:"command"`
+	Env         []string `json:"env"`
+	Hostname    string   `json:"hostname"`
+	Seed        int64    `json:"seed"`
+	// Offset of the faked clock, 0 if the time isn't faked.
+	TimeOffset time.Duration `json:"time_offset_ns,omitempty"`
+}
+
+// DeadLetter records a webhook delivery which failed permanently.
+type DeadLetter struct {
+	SubmissionID string    `json:"submission_id"`
+	CallbackURL  string    `json:"callback_url"`
+	Payload      []byte    `json:"payload"`
+	Attempts     int       `json:"attempts"`
+	Error        string    `json:"error"`
+	FailedAt     time.Time `json:"failed_at"`
+}
+
+// Filter selects the submissions returned by List, zero values match everything.
+type Filter struct {
+	Tenant   string
+	Language config.Language
+	Verdict  string
+	// Time range of the creation, From is inclusive and To exclusive.
+	From time.Time
+	To   time.Time
+
+	// Maximum number of submissions, DefaultPageSize if zero and at most MaxPageSize.
+	Limit int
+	// Returned by List to fetch the next page, empty for the first page.
+	Cursor string
+}
+
+func (f Filter) Matches(s *Submission) bool {
+	return (f.Tenant == "" || s.Tenant == f.Tenant) &&
+		(f.Language == "" || s.Language == f.Language) &&
+		(f.Verdict == "" || s.Verdict == f.Verdict) &&
+		(f.From.IsZero() || !s.CreatedAt.Before(f.From)) &&
+		(f.To.IsZero() || s.CreatedAt.Before(f.To))
+}
+
+func (f Filter) PageSize() int {
+	if f.Limit <= 0 {
+		return DefaultPageSize
+	}
+	return min(f.Limit, MaxPageSize)
+}
+
+// Store is safe for concurrent use.
+type Store interface {
+	Save(ctx context.Context, submission *Submission) error
+	// Get returns ErrNotFound if there is no submission with the ID.
+	Get(ctx context.Context, id string) (*Submission, error)
+	// List returns the matching submissions, newest first, and the cursor of the next page which is empty on the last page.
+	List(ctx context.Context, filter Filter) ([]Submission, string, error)
+	// DeleteBefore deletes the submissions and dead letters created before the time and returns how many
+	// submissions were deleted.
+	DeleteBefore(ctx context.Context, before time.Time) (int, error)
+
+	SaveDeadLetter(ctx context.Context, deadLetter *DeadLetter) error
+	// ListDeadLetters returns the dead letters, newest first.
+	ListDeadLetters(ctx context.Context) ([]DeadLetter, error)
+	Close() error
+}
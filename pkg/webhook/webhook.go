@@ -0,0 +1,277 @@
+// Package webhook delivers the results of submissions to callback URLs.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"remote-code-engine/pkg/store"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Headers of a delivery. The signature is "sha256=" followed by the hex encoded HMAC-SHA256 of the timestamp, a dot
+// and the body, keyed with the webhook secret. The timestamp is the time of the attempt in Unix seconds, receivers
+// reject old timestamps so a captured delivery can't be replayed.
+const (
+	SignatureHeader  = "X-RCE-Signature"
+	TimestampHeader  = "X-RCE-Timestamp"
+	SubmissionHeader = "X-RCE-Submission-ID"
+	AttemptHeader    = "X-RCE-Attempt"
+)
+
+var (
+	ErrInvalidCallbackURL = errors.New("invalid callback URL")
+	// The callback resolves to a loopback, private or link-local address and its host isn't allowed.
+	errInternalAddress = errors.New("internal callback address")
+)
+
+// Callback hosts are resolved within this time when the submission is validated.
+const resolveTimeout = 5 * time.Second
+
+// DeadLetterRecorder keeps the deliveries which failed permanently, e.g. to deliver them by hand.
+type DeadLetterRecorder interface {
+	SaveDeadLetter(ctx context.Context, deadLetter *store.DeadLetter) error
+}
+
+type Sender struct {
+	client *http.Client
+	dialer *net.Dialer
+	secret []byte
+	// Hosts whose callbacks may use internal addresses, e.g. an LMS in the same network.
+	allowedHosts []string
+	lookupIP     func(ctx context.Context, network, host string) ([]net.IP, error)
+	// Delivery attempts before giving up, the backoff doubles after every failed attempt up to maxBackoff.
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	// nil if the dead letters are only logged.
+	deadLetters DeadLetterRecorder
+	logger      *zap.Logger
+}
+
+func NewSender(secret string, maxAttempts int, initialBackoff time.Duration, allowedHosts []string, deadLetters DeadLetterRecorder, logger *zap.Logger) *Sender {
+	s := &Sender{
+		dialer:         &net.Dialer{Timeout: 10 * time.Second},
+		secret:         []byte(secret),
+		allowedHosts:   allowedHosts,
+		lookupIP:       net.DefaultResolver.LookupIP,
+		maxAttempts:    maxAttempts,
+		initialBackoff: initialBackoff,
+		maxBackoff:     time.Minute,
+		deadLetters:    deadLetters,
+		logger:         logger,
+	}
+
+	// Every connection, including the ones of redirects, is checked when it is dialed. A proxy would dial
+	// the callback instead, so none is used.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = s.dial
+	s.client = &http.Client{Timeout: 10 * time.Second, Transport: transport}
+	return s
+}
+
+// ValidateCallbackURL checks that the URL is an absolute http or https URL whose host doesn't resolve to a
+// loopback, private or link-local address, unless the host is allowed.
+func (s *Sender) ValidateCallbackURL(callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w %q, use an absolute http or https URL", ErrInvalidCallbackURL, callbackURL)
+	}
+	if s.isAllowedHost(u.Hostname()) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	if _, err := s.resolve(ctx, u.Hostname()); err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidCallbackURL, callbackURL, err)
+	}
+	return nil
+}
+
+func (s *Sender) isAllowedHost(host string) bool {
+	return slices.ContainsFunc(s.allowedHosts, func(allowed string) bool {
+		return strings.EqualFold(allowed, host)
+	})
+}
+
+// resolve returns the addresses of the host, it fails if any of them is internal.
+func (s *Sender) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		if ips, err = s.lookupIP(ctx, "ip", host); err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+		}
+	}
+
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+			ip.IsUnspecified() || ip.IsMulticast() {
+			return nil, fmt.Errorf("%w: %s resolves to %s, which is a loopback, private or link-local address", errInternalAddress, host, ip)
+		}
+	}
+	return ips, nil
+}
+
+// dial connects to the resolved addresses of the callback, so the host can't resolve to an internal address
+// after it was validated.
+func (s *Sender) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if s.isAllowedHost(host) {
+		return s.dialer.DialContext(ctx, network, address)
+	}
+
+	ips, err := s.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		var conn net.Conn
+		if conn, err = s.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// Sign returns the value of the signature header for the body sent at the timestamp.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// permanentError is a failure which retrying won't fix, e.g. the receiver rejecting the payload.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Deliver posts the payload to the callback URL until it is accepted, the attempts are used up or the context
+// is cancelled. Failed deliveries are recorded as dead letters.
+func (s *Sender) Deliver(ctx context.Context, callbackURL, submissionID string, payload []byte) error {
+	backoff := s.initialBackoff
+	attempt := 1
+	var err error
+	for ; attempt <= s.maxAttempts; attempt++ {
+		err = s.post(ctx, callbackURL, submissionID, attempt, payload)
+		if err == nil {
+			s.logger.Info("delivered the webhook",
+				zap.String("submission ID", submissionID),
+				zap.Int("attempt", attempt),
+			)
+			return nil
+		}
+
+		var permanent permanentError
+		if errors.As(err, &permanent) || attempt == s.maxAttempts {
+			break
+		}
+
+		s.logger.Info("failed to deliver the webhook, retrying",
+			zap.String("submission ID", submissionID),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-time.After(backoff):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		backoff = min(2*backoff, s.maxBackoff)
+	}
+
+	s.logger.Error("failed to deliver the webhook, giving up",
+		zap.String("submission ID", submissionID),
+		zap.String("callback URL", callbackURL),
+		zap.Error(err),
+	)
+	s.recordDeadLetter(callbackURL, submissionID, min(attempt, s.maxAttempts), payload, err)
+	return fmt.Errorf("failed to deliver the webhook: %w", err)
+}
+
+func (s *Sender) post(ctx context.Context, callbackURL, submissionID string, attempt int, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(payload))
+	if err != nil {
+		return permanentError{err}
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(s.secret, timestamp, payload))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SubmissionHeader, submissionID)
+	req.Header.Set(AttemptHeader, fmt.Sprint(attempt))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		if errors.Is(err, errInternalAddress) {
+			return permanentError{err}
+		}
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+		_ = res.Body.Close()
+	}()
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return nil
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		return fmt.Errorf("the receiver responded with %s", res.Status)
+	default:
+		return permanentError{fmt.Errorf("the receiver rejected the webhook with %s", res.Status)}
+	}
+}
+
+func (s *Sender) recordDeadLetter(callbackURL, submissionID string, attempts int, payload []byte, err error) {
+	if s.deadLetters == nil {
+		return
+	}
+
+	// The context of the delivery may be cancelled already.
+	deadLetter := &store.DeadLetter{
+		SubmissionID: submissionID,
+		CallbackURL:  callbackURL,
+		Payload:      payload,
+		Attempts:     attempts,
+		Error:        err.Error(),
+		FailedAt:     time.Now(),
+	}
+	if err := s.deadLetters.SaveDeadLetter(context.Background(), deadLetter); err != nil {
+		s.logger.Error("failed to record the dead letter",
+			zap.String("submission ID", submissionID),
+			zap.Error(err),
+		)
+	}
+}
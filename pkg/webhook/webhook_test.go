@@ -0,0 +1,167 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"remote-code-engine/pkg/store"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type deadLetters struct {
+	mu          sync.Mutex
+	deadLetters []store.DeadLetter
+}
+
+func (d *deadLetters) SaveDeadLetter(ctx context.Context, deadLetter *store.DeadLetter) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deadLetters = append(d.deadLetters, *deadLetter)
+	return nil
+}
+
+func TestDeliver(t *testing.T) {
+	payload := []byte(`{"id":"submission","verdict":"ok"}`)
+
+	tests := []struct {
+		name               string
+		statuses           []int
+		expectedAttempts   int
+		expectedDeadLetter bool
+	}{
+		{
+			name:             "delivered",
+			statuses:         []int{http.StatusOK},
+			expectedAttempts: 1,
+		},
+		{
+			name:             "delivered after retries",
+			statuses:         []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusNoContent},
+			expectedAttempts: 3,
+		},
+		{
+			name:               "attempts used up",
+			statuses:           []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			expectedAttempts:   3,
+			expectedDeadLetter: true,
+		},
+		{
+			name:               "rejected",
+			statuses:           []int{http.StatusBadRequest},
+			expectedAttempts:   1,
+			expectedDeadLetter: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				timestamp := r.Header.Get(TimestampHeader)
+				if r.Header.Get(SignatureHeader) != Sign([]byte("secret"), timestamp, body) {
+					t.Errorf("invalid signature %q", r.Header.Get(SignatureHeader))
+				}
+				if sent, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+					t.Errorf("expected the time of the attempt, got %q", timestamp)
+				}
+				if r.Header.Get(SubmissionHeader) != "submission" {
+					t.Errorf("expected the submission ID header, got %q", r.Header.Get(SubmissionHeader))
+				}
+				w.WriteHeader(tt.statuses[min(attempts, len(tt.statuses)-1)])
+				attempts++
+			}))
+			defer receiver.Close()
+
+			recorder := &deadLetters{}
+			// The receiver listens on the loopback address.
+			sender := NewSender("secret", 3, time.Millisecond, []string{"127.0.0.1"}, recorder, zap.NewNop())
+			err := sender.Deliver(context.Background(), receiver.URL, "submission", payload)
+
+			if (err != nil) != tt.expectedDeadLetter {
+				t.Errorf("unexpected error %v", err)
+			}
+			if attempts != tt.expectedAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.expectedAttempts, attempts)
+			}
+			if (len(recorder.deadLetters) == 1) != tt.expectedDeadLetter {
+				t.Fatalf("expected dead letter %v, got %+v", tt.expectedDeadLetter, recorder.deadLetters)
+			}
+			if tt.expectedDeadLetter && recorder.deadLetters[0].Attempts != tt.expectedAttempts {
+				t.Errorf("expected %d attempts in the dead letter, got %d", tt.expectedAttempts, recorder.deadLetters[0].Attempts)
+			}
+		})
+	}
+}
+
+func TestDeliverToInternalAddress(t *testing.T) {
+	attempts := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+	}))
+	defer receiver.Close()
+
+	recorder := &deadLetters{}
+	sender := NewSender("secret", 3, time.Millisecond, nil, recorder, zap.NewNop())
+	if err := sender.Deliver(context.Background(), receiver.URL, "submission", []byte("{}")); !errors.Is(err, errInternalAddress) {
+		t.Errorf("expected the loopback receiver to be rejected, got %v", err)
+	}
+	if attempts != 0 || len(recorder.deadLetters) != 1 || recorder.deadLetters[0].Attempts != 1 {
+		t.Errorf("expected a single failed attempt, got %d requests and %+v", attempts, recorder.deadLetters)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	expected := "sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"
+	if signature := Sign([]byte("secret"), "1700000000", []byte("{}")); signature != expected {
+		t.Errorf("expected signature %q, got %q", expected, signature)
+	}
+}
+
+func TestValidateCallbackURL(t *testing.T) {
+	sender := NewSender("secret", 3, time.Second, []string{"lms.internal"}, nil, zap.NewNop())
+	sender.lookupIP = func(ctx context.Context, network, host string) ([]net.IP, error) {
+		addresses := map[string][]net.IP{
+			"lms.example.com": {net.ParseIP("93.184.216.34")},
+			"lms.internal":    {net.ParseIP("10.0.0.5")},
+			"localhost":       {net.ParseIP("127.0.0.1")},
+			"rebind.example":  {net.ParseIP("93.184.216.34"), net.ParseIP("127.0.0.1")},
+		}
+		if ips, ok := addresses[host]; ok {
+			return ips, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	for url, valid := range map[string]bool{
+		"https://lms.example.com/rce":              true,
+		"https://93.184.216.34/rce":                true,
+		"http://lms.internal:8080/hook":            true,
+		"http://LMS.internal/hook":                 true,
+		"http://localhost:8080/hook":               false,
+		"http://127.0.0.1:8080/hook":               false,
+		"http://[::1]/hook":                        false,
+		"http://10.1.2.3/hook":                     false,
+		"http://192.168.0.1/hook":                  false,
+		"http://169.254.169.254/latest/meta-data/": false,
+		"http://0.0.0.0/hook":                      false,
+		"http://rebind.example/hook":               false,
+		"http://unknown.example/hook":              false,
+		"ftp://example.com":                        false,
+		"/relative":                                false,
+		"https://":                                 false,
+	} {
+		if err := sender.ValidateCallbackURL(url); (err == nil) != valid {
+			t.Errorf("%s: expected valid %v, got %v", url, valid, err)
+		}
+	}
+}
@@ -19,8 +19,8 @@ script_dir=$(dirname "$(realpath "$0")")
 dockerfiles_folder="$script_dir/../Dockerfiles/$arch/"
 
 # Define the dockerfile_map as a regular associative array in zsh
-languages=("cpp" "golang")
-dockerfiles=("cpp.Dockerfile" "golang.Dockerfile")
+languages=("cpp" "golang" "c" "python" "java" "rust" "javascript")
+dockerfiles=("cpp.Dockerfile" "golang.Dockerfile" "c.Dockerfile" "python.Dockerfile" "java.Dockerfile" "rust.Dockerfile" "javascript.Dockerfile")
 
 # Loop through each Dockerfile and build the Docker image
 for i in "${!languages[@]}"; do
@@ -28,7 +28,7 @@ for i in "${!languages[@]}"; do
     dockerfile="${dockerfiles_folder}${dockerfiles[$i]}"
     if [[ -f "$dockerfile" ]]; then
         echo "Building Docker image for $language using $dockerfile"
-        docker build -t "${language}_${arch}:latest" -f "$dockerfile" "${dockerfiles_folder}"
+        docker build -t "${language}_${arch}:latest" --label "rce.repository=${language}_${arch}" -f "$dockerfile" "${dockerfiles_folder}"
     else
         echo "Dockerfile for $language not found: $dockerfile"
     fi